@@ -1,31 +1,134 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/ZulfiPy/RWAPIGo/internal/api"
-	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
-	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
-	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/app"
+	"github.com/ZulfiPy/RWAPIGo/internal/backup"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
 	"github.com/ZulfiPy/RWAPIGo/internal/storage"
 )
 
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func setupLogging(cfg config.Config) {
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	slog.SetDefault(logging.New(os.Stdout, level))
+}
+
+// restore runs "rwapigolang restore --at <time>", replacing the storage files
+// with the newest backup taken at or before that time. The server must not be
+// running while it does.
+func restore(args []string) {
+	flags := flag.NewFlagSet("rwapigolang restore", flag.ContinueOnError)
+	atFlag := flags.String("at", "", "restore the newest backup taken at or before this RFC 3339 time or backup timestamp")
+
+	cfg, err := config.LoadFlags(flags, args)
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	setupLogging(cfg)
+
+	if *atFlag == "" {
+		fatal("invalid arguments", errors.New("restore requires -at"))
+	}
+
+	at, err := backup.ParseTime(*atFlag)
+	if err != nil {
+		fatal("invalid arguments", err)
+	}
+
+	lock, err := storage.LockDir(cfg.DataDir)
+	if err != nil {
+		fatal("locking data directory failed", err)
+	}
+	defer lock.Unlock()
+
+	storages, err := app.OpenStorages(cfg)
+	if err != nil {
+		fatal("opening storage failed", err)
+	}
+
+	snapshot, err := storages.Backups.Restore(at)
+	if err != nil {
+		fatal("restore failed", err)
+	}
+
+	slog.Info("backup restored", "backup", snapshot.Name, "created_at", snapshot.CreatedAt)
+}
+
 func main() {
-	fmt.Println("RWAPIGolang runs...")
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		restore(os.Args[2:])
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	setupLogging(cfg)
+
+	slog.Info("RWAPIGolang starting", "data_dir", cfg.DataDir)
+
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		fatal("creating data directory failed", err)
+	}
+
+	lock, err := storage.LockDir(cfg.DataDir)
+	if err != nil {
+		fatal("locking data directory failed", err)
+	}
+	defer lock.Unlock()
+
+	storages, err := app.OpenStorages(cfg)
+	if err != nil {
+		fatal("opening storage failed", err)
+	}
+
+	if settled, err := storages.Payments.SettlePending(); err != nil {
+		slog.Error("settling pending payments failed", "settled", settled, "error", err)
+	} else if settled > 0 {
+		slog.Info("settled pending payments", "count", settled)
+	}
+
+	expiryWorker := storages.Reservations.StartExpiryWorker(ctx, storages.Journal, cfg.ReservationExpiryInterval.Duration)
 
-	customers := customer.Customers{}
-	vehicles := vehicle.Vehicles{}
-	employees := employee.Employees{}
+	backupScheduler := storages.Backups.StartScheduler(ctx, cfg.Backup.Interval.Duration)
+	webhookDispatcher := storages.Webhooks.Start(ctx)
+	notifier := storages.Notifier.Start(ctx)
+	overdueMonitor := storages.Overdue.Start(ctx)
 
-	customerStorage := customer.NewCustomerStorage("customers.json")
-	storage.EnsureStorageFile(customerStorage.GetStorage(), customers)
+	server := storages.NewAPIServer(cfg)
+	runErr := server.Run(ctx)
 
-	vehicleStorage := vehicle.NewVehicleStorage("vehicles.json")
-	storage.EnsureStorageFile(vehicleStorage.GetStorage(), vehicles)
+	stop()
+	<-expiryWorker
+	<-backupScheduler
+	<-webhookDispatcher
+	<-notifier
+	<-overdueMonitor
+	storage.Flush()
 
-	employeeStorage := employee.NewEmployeeStorage("employees.json")
-	storage.EnsureStorageFile(employeeStorage.GetStorage(), employees)
+	if runErr != nil {
+		fatal("server stopped", runErr)
+	}
 
-	server := api.NewAPIServer(":8080", customerStorage, vehicleStorage, employeeStorage)
-	server.Run()
+	slog.Info("RWAPIGolang stopped")
 }
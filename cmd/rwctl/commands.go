@@ -0,0 +1,583 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ZulfiPy/RWAPIGo/pkg/client"
+)
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	return flags
+}
+
+func parse(flags *flag.FlagSet, args []string) (map[string]bool, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, errUsage
+	}
+
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("%w: unexpected argument %q", errUsage, flags.Arg(0))
+	}
+
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set, nil
+}
+
+func action(resource string, args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("%w: %s needs an action: list, add, edit or delete", errUsage, resource)
+	}
+
+	return args[0], args[1:], nil
+}
+
+func runCustomers(ctx context.Context, e *env, args []string) error {
+	name, args, err := action("customers", args)
+	if err != nil {
+		return err
+	}
+
+	customers := e.client.Customers()
+	flags := newFlagSet("customers " + name)
+
+	switch name {
+	case "list":
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+
+		list, err := customers.List(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, c := range list {
+			rows = append(rows, customerRow(c))
+		}
+		return e.print(list, customerHeaders, rows)
+
+	case "add", "edit":
+		personalID := flags.Int64("personal-id", 0, "11 digit personal ID")
+		firstName := flags.String("first-name", "", "first name")
+		lastName := flags.String("last-name", "", "last name")
+		email := flags.String("email", "", "email address")
+		phone := flags.String("phone", "", "phone number")
+
+		set, err := parse(flags, args)
+		if err != nil {
+			return err
+		}
+
+		if name == "add" {
+			if _, err := customers.Create(ctx, client.Customer{PersonalID: *personalID, FirstName: *firstName, LastName: *lastName, Email: *email, PhoneNumber: *phone}); err != nil {
+				return err
+			}
+			return e.message(fmt.Sprintf("customer %d added", *personalID))
+		}
+
+		list, err := customers.List(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		idx := findCustomer(list, *personalID)
+		if idx == -1 {
+			return fmt.Errorf("customer %d not found", *personalID)
+		}
+
+		current := list[idx]
+		edit := client.EditCustomerRequest{PersonalID: *personalID, FirstName: current.FirstName, LastName: current.LastName, Email: current.Email, PhoneNumber: current.PhoneNumber}
+		if set["first-name"] {
+			edit.FirstName = *firstName
+		}
+		if set["last-name"] {
+			edit.LastName = *lastName
+		}
+		if set["email"] {
+			edit.Email = *email
+		}
+		if set["phone"] {
+			edit.PhoneNumber = *phone
+		}
+
+		if err := customers.Update(ctx, edit); err != nil {
+			return err
+		}
+		return e.message(fmt.Sprintf("customer %d edited", *personalID))
+
+	case "delete":
+		personalID := flags.Int64("personal-id", 0, "11 digit personal ID")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+
+		if err := customers.Delete(ctx, *personalID); err != nil {
+			return err
+		}
+		return e.message(fmt.Sprintf("customer %d deleted", *personalID))
+	}
+
+	return fmt.Errorf("%w: unknown customers action %q", errUsage, name)
+}
+
+func findCustomer(customers client.Customers, personalID int64) int {
+	for idx, c := range customers {
+		if c.PersonalID == personalID {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func vehicleFlags(flags *flag.FlagSet) func(set map[string]bool, v *client.Vehicle) {
+	plate := flags.String("plate", "", "plate number")
+	make := flags.String("make", "", "manufacturer")
+	model := flags.String("model", "", "model")
+	year := flags.Int("year", 0, "model year")
+	fuel := flags.String("fuel", "", "fuel type")
+	gearbox := flags.String("gearbox", "", "gearbox")
+	color := flags.String("color", "", "color")
+	body := flags.String("body", "", "body type")
+	branch := flags.Int("branch", 0, "branch ID")
+
+	return func(set map[string]bool, v *client.Vehicle) {
+		v.PlateNumber = *plate
+		if set["make"] {
+			v.Make = *make
+		}
+		if set["model"] {
+			v.Model = *model
+		}
+		if set["year"] {
+			v.Year = *year
+		}
+		if set["fuel"] {
+			v.FuelType = *fuel
+		}
+		if set["gearbox"] {
+			v.Gearbox = *gearbox
+		}
+		if set["color"] {
+			v.Color = *color
+		}
+		if set["body"] {
+			v.Body = *body
+		}
+		if set["branch"] {
+			v.BranchID = *branch
+		}
+	}
+}
+
+func sortedVehicles(vehicles client.Vehicles) []client.Vehicle {
+	list := make([]client.Vehicle, 0, len(vehicles))
+	for _, v := range vehicles {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].PlateNumber < list[j].PlateNumber })
+
+	return list
+}
+
+func runVehicles(ctx context.Context, e *env, args []string) error {
+	name, args, err := action("vehicles", args)
+	if err != nil {
+		return err
+	}
+
+	vehicles := e.client.Vehicles()
+	flags := newFlagSet("vehicles " + name)
+
+	switch name {
+	case "list":
+		branch := flags.Int("branch", 0, "only list vehicles of this branch")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+
+		list, err := vehicles.List(ctx, &client.ListOptions{BranchID: *branch})
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, v := range sortedVehicles(list) {
+			rows = append(rows, vehicleRow(v))
+		}
+		return e.print(list, vehicleHeaders, rows)
+
+	case "add", "edit":
+		apply := vehicleFlags(flags)
+		set, err := parse(flags, args)
+		if err != nil {
+			return err
+		}
+
+		var v client.Vehicle
+		if name == "edit" {
+			list, err := vehicles.List(ctx, nil)
+			if err != nil {
+				return err
+			}
+
+			plate := flags.Lookup("plate").Value.String()
+			current, ok := list[plate]
+			if !ok {
+				return fmt.Errorf("vehicle %s not found", plate)
+			}
+			v = current
+		}
+		apply(set, &v)
+
+		save := vehicles.Create
+		if name == "edit" {
+			save = vehicles.Update
+		}
+
+		saved, err := save(ctx, v)
+		if err != nil {
+			return err
+		}
+		return e.print(saved, vehicleHeaders, [][]string{vehicleRow(saved)})
+
+	case "delete":
+		plate := flags.String("plate", "", "plate number")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+
+		if err := vehicles.Delete(ctx, *plate); err != nil {
+			return err
+		}
+		return e.message(fmt.Sprintf("vehicle %s deleted", *plate))
+	}
+
+	return fmt.Errorf("%w: unknown vehicles action %q", errUsage, name)
+}
+
+func runEmployees(ctx context.Context, e *env, args []string) error {
+	name, args, err := action("employees", args)
+	if err != nil {
+		return err
+	}
+
+	employees := e.client.Employees()
+	flags := newFlagSet("employees " + name)
+
+	switch name {
+	case "list":
+		branch := flags.Int("branch", 0, "only list employees of this branch")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+
+		list, err := employees.List(ctx, &client.ListOptions{BranchID: *branch})
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, em := range list {
+			rows = append(rows, employeeRow(em))
+		}
+		return e.print(list, employeeHeaders, rows)
+
+	case "add":
+		personalID := flags.Int64("personal-id", 0, "11 digit personal ID")
+		firstName := flags.String("first-name", "", "first name")
+		lastName := flags.String("last-name", "", "last name")
+		born := flags.String("born", "", "date of birth, formatted as DD.MM.YYYY")
+		email := flags.String("email", "", "email address")
+		phone := flags.String("phone", "", "phone number")
+		address := flags.String("address", "", "home address")
+		branch := flags.Int("branch", 0, "branch ID")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+
+		created, err := employees.Create(ctx, client.Employee{PersonalID: *personalID, FirstName: *firstName, LastName: *lastName, DateOfBirth: *born, Email: *email, PhoneNumber: *phone, Address: *address, BranchID: *branch})
+		if err != nil {
+			return err
+		}
+		return e.print(created, employeeHeaders, [][]string{employeeRow(created)})
+
+	case "edit":
+		personalID := flags.Int64("personal-id", 0, "11 digit personal ID")
+		email := flags.String("email", "", "email address")
+		phone := flags.String("phone", "", "phone number")
+		address := flags.String("address", "", "home address")
+		branch := flags.Int("branch", 0, "move the employee to this branch")
+		set, err := parse(flags, args)
+		if err != nil {
+			return err
+		}
+
+		list, err := employees.List(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		var current *client.Employee
+		for idx := range list {
+			if list[idx].PersonalID == *personalID {
+				current = &list[idx]
+			}
+		}
+		if current == nil {
+			return fmt.Errorf("employee %d not found", *personalID)
+		}
+
+		edit := client.EditEmployeeRequest{PersonalID: *personalID, Email: current.Email, PhoneNumber: current.PhoneNumber, Address: current.Address}
+		if set["email"] {
+			edit.Email = *email
+		}
+		if set["phone"] {
+			edit.PhoneNumber = *phone
+		}
+		if set["address"] {
+			edit.Address = *address
+		}
+
+		edited, err := employees.Update(ctx, edit)
+		if err != nil {
+			return err
+		}
+
+		if set["branch"] {
+			if edited, err = employees.AssignBranch(ctx, *personalID, *branch); err != nil {
+				return err
+			}
+		}
+		return e.print(edited, employeeHeaders, [][]string{employeeRow(edited)})
+
+	case "delete":
+		personalID := flags.Int64("personal-id", 0, "11 digit personal ID")
+		if _, err := parse(flags, args); err != nil {
+			return err
+		}
+
+		if err := employees.Delete(ctx, *personalID); err != nil {
+			return err
+		}
+		return e.message(fmt.Sprintf("employee %d deleted", *personalID))
+	}
+
+	return fmt.Errorf("%w: unknown employees action %q", errUsage, name)
+}
+
+func runAssign(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("assign")
+	personalID := flags.Int64("customer", 0, "personal ID of the customer")
+	plate := flags.String("plate", "", "plate number of the vehicle")
+	odometer := flags.Int("odometer", -1, "odometer reading at checkout")
+	fuel := flags.Int("fuel", -1, "fuel or battery level at checkout, in percent")
+	set, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+
+	input := client.CheckoutRequest{Vehicle: client.Vehicle{PlateNumber: *plate}}
+	if set["odometer"] {
+		input.Odometer = odometer
+	}
+	if set["fuel"] {
+		input.FuelLevel = fuel
+	}
+
+	updated, err := e.client.Customers().Checkout(ctx, *personalID, input)
+	if err != nil {
+		return err
+	}
+	return e.print(updated, customerHeaders, [][]string{customerRow(updated)})
+}
+
+func runUnassign(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("unassign")
+	personalID := flags.Int64("customer", 0, "personal ID of the customer")
+	plate := flags.String("plate", "", "plate number of the vehicle")
+	odometer := flags.Int("odometer", -1, "odometer reading at return")
+	fuel := flags.Int("fuel", -1, "fuel or battery level at return, in percent")
+	returnBranch := flags.Int("return-branch", 0, "branch the vehicle is returned to, defaults to the pickup branch")
+	set, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+
+	input := client.CheckInRequest{ReturnBranchID: *returnBranch}
+	if set["odometer"] {
+		input.Odometer = odometer
+	}
+	if set["fuel"] {
+		input.FuelLevel = fuel
+	}
+
+	returned, err := e.client.Customers().CheckIn(ctx, *personalID, *plate, input)
+	if err != nil {
+		return err
+	}
+	return e.print(returned, rentalHeaders, [][]string{rentalRow(returned)})
+}
+
+type dump struct {
+	Branches  client.Branches
+	Vehicles  client.Vehicles
+	Employees client.Employees
+	Customers client.Customers
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("export")
+	file := flags.String("f", "-", "file to write, - for standard output")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+
+	var data dump
+	var err error
+	if data.Branches, err = e.client.Branches().List(ctx); err != nil {
+		return err
+	}
+	if data.Vehicles, err = e.client.Vehicles().List(ctx, nil); err != nil {
+		return err
+	}
+	if data.Employees, err = e.client.Employees().List(ctx, nil); err != nil {
+		return err
+	}
+	if data.Customers, err = e.client.Customers().List(ctx, nil); err != nil {
+		return err
+	}
+
+	out := e.stdout
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(data)
+}
+
+type importResult struct {
+	Created int
+	Failed  []string
+}
+
+// runImport creates branches first and remaps the branch IDs of imported
+// vehicles and employees, since branch IDs are assigned by the target.
+// Rented vehicles of customers are not restored.
+func runImport(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet("import")
+	file := flags.String("f", "-", "file to read, - for standard input")
+	keepGoing := flags.Bool("continue", false, "keep importing after a record fails")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+
+	in := e.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var data dump
+	decoder := json.NewDecoder(in)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&data); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading import file: %w", err)
+	}
+
+	result := importResult{Failed: []string{}}
+	record := func(label string, err error) error {
+		if err == nil {
+			result.Created++
+			return nil
+		}
+
+		result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", label, err))
+		if *keepGoing {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", label, err)
+	}
+
+	existing, err := e.client.Branches().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	branchIDs := map[int]int{}
+	for _, b := range data.Branches {
+		found := false
+		for _, current := range existing {
+			if current.Name == b.Name {
+				branchIDs[b.ID] = current.ID
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+
+		created, err := e.client.Branches().Create(ctx, b)
+		if err == nil {
+			branchIDs[b.ID] = created.ID
+		}
+		if err := record("branch "+b.Name, err); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range sortedVehicles(data.Vehicles) {
+		if id, ok := branchIDs[v.BranchID]; ok {
+			v.BranchID = id
+		}
+		_, err := e.client.Vehicles().Create(ctx, v)
+		if err := record("vehicle "+v.PlateNumber, err); err != nil {
+			return err
+		}
+	}
+
+	for _, em := range data.Employees {
+		if id, ok := branchIDs[em.BranchID]; ok {
+			em.BranchID = id
+		}
+		_, err := e.client.Employees().Create(ctx, em)
+		if err := record(fmt.Sprintf("employee %d", em.PersonalID), err); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range data.Customers {
+		_, err := e.client.Customers().Create(ctx, c)
+		if err := record(fmt.Sprintf("customer %d", c.PersonalID), err); err != nil {
+			return err
+		}
+	}
+
+	rows := [][]string{{fmt.Sprint(result.Created), fmt.Sprint(len(result.Failed))}}
+	for _, failure := range result.Failed {
+		rows = append(rows, []string{"", failure})
+	}
+	return e.print(result, []string{"CREATED", "FAILED"}, rows)
+}
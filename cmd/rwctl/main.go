@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/app"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/pkg/client"
+)
+
+const usage = `Usage: rwctl [global flags] <command> [flags]
+
+Commands:
+  customers list|add|edit|delete
+  vehicles  list|add|edit|delete
+  employees list|add|edit|delete
+  assign    check a vehicle out to a customer
+  unassign  check a vehicle back in from a customer
+  export    write customers, vehicles, employees and branches as JSON
+  import    create the records of an export file
+
+Without -remote, rwctl works directly on the JSON storage files in
+-data-dir. Local mode refuses to run while the server holds the same
+directory, use -remote against the server instead.
+
+Global flags:
+`
+
+var errUsage = errors.New("usage error")
+
+type env struct {
+	client *client.Client
+	output string
+	stdout io.Writer
+	stdin  io.Reader
+}
+
+type command func(ctx context.Context, e *env, args []string) error
+
+var commands = map[string]command{
+	"customers": runCustomers,
+	"vehicles":  runVehicles,
+	"employees": runEmployees,
+	"assign":    runAssign,
+	"unassign":  runUnassign,
+	"export":    runExport,
+	"import":    runImport,
+}
+
+// responseBuffer collects the response of an in-process handler.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rb *responseBuffer) Header() http.Header {
+	return rb.header
+}
+
+func (rb *responseBuffer) WriteHeader(status int) {
+	if rb.status == 0 {
+		rb.status = status
+	}
+}
+
+func (rb *responseBuffer) Write(data []byte) (int, error) {
+	rb.WriteHeader(http.StatusOK)
+	return rb.body.Write(data)
+}
+
+// handlerTransport serves client requests with an in-process handler, so
+// local mode applies exactly the same validation as the HTTP API.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rb := &responseBuffer{header: http.Header{}}
+	t.handler.ServeHTTP(rb, req)
+	rb.WriteHeader(http.StatusOK)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rb.status, http.StatusText(rb.status)),
+		StatusCode:    rb.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rb.header,
+		Body:          io.NopCloser(&rb.body),
+		ContentLength: int64(rb.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// localClient opens the storages in the data directory and locks it for
+// as long as the command runs, so it cannot write next to a running server.
+func localClient(configFile, dataDir string, employeeID int64) (*client.Client, *storage.DirLock, error) {
+	args := []string{}
+	if configFile != "" {
+		args = append(args, "-config", configFile)
+	}
+	if dataDir != "" {
+		args = append(args, "-data-dir", dataDir)
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.Server.RateLimits = nil
+
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return nil, nil, err
+	}
+
+	lock, err := storage.LockDir(cfg.DataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storages, err := app.OpenStorages(cfg)
+	if err != nil {
+		lock.Unlock()
+		return nil, nil, err
+	}
+
+	handler := storages.NewAPIServer(cfg).Handler()
+
+	c, err := client.New("http://rwctl.local",
+		client.WithHTTPClient(&http.Client{Transport: handlerTransport{handler: handler}}),
+		client.WithEmployee(employeeID),
+		client.WithRetries(0, 0, 0),
+	)
+	if err != nil {
+		lock.Unlock()
+		return nil, nil, err
+	}
+
+	return c, lock, nil
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, stdin io.Reader) error {
+	flags := flag.NewFlagSet("rwctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	remote := flags.String("remote", os.Getenv("RWCTL_REMOTE"), "base URL of a running API, e.g. http://localhost:8080")
+	apiKey := flags.String("api-key", os.Getenv("RWCTL_API_KEY"), "API key sent in the X-API-Key header")
+	configFile := flags.String("config", os.Getenv("RWAPI_CONFIG"), "server configuration file used in local mode")
+	dataDir := flags.String("data-dir", "", "storage directory used in local mode")
+	employeeID := flags.Int64("employee", 0, "personal ID of the employee handling assign and unassign")
+	output := flags.String("o", "table", "output format: table or json")
+
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("%w: unknown output format %q", errUsage, *output)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("%w: unknown command %q", errUsage, flags.Arg(0))
+	}
+
+	var c *client.Client
+	var err error
+	if *remote != "" {
+		c, err = client.New(*remote, client.WithAPIKey(*apiKey), client.WithEmployee(*employeeID), client.WithUserAgent("rwctl"))
+	} else {
+		var lock *storage.DirLock
+		c, lock, err = localClient(*configFile, *dataDir, *employeeID)
+		if err == nil {
+			defer lock.Unlock()
+			defer storage.Flush()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return cmd(ctx, &env{client: c, output: *output, stdout: stdout, stdin: stdin}, flags.Args()[1:])
+}
+
+func main() {
+	slog.SetDefault(logging.New(os.Stderr, slog.LevelError))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Stdin)
+	storage.Flush()
+
+	if err != nil {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "rwctl:", err)
+		}
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/pkg/client"
+)
+
+// rwctl runs a local mode command against dataDir and returns its output.
+func rwctl(t *testing.T, dataDir string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), append([]string{"-data-dir", dataDir}, args...), &stdout, &stderr, strings.NewReader(""))
+	return stdout.String(), err
+}
+
+func mustRWCtl(t *testing.T, dataDir string, args ...string) string {
+	t.Helper()
+
+	out, err := rwctl(t, dataDir, args...)
+	if err != nil {
+		t.Fatalf("rwctl %s: %v", strings.Join(args, " "), err)
+	}
+
+	return out
+}
+
+func seed(t *testing.T, dataDir string) {
+	t.Helper()
+
+	mustRWCtl(t, dataDir, "employees", "add", "-personal-id", "38001010000", "-first-name", "Jaan", "-last-name", "Kask",
+		"-born", "01.01.1980", "-email", "jaan.kask@example.com", "-phone", "5551234", "-address", "Tartu mnt 1, Tallinn")
+	mustRWCtl(t, dataDir, "customers", "add", "-personal-id", "49001010001", "-first-name", "Mari", "-last-name", "Tamm",
+		"-email", "mari.tamm@example.com", "-phone", "5551001")
+	mustRWCtl(t, dataDir, "vehicles", "add", "-plate", "123ABC", "-make", "Toyota", "-model", "Corolla", "-year", "2020",
+		"-fuel", "Petrol", "-gearbox", "Automatic", "-color", "White", "-body", "Sedan")
+}
+
+func TestCustomers(t *testing.T) {
+	dataDir := t.TempDir()
+	seed(t, dataDir)
+
+	mustRWCtl(t, dataDir, "customers", "edit", "-personal-id", "49001010001", "-first-name", "Maria")
+
+	var customers client.Customers
+	if err := json.Unmarshal([]byte(mustRWCtl(t, dataDir, "-o", "json", "customers", "list")), &customers); err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 1 || customers[0].FirstName != "Maria" || customers[0].LastName != "Tamm" {
+		t.Fatalf("customers = %+v, want Maria Tamm", customers)
+	}
+
+	if out := mustRWCtl(t, dataDir, "customers", "delete", "-personal-id", "49001010001"); !strings.Contains(out, "deleted") {
+		t.Errorf("delete printed %q", out)
+	}
+	if out := mustRWCtl(t, dataDir, "customers", "list"); strings.Contains(out, "49001010001") {
+		t.Errorf("deleted customer still listed:\n%s", out)
+	}
+}
+
+func TestAssignAndUnassign(t *testing.T) {
+	dataDir := t.TempDir()
+	seed(t, dataDir)
+
+	if _, err := rwctl(t, dataDir, "assign", "-customer", "49001010001", "-plate", "123ABC", "-odometer", "1000", "-fuel", "100"); err == nil {
+		t.Fatal("expected assign without -employee to fail")
+	}
+
+	out := mustRWCtl(t, dataDir, "-employee", "38001010000", "assign", "-customer", "49001010001", "-plate", "123ABC", "-odometer", "1000", "-fuel", "100")
+	if !strings.Contains(out, "123ABC") {
+		t.Errorf("assign printed:\n%s", out)
+	}
+
+	out = mustRWCtl(t, dataDir, "-employee", "38001010000", "-o", "json", "unassign", "-customer", "49001010001", "-plate", "123ABC", "-odometer", "1150", "-fuel", "100")
+
+	var rental client.Rental
+	if err := json.Unmarshal([]byte(out), &rental); err != nil {
+		t.Fatal(err)
+	}
+	if rental.PlateNumber != "123ABC" || rental.DistanceDriven != 150 {
+		t.Errorf("rental = %+v, want 150 km on 123ABC", rental)
+	}
+}
+
+func TestExportAndImport(t *testing.T) {
+	source := t.TempDir()
+	seed(t, source)
+
+	file := filepath.Join(t.TempDir(), "export.json")
+	mustRWCtl(t, source, "export", "-f", file)
+
+	target := t.TempDir()
+	out := mustRWCtl(t, target, "-o", "json", "import", "-f", file)
+
+	var result importResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 3 || len(result.Failed) != 0 {
+		t.Fatalf("import result = %+v, want 3 records created", result)
+	}
+
+	if out := mustRWCtl(t, target, "vehicles", "list"); !strings.Contains(out, "123ABC") {
+		t.Errorf("imported vehicles:\n%s", out)
+	}
+
+	// Importing again fails on the first duplicate unless told to continue.
+	if _, err := rwctl(t, target, "import", "-f", file); err == nil {
+		t.Error("expected a second import to fail")
+	}
+	if err := json.Unmarshal([]byte(mustRWCtl(t, target, "-o", "json", "import", "-f", file, "-continue")), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 0 || len(result.Failed) != 3 {
+		t.Errorf("import result = %+v, want 3 failures", result)
+	}
+}
+
+func TestLocalModeRefusesLockedDirectory(t *testing.T) {
+	dataDir := t.TempDir()
+
+	lock, err := storage.LockDir(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rwctl(t, dataDir, "customers", "list"); !errors.Is(err, storage.ErrLocked) {
+		t.Fatalf("err = %v, want %v", err, storage.ErrLocked)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	mustRWCtl(t, dataDir, "customers", "list")
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"trucks", "list"},
+		{"-o", "yaml", "customers", "list"},
+		{"customers"},
+		{"customers", "rename"},
+		{"customers", "list", "extra"},
+	}
+
+	for _, args := range tests {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			if _, err := rwctl(t, t.TempDir(), args...); !errors.Is(err, errUsage) {
+				t.Errorf("err = %v, want a usage error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ZulfiPy/RWAPIGo/pkg/client"
+)
+
+func (e *env) print(value any, headers []string, rows [][]string) error {
+	if e.output == "json" {
+		encoder := json.NewEncoder(e.stdout)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(value)
+	}
+
+	writer := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+func (e *env) message(text string) error {
+	if e.output == "json" {
+		return e.print(client.CustomResponse{Response: text}, nil, nil)
+	}
+
+	_, err := fmt.Fprintln(e.stdout, text)
+	return err
+}
+
+var customerHeaders = []string{"PERSONAL ID", "FIRST NAME", "LAST NAME", "EMAIL", "PHONE", "RENTED"}
+
+func customerRow(c client.Customer) []string {
+	plates := []string{}
+	for _, v := range c.RentedVehicles {
+		plates = append(plates, v.PlateNumber)
+	}
+
+	return []string{strconv.FormatInt(c.PersonalID, 10), c.FirstName, c.LastName, c.Email, c.PhoneNumber, strings.Join(plates, ",")}
+}
+
+var vehicleHeaders = []string{"PLATE", "MAKE", "MODEL", "YEAR", "FUEL", "GEARBOX", "COLOR", "BODY", "BRANCH"}
+
+func vehicleRow(v client.Vehicle) []string {
+	return []string{v.PlateNumber, v.Make, v.Model, strconv.Itoa(v.Year), v.FuelType, v.Gearbox, v.Color, v.Body, strconv.Itoa(v.BranchID)}
+}
+
+var employeeHeaders = []string{"PERSONAL ID", "FIRST NAME", "LAST NAME", "BORN", "EMAIL", "PHONE", "ADDRESS", "BRANCH"}
+
+func employeeRow(em client.Employee) []string {
+	return []string{strconv.FormatInt(em.PersonalID, 10), em.FirstName, em.LastName, em.DateOfBirth, em.Email, em.PhoneNumber, em.Address, strconv.Itoa(em.BranchID)}
+}
+
+var rentalHeaders = []string{"RENTAL", "PERSONAL ID", "PLATE", "DISTANCE", "OVERAGE", "REFUEL", "ONE-WAY", "TOTAL"}
+
+func rentalRow(r client.Rental) []string {
+	money := func(amount float64) string { return strconv.FormatFloat(amount, 'f', 2, 64) }
+	return []string{strconv.Itoa(r.ID), strconv.FormatInt(r.PersonalID, 10), r.PlateNumber, strconv.Itoa(r.DistanceDriven), money(r.OverageCharge), money(r.RefuelCharge), money(r.OneWayFee), money(r.TotalCharges())}
+}
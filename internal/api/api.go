@@ -1,33 +1,94 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/ZulfiPy/RWAPIGo/internal/backup"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/branch"
 	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/damage"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/document"
 	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/payment"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/reservation"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/shift"
 	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/notify"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
 	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+	"github.com/ZulfiPy/RWAPIGo/internal/webhook"
 
 	"github.com/gorilla/mux"
 )
 
 type APIServer struct {
-	listenAddr      string
-	customerStorage *customer.CustomerStorage
-	vehicleStorage  *vehicle.VehicleStorage
-	employeeStorage *employee.EmployeeStorage
+	listenAddr         string
+	serverConfig       config.Server
+	customerStorage    *customer.CustomerStorage
+	vehicleStorage     *vehicle.VehicleStorage
+	employeeStorage    *employee.EmployeeStorage
+	rentalStorage      *rental.RentalStorage
+	damageStorage      *damage.DamageStorage
+	documentStorage    *document.DocumentStorage
+	reservationStorage *reservation.ReservationStorage
+	branchStorage      *branch.BranchStorage
+	shiftStorage       *shift.ShiftStorage
+	paymentStorage     *payment.PaymentStorage
+	journal            *storage.Journal
+	files              []storage.File
+	events             *events.Outbox
+	webhooks           *webhook.Manager
+	notifier           *notify.Notifier
+	backups            *backup.Manager
+	clock              rental.Clock
+	metrics            *serverMetrics
+	rateLimits         map[string]*rateLimitGroup
+	cors               *corsPolicy
+	shutdown           chan struct{}
 }
 
-func NewAPIServer(listenAddr string, customerStorage *customer.CustomerStorage, vehicleStorage *vehicle.VehicleStorage, employeeStorage *employee.EmployeeStorage) *APIServer {
+func NewAPIServer(listenAddr string, serverConfig config.Server, customerStorage *customer.CustomerStorage, vehicleStorage *vehicle.VehicleStorage, employeeStorage *employee.EmployeeStorage, rentalStorage *rental.RentalStorage, damageStorage *damage.DamageStorage, documentStorage *document.DocumentStorage, reservationStorage *reservation.ReservationStorage, branchStorage *branch.BranchStorage, shiftStorage *shift.ShiftStorage, paymentStorage *payment.PaymentStorage, journal *storage.Journal, files []storage.File, outbox *events.Outbox, webhooks *webhook.Manager, notifier *notify.Notifier, backups *backup.Manager, clock rental.Clock) *APIServer {
+	if clock == nil {
+		clock = time.Now
+	}
+
 	return &APIServer{
-		listenAddr:      listenAddr,
-		customerStorage: customerStorage,
-		vehicleStorage:  vehicleStorage,
-		employeeStorage: employeeStorage,
+		listenAddr:         listenAddr,
+		serverConfig:       serverConfig,
+		customerStorage:    customerStorage,
+		vehicleStorage:     vehicleStorage,
+		employeeStorage:    employeeStorage,
+		rentalStorage:      rentalStorage,
+		damageStorage:      damageStorage,
+		documentStorage:    documentStorage,
+		reservationStorage: reservationStorage,
+		branchStorage:      branchStorage,
+		shiftStorage:       shiftStorage,
+		paymentStorage:     paymentStorage,
+		journal:            journal,
+		files:              files,
+		events:             outbox,
+		webhooks:           webhooks,
+		notifier:           notifier,
+		backups:            backups,
+		clock:              clock,
+		metrics:            newServerMetrics(),
+		rateLimits:         newRateLimitGroups(serverConfig.RateLimits),
+		cors:               newCORSPolicy(serverConfig.CORS),
+		shutdown:           make(chan struct{}),
 	}
 }
 
@@ -35,21 +96,142 @@ type APIError struct {
 	Error string `json:"error"`
 }
 
-func (s *APIServer) Run() {
+func (s *APIServer) routes() *mux.Router {
+	s.registerMetrics()
+
 	router := mux.NewRouter()
+	router.Use(s.loggingMiddleware, s.metricsMiddleware, s.corsMiddleware, s.rateLimitMiddleware, s.bodyLimitMiddleware)
+
+	router.HandleFunc("/healthz", s.handle((*APIServer).handleHealthz))
+	router.HandleFunc("/readyz", s.handle((*APIServer).handleReadyz))
+	router.HandleFunc("/metrics", s.handle((*APIServer).handleMetrics))
+	router.HandleFunc("/openapi.json", s.handle((*APIServer).handleOpenAPI))
+	router.HandleFunc("/docs", s.handle((*APIServer).handleDocs))
 
 	// /customers
-	router.HandleFunc("/customers", makeHTTPHandleFunc(s.handleCustomer))
-	router.HandleFunc("/customers/{personalID}/vehicles", makeHTTPHandleFunc(s.handleCustomerVehicle))
-	router.HandleFunc("/customers/{personalID}/{plateNumber}/delete-vehicle", makeHTTPHandleFunc(s.handleDeleteVehicleFromCustomer))
+	router.HandleFunc("/customers", s.handle((*APIServer).handleCustomer))
+	router.HandleFunc("/customers/import", s.handle((*APIServer).handleImportCustomers))
+	router.HandleFunc("/customers/export", s.handle((*APIServer).handleExportCustomers))
+	router.HandleFunc("/customers/{personalID}/vehicles", s.handle((*APIServer).handleCustomerVehicle))
+	router.HandleFunc("/customers/{personalID}/{plateNumber}/delete-vehicle", s.handle((*APIServer).handleDeleteVehicleFromCustomer))
+	router.HandleFunc("/customers/{personalID}/payments", s.handle((*APIServer).handleCustomerPayment))
+	router.HandleFunc("/customers/{personalID}/balance", s.handle((*APIServer).handleCustomerBalance))
+	router.HandleFunc("/customers/{personalID}/notifications", s.handle((*APIServer).handleCustomerNotifications))
+	router.HandleFunc("/customers/{personalID}/risk-flags", s.handle((*APIServer).handleCustomerRiskFlags))
+	router.HandleFunc("/customers/{personalID}/documents", s.handle((*APIServer).handleCustomerDocument))
+	router.HandleFunc("/customers/{personalID}/documents/{documentID}", s.handle((*APIServer).handleGetCustomerDocument))
+
+	router.HandleFunc("/vehicles", s.handle((*APIServer).handleVehicle))
+	router.HandleFunc("/vehicles/import", s.handle((*APIServer).handleImportVehicles))
+	router.HandleFunc("/vehicles/export", s.handle((*APIServer).handleExportVehicles))
+	router.HandleFunc("/vehicles/{plateNumber}/damages", s.handle((*APIServer).handleVehicleDamage))
+	router.HandleFunc("/vehicles/{plateNumber}/damages/{damageID}/repair", s.handle((*APIServer).handleRepairVehicleDamage))
+	router.HandleFunc("/vehicles/{plateNumber}/damages/photos/{photo}", s.handle((*APIServer).handleGetDamagePhoto))
+
+	router.HandleFunc("/employees", s.handle((*APIServer).handleEmployee))
+	router.HandleFunc("/employees/import", s.handle((*APIServer).handleImportEmployees))
+	router.HandleFunc("/employees/export", s.handle((*APIServer).handleExportEmployees))
+	router.HandleFunc("/employees/{personalID}/branch", s.handle((*APIServer).handleAssignEmployeeBranch))
+	router.HandleFunc("/employees/{personalID}/shifts", s.handle((*APIServer).handleEmployeeShift))
+	router.HandleFunc("/employees/{personalID}/activity", s.handle((*APIServer).handleEmployeeActivity))
+
+	router.HandleFunc("/branches", s.handle((*APIServer).handleBranch))
+
+	router.HandleFunc("/rentals/overdue", s.handle((*APIServer).handleOverdueRentals))
+
+	router.HandleFunc("/batch", s.handle((*APIServer).handleBatch))
+
+	router.HandleFunc("/reservations", s.handle((*APIServer).handleReservation))
+	router.HandleFunc("/reservations/{reservationID}/pickup", s.handle((*APIServer).handlePickupReservation))
+	router.HandleFunc("/reservations/{reservationID}/cancel", s.handle((*APIServer).handleCancelReservation))
+
+	router.HandleFunc("/events", s.handle((*APIServer).handleEvents))
+	router.HandleFunc("/events/stream", s.handle((*APIServer).handleEventStream))
+
+	router.HandleFunc("/webhooks", s.handle((*APIServer).handleWebhook))
+	router.HandleFunc("/webhooks/{webhookID}/deliveries", s.handle((*APIServer).handleWebhookDeliveries))
+	router.HandleFunc("/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", s.handle((*APIServer).handleRedeliverWebhook))
+
+	router.HandleFunc("/notifications/emails", s.handle((*APIServer).handleEmails))
+	router.HandleFunc("/notifications/templates", s.handle((*APIServer).handleEmailTemplates))
+	router.HandleFunc("/notifications/templates/{name}/preview", s.handle((*APIServer).handlePreviewEmailTemplate))
+
+	router.HandleFunc("/admin/backups", s.handle((*APIServer).handleBackups))
+
+	return router
+}
 
-	router.HandleFunc("/vehicles", makeHTTPHandleFunc(s.handleVehicle))
+func (s *APIServer) Handler() http.Handler {
+	return s.routes()
+}
 
-	router.HandleFunc("/employees", makeHTTPHandleFunc(s.handleEmployee))
+func (s *APIServer) Run(ctx context.Context) error {
+	router := s.routes()
+	if err := checkRouteDocs(router, operationDocs); err != nil {
+		return err
+	}
 
-	log.Println("JSON API server is running on port", s.listenAddr)
+	server := &http.Server{
+		Addr:              s.listenAddr,
+		Handler:           router,
+		ReadTimeout:       s.serverConfig.ReadTimeout.Duration,
+		ReadHeaderTimeout: s.serverConfig.ReadHeaderTimeout.Duration,
+		WriteTimeout:      s.serverConfig.WriteTimeout.Duration,
+		IdleTimeout:       s.serverConfig.IdleTimeout.Duration,
+		MaxHeaderBytes:    s.serverConfig.MaxHeaderBytes,
+	}
 
-	http.ListenAndServe(s.listenAddr, router)
+	// Long polls and event streams would otherwise hold Shutdown until its
+	// timeout.
+	server.RegisterOnShutdown(func() { close(s.shutdown) })
+
+	if s.serverConfig.TLSEnabled() {
+		reloader, err := newCertReloader(s.serverConfig.TLSCertFile, s.serverConfig.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("loading TLS certificate: %w", err)
+		}
+
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+
+	listener, err := net.Listen("tcp", s.listenAddr)
+	if err != nil {
+		return err
+	}
+
+	// Serve sets up HTTP/2 on the server, so decide on TLS before it starts.
+	useTLS := server.TLSConfig != nil
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if useTLS {
+			serveErr <- server.ServeTLS(listener, "", "")
+			return
+		}
+		serveErr <- server.Serve(listener)
+	}()
+
+	slog.Info("JSON API server is running", "addr", s.listenAddr, "tls", useTLS)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.serverConfig.ShutdownTimeout.Duration)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	return nil
 }
 
 func (s *APIServer) handleCustomer(w http.ResponseWriter, r *http.Request) error {
@@ -118,33 +300,64 @@ func (s *APIServer) handleGetCustomer(w http.ResponseWriter, _ *http.Request) er
 
 }
 
-func (s *APIServer) handleGetVehicle(w http.ResponseWriter, _ *http.Request) error {
+func (s *APIServer) handleGetVehicle(w http.ResponseWriter, r *http.Request) error {
 	vehicles, err := s.vehicleStorage.GetVehicles()
 
 	if err != nil {
 		return err
 	}
 
+	branchID, err := branchFilter(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if branchID != 0 {
+		for plateNumber, vehicle := range vehicles {
+			if vehicle.BranchID != branchID {
+				delete(vehicles, plateNumber)
+			}
+		}
+	}
+
 	return WriteJSON(w, http.StatusAccepted, vehicles)
 }
 
-func (s *APIServer) handleGetEmployee(w http.ResponseWriter, _ *http.Request) error {
+func (s *APIServer) handleGetEmployee(w http.ResponseWriter, r *http.Request) error {
 	employees, err := s.employeeStorage.GetEmployees()
 
 	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
+	branchID, err := branchFilter(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if branchID != 0 {
+		filtered := employee.Employees{}
+		for _, employee := range employees {
+			if employee.BranchID == branchID {
+				filtered = append(filtered, employee)
+			}
+		}
+		employees = filtered
+	}
+
 	return WriteJSON(w, http.StatusOK, employees)
 }
 
 func (s *APIServer) handleAddCustomer(w http.ResponseWriter, r *http.Request) error {
 	var newCustomer customer.Customer
-	if err := json.NewDecoder(r.Body).Decode(&newCustomer); err != nil {
+	if err := decodeJSON(r, &newCustomer); err != nil {
 		return err
 	}
 
-	if err := s.customerStorage.AddCustomer(newCustomer); err != nil {
+	err := s.transaction(func(tx *APIServer) error {
+		return tx.customerStorage.AddCustomer(newCustomer)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -153,43 +366,94 @@ func (s *APIServer) handleAddCustomer(w http.ResponseWriter, r *http.Request) er
 
 func (s *APIServer) handleAddVehicle(w http.ResponseWriter, r *http.Request) error {
 	var newVehicle vehicle.Vehicle
-	if err := json.NewDecoder(r.Body).Decode(&newVehicle); err != nil {
+	if err := decodeJSON(r, &newVehicle); err != nil {
 		return err
 	}
 
-	vehicle, err := s.vehicleStorage.AddVehicle(newVehicle)
+	var added vehicle.Vehicle
+	err := s.transaction(func(tx *APIServer) (err error) {
+		if err := tx.ensureBranchExists(newVehicle.BranchID); err != nil {
+			return err
+		}
+
+		added, err = tx.vehicleStorage.AddVehicle(newVehicle)
+		return err
+	})
 	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	return WriteJSON(w, http.StatusOK, vehicle)
+	return WriteJSON(w, http.StatusOK, added)
 }
 
 func (s *APIServer) handleAddEmployee(w http.ResponseWriter, r *http.Request) error {
 	var newEmployee employee.Employee
 
-	if err := json.NewDecoder(r.Body).Decode(&newEmployee); err != nil {
+	if err := decodeJSON(r, &newEmployee); err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	employee, err := s.employeeStorage.AddEmployee(newEmployee)
+	var added employee.Employee
+	err := s.transaction(func(tx *APIServer) (err error) {
+		if err := tx.ensureBranchExists(newEmployee.BranchID); err != nil {
+			return err
+		}
 
+		added, err = tx.employeeStorage.AddEmployee(newEmployee)
+		return err
+	})
 	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	return WriteJSON(w, http.StatusOK, employee)
+	return WriteJSON(w, http.StatusOK, added)
 }
 
 type CustomResponse struct {
 	Response string `json:"response"`
 }
 
+type PersonalIDRequest struct {
+	PersonalID int64 `json:"PersonalID"`
+}
+
+type PlateNumberRequest struct {
+	PlateNumber string `json:"PlateNumber"`
+}
+
+type IDRequest struct {
+	ID int `json:"ID"`
+}
+
+type EditCustomerRequest struct {
+	FirstName   string `json:"FirstName"`
+	LastName    string `json:"LastName"`
+	Email       string `json:"Email"`
+	PhoneNumber string `json:"PhoneNumber"`
+	PersonalID  int64  `json:"PersonalID"`
+}
+
+type EditEmployeeRequest struct {
+	PersonalID  int64  `json:"PersonalID"`
+	Email       string `json:"Email"`
+	PhoneNumber string `json:"PhoneNumber"`
+	Address     string `json:"Address"`
+}
+
+type CheckoutRequest struct {
+	vehicle.Vehicle
+	VehicleReading
+	DueBackAt *time.Time `json:"DueBackAt"`
+}
+
+type CheckInRequest struct {
+	VehicleReading
+	ReturnBranchID int `json:"ReturnBranchID"`
+}
+
 func (s *APIServer) handleDeleteCustomer(w http.ResponseWriter, r *http.Request) error {
-	var personalID struct {
-		PersonalID int64 `json:"PersonalID"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&personalID); err != nil {
+	var personalID PersonalIDRequest
+	if err := decodeJSON(r, &personalID); err != nil {
 		return err
 	}
 
@@ -199,7 +463,14 @@ func (s *APIServer) handleDeleteCustomer(w http.ResponseWriter, r *http.Request)
 		return WriteJSON(w, http.StatusBadRequest, ApiError{Error: "personalID must be exactly 11 digits"})
 	}
 
-	if err := s.customerStorage.DeleteCustomer(personalID.PersonalID); err != nil {
+	err := s.transaction(func(tx *APIServer) error {
+		if err := tx.customerStorage.DeleteCustomer(personalID.PersonalID); err != nil {
+			return err
+		}
+
+		return tx.documentStorage.DeleteCustomerDocuments(personalID.PersonalID)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -207,15 +478,16 @@ func (s *APIServer) handleDeleteCustomer(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *APIServer) handleDeleteVehicle(w http.ResponseWriter, r *http.Request) error {
-	var plateNumber struct {
-		PlateNumber string `json:"PlateNumber"`
-	}
+	var plateNumber PlateNumberRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&plateNumber); err != nil {
+	if err := decodeJSON(r, &plateNumber); err != nil {
 		return err
 	}
 
-	if err := s.vehicleStorage.DeleteVehicle(plateNumber.PlateNumber); err != nil {
+	err := s.transaction(func(tx *APIServer) error {
+		return tx.vehicleStorage.DeleteVehicle(plateNumber.PlateNumber)
+	})
+	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
@@ -229,46 +501,84 @@ func (s *APIServer) handleDeleteVehicleFromCustomer(w http.ResponseWriter, r *ht
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	plateNumber := vars["plateNumber"]
-	if err := s.vehicleStorage.GetVehicle(plateNumber); err != nil {
+	var input CheckInRequest
+	if err := decodeJSON(r, &input); err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	if err := s.customerStorage.DeleteVehicle(plateNumber, personalID); err != nil {
+	if err := input.VehicleReading.validate(); err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	return WriteJSON(w, http.StatusOK, CustomResponse{Response: "vehicle deleted from customer"})
+	if err := s.ensureBranchExists(input.ReturnBranchID); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	employeeID, err := s.handlingEmployee(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	plateNumber := vars["plateNumber"]
+	var returned rental.Rental
+	err = s.transaction(func(tx *APIServer) error {
+		if _, err := tx.vehicleStorage.GetVehicle(plateNumber); err != nil {
+			return err
+		}
+
+		returned, err = tx.rentalStorage.CheckIn(personalID, plateNumber, rental.Handover{
+			Odometer:   *input.Odometer,
+			FuelLevel:  *input.FuelLevel,
+			BranchID:   input.ReturnBranchID,
+			EmployeeID: employeeID,
+		}, tx.clock())
+		if err != nil {
+			return err
+		}
+
+		if err := tx.vehicleStorage.SetBranch(plateNumber, returned.ReturnBranchID); err != nil {
+			return err
+		}
+
+		if _, err := tx.paymentStorage.SettleRental(personalID, returned.ID, returned.TotalCharges()); err != nil {
+			return err
+		}
+
+		return tx.customerStorage.DeleteVehicle(plateNumber, personalID)
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, returned)
 }
 
 func (s *APIServer) handleDeleteEmployee(w http.ResponseWriter, r *http.Request) error {
-	var personalID struct {
-		PersonalID int64 `json:"PersonalID"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&personalID); err != nil {
+	var personalID PersonalIDRequest
+	if err := decodeJSON(r, &personalID); err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	if err := s.employeeStorage.DeleteEmployee(personalID.PersonalID); err != nil {
+	err := s.transaction(func(tx *APIServer) error {
+		return tx.employeeStorage.DeleteEmployee(personalID.PersonalID)
+	})
+	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 	return nil
 }
 
 func (s *APIServer) handleEditCustomer(w http.ResponseWriter, r *http.Request) error {
-	var editData struct {
-		FirstName   string `json:"FirstName"`
-		LastName    string `json:"LastName"`
-		Email       string `json:"Email"`
-		PhoneNumber string `json:"PhoneNumber"`
-		PersonalID  int64  `json:"PersonalID"`
-	}
+	var editData EditCustomerRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&editData); err != nil {
+	if err := decodeJSON(r, &editData); err != nil {
 		return err
 	}
 
-	if err := s.customerStorage.EditCustomer(editData.LastName, editData.FirstName, editData.Email, editData.PhoneNumber, editData.PersonalID); err != nil {
+	err := s.transaction(func(tx *APIServer) error {
+		return tx.customerStorage.EditCustomer(editData.FirstName, editData.LastName, editData.Email, editData.PhoneNumber, editData.PersonalID)
+	})
+	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
@@ -277,37 +587,43 @@ func (s *APIServer) handleEditCustomer(w http.ResponseWriter, r *http.Request) e
 
 func (s *APIServer) handleEditVehicle(w http.ResponseWriter, r *http.Request) error {
 	var editVehicle vehicle.Vehicle
-	if err := json.NewDecoder(r.Body).Decode(&editVehicle); err != nil {
+	if err := decodeJSON(r, &editVehicle); err != nil {
 		return err
 	}
-	vehicle, err := s.vehicleStorage.EditVehicle(editVehicle)
 
+	var edited vehicle.Vehicle
+	err := s.transaction(func(tx *APIServer) (err error) {
+		if err := tx.ensureBranchExists(editVehicle.BranchID); err != nil {
+			return err
+		}
+
+		edited, err = tx.vehicleStorage.EditVehicle(editVehicle)
+		return err
+	})
 	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	return WriteJSON(w, http.StatusOK, vehicle)
+	return WriteJSON(w, http.StatusOK, edited)
 }
 
 func (s *APIServer) handleEditEmployee(w http.ResponseWriter, r *http.Request) error {
-	var editCustomerData struct {
-		PersonalID  int64  `json:"PersonalID"`
-		Email       string `json:"Email"`
-		PhoneNumber string `json:"PhoneNumber"`
-		Address     string `json:"Address"`
-	}
+	var editCustomerData EditEmployeeRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&editCustomerData); err != nil {
+	if err := decodeJSON(r, &editCustomerData); err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	employee, err := s.employeeStorage.EditEmployeeContacts(editCustomerData.Email, editCustomerData.PhoneNumber, editCustomerData.Address, editCustomerData.PersonalID)
-
+	var edited employee.Employee
+	err := s.transaction(func(tx *APIServer) (err error) {
+		edited, err = tx.employeeStorage.EditEmployeeContacts(editCustomerData.Email, editCustomerData.PhoneNumber, editCustomerData.Address, editCustomerData.PersonalID)
+		return err
+	})
 	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	return WriteJSON(w, http.StatusOK, employee)
+	return WriteJSON(w, http.StatusOK, edited)
 }
 
 func (s *APIServer) handleAddVehicleToCustomer(w http.ResponseWriter, r *http.Request) error {
@@ -318,21 +634,135 @@ func (s *APIServer) handleAddVehicleToCustomer(w http.ResponseWriter, r *http.Re
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: personalIDErr.Error()})
 	}
 
-	var vehicle vehicle.Vehicle
-	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+	var input CheckoutRequest
+	if err := decodeJSON(r, &input); err != nil {
 		return err
 	}
 
-	if err := s.vehicleStorage.GetVehicle(vehicle.PlateNumber); err != nil {
+	if err := input.VehicleReading.validate(); err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	customer, err := s.customerStorage.AddVehicle(vehicle, personalID)
+	employeeID, err := s.handlingEmployee(r)
 	if err != nil {
 		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
 	}
 
-	return WriteJSON(w, http.StatusOK, customer)
+	managerID, err := s.overridingManager(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var checkedOut customer.Customer
+	err = s.transaction(func(tx *APIServer) error {
+		checkedOut, _, err = tx.checkoutVehicle(personalID, input.Vehicle, input.VehicleReading, input.DueBackAt, employeeID, managerID)
+		return err
+	})
+	if err != nil {
+		return writeError(w, err)
+	}
+
+	return WriteJSON(w, http.StatusOK, checkedOut)
+}
+
+func (s *APIServer) checkoutVehicle(personalID int64, input vehicle.Vehicle, reading VehicleReading, dueBackAt *time.Time, employeeID, managerID int64) (customer.Customer, rental.Rental, error) {
+	vehicle, err := s.vehicleStorage.GetVehicle(input.PlateNumber)
+	if err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	if err := s.ensureNoOpenDamages(vehicle.PlateNumber); err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	fleet, err := s.vehicleStorage.GetVehicles()
+	if err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	rented, err := s.rentalStorage.RentedPlates()
+	if err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	held, err := s.reservationStorage.IsHeldForOthers(vehicle, personalID, s.clock(), fleet, rented)
+	if err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	if held {
+		return customer.Customer{}, rental.Rental{}, fmt.Errorf("vehicle %v is held by another customer's reservation", vehicle.PlateNumber)
+	}
+
+	renter, err := s.customerStorage.GetCustomer(personalID)
+	if err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	overrideBy, err := s.checkCustomerRisk(renter, vehicle.PlateNumber, employeeID, managerID)
+	if err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	newRental, err := s.rentalStorage.Checkout(personalID, vehicle.PlateNumber, rental.Handover{
+		Odometer:   *reading.Odometer,
+		FuelLevel:  *reading.FuelLevel,
+		BranchID:   vehicle.BranchID,
+		EmployeeID: employeeID,
+		DueBackAt:  dueBackAt,
+		OverrideBy: overrideBy,
+	}, s.clock())
+	if err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	if _, err := s.paymentStorage.CaptureDeposit(personalID, newRental.ID); err != nil {
+		return customer.Customer{}, rental.Rental{}, err
+	}
+
+	customer, err := s.customerStorage.AddVehicle(vehicle, personalID)
+	if err != nil {
+		return customer, rental.Rental{}, err
+	}
+
+	return customer, newRental, nil
+}
+
+const employeeHeader = "X-Employee-ID"
+
+func (s *APIServer) handlingEmployee(r *http.Request) (int64, error) {
+	value := r.Header.Get(employeeHeader)
+	if value == "" {
+		return 0, fmt.Errorf("invalid input: %s header with the handling employee's personal ID is required", employeeHeader)
+	}
+
+	personalID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input: %s header must be a personal ID", employeeHeader)
+	}
+
+	if _, err := s.employeeStorage.GetEmployee(personalID); err != nil {
+		return 0, err
+	}
+
+	return personalID, nil
+}
+
+type VehicleReading struct {
+	Odometer  *int `json:"Odometer"`
+	FuelLevel *int `json:"FuelLevel"`
+}
+
+func (vr VehicleReading) validate() error {
+	if vr.Odometer == nil {
+		return errors.New("invalid input: odometer reading is required")
+	}
+
+	if vr.FuelLevel == nil {
+		return errors.New("invalid input: fuel or battery level reading is required")
+	}
+
+	return nil
 }
 
 type ApiFunc func(w http.ResponseWriter, r *http.Request) error
@@ -351,6 +781,7 @@ func WriteJSON(w http.ResponseWriter, status int, value any) error {
 func makeHTTPHandleFunc(f ApiFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := f(w, r); err != nil {
+			logging.FromContext(r.Context()).Warn("request failed", "error", err)
 			WriteJSON(w, http.StatusBadRequest, ApiError{Error: err.Error()})
 		}
 	}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (s *APIServer) handleBackups(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleListBackups(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleCreateBackup(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleListBackups(w http.ResponseWriter, r *http.Request) error {
+	snapshots, err := s.backups.List()
+	if err != nil {
+		return WriteJSON(w, http.StatusInternalServerError, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, snapshots)
+}
+
+func (s *APIServer) handleCreateBackup(w http.ResponseWriter, r *http.Request) error {
+	snapshot, err := s.backups.Snapshot()
+	if err != nil {
+		return WriteJSON(w, http.StatusInternalServerError, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusCreated, snapshot)
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+)
+
+const maxBatchOperations = 1000
+
+type BatchOperation struct {
+	Action   string          `json:"Action"`
+	Resource string          `json:"Resource"`
+	Data     json.RawMessage `json:"Data"`
+}
+
+type BatchRequest struct {
+	Operations      []BatchOperation `json:"Operations"`
+	RollbackOnError bool             `json:"RollbackOnError"`
+}
+
+type BatchResult struct {
+	Index    int    `json:"Index"`
+	Action   string `json:"Action"`
+	Resource string `json:"Resource"`
+	Error    string `json:"Error,omitempty"`
+	Result   any    `json:"Result,omitempty"`
+}
+
+type BatchResponse struct {
+	RollbackOnError bool
+	Committed       bool
+	Succeeded       int
+	Failed          int
+	Results         []BatchResult
+}
+
+// batchState holds one loaded copy of every storage file a batch touches, so
+// the whole batch costs a single Load and Save per file, committed together
+// in one transaction.
+type batchState struct {
+	customers    *customer.Batch
+	vehicles     *vehicle.Batch
+	employees    *employee.Batch
+	branchExists func(int) error
+	// deleted lists the customers deleted by the batch, whose documents are
+	// removed once the batch is committed.
+	deleted []int64
+}
+
+func decodeOperationData(data json.RawMessage, v any) error {
+	if len(data) == 0 {
+		return errors.New("operation data is required")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
+
+func (s *APIServer) handleBatch(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	var input BatchRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if len(input.Operations) == 0 {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: "invalid input: batch has no operations"})
+	}
+
+	if len(input.Operations) > maxBatchOperations {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: fmt.Sprintf("invalid input: batch may not contain more than %d operations", maxBatchOperations)})
+	}
+
+	response := BatchResponse{RollbackOnError: input.RollbackOnError, Results: make([]BatchResult, len(input.Operations))}
+
+	err := s.transaction(func(tx *APIServer) error {
+		state := &batchState{}
+
+		for idx, operation := range input.Operations {
+			result := BatchResult{Index: idx, Action: operation.Action, Resource: operation.Resource}
+
+			value, err := tx.applyBatchOperation(state, operation)
+			if err != nil {
+				result.Error = err.Error()
+				response.Failed++
+			} else {
+				result.Result = value
+				response.Succeeded++
+			}
+
+			response.Results[idx] = result
+		}
+
+		if input.RollbackOnError && response.Failed > 0 {
+			return nil
+		}
+
+		response.Committed = response.Succeeded > 0
+		if err := state.commit(); err != nil {
+			return err
+		}
+
+		for _, personalID := range state.deleted {
+			if err := tx.documentStorage.DeleteCustomerDocuments(personalID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, response)
+}
+
+func (state *batchState) commit() error {
+	if state.customers != nil {
+		if err := state.customers.Commit(); err != nil {
+			return err
+		}
+	}
+
+	if state.vehicles != nil {
+		if err := state.vehicles.Commit(); err != nil {
+			return err
+		}
+	}
+
+	if state.employees != nil {
+		if err := state.employees.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *APIServer) applyBatchOperation(state *batchState, operation BatchOperation) (any, error) {
+	switch operation.Resource {
+	case "customers":
+		if state.customers == nil {
+			batch, err := s.customerStorage.Begin()
+			if err != nil {
+				return nil, err
+			}
+			state.customers = batch
+		}
+
+		value, err := applyCustomerOperation(state.customers, operation)
+		if deleted, ok := value.(PersonalIDRequest); ok && err == nil {
+			state.deleted = append(state.deleted, deleted.PersonalID)
+		}
+
+		return value, err
+	case "vehicles":
+		if state.vehicles == nil {
+			batch, err := s.vehicleStorage.Begin()
+			if err != nil {
+				return nil, err
+			}
+			state.vehicles = batch
+		}
+
+		if state.branchExists == nil {
+			branchExists, err := s.branchCheck()
+			if err != nil {
+				return nil, err
+			}
+			state.branchExists = branchExists
+		}
+
+		return applyVehicleOperation(state.vehicles, state.branchExists, operation)
+	case "employees":
+		if state.employees == nil {
+			batch, err := s.employeeStorage.Begin()
+			if err != nil {
+				return nil, err
+			}
+			state.employees = batch
+		}
+
+		if state.branchExists == nil {
+			branchExists, err := s.branchCheck()
+			if err != nil {
+				return nil, err
+			}
+			state.branchExists = branchExists
+		}
+
+		return applyEmployeeOperation(state.employees, state.branchExists, operation)
+	default:
+		return nil, fmt.Errorf("invalid input: unknown resource %q, expected customers, vehicles or employees", operation.Resource)
+	}
+}
+
+func unknownAction(action string) error {
+	return fmt.Errorf("invalid input: unknown action %q, expected create, update or delete", action)
+}
+
+func applyCustomerOperation(batch *customer.Batch, operation BatchOperation) (any, error) {
+	switch operation.Action {
+	case "create":
+		var input customer.Customer
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		return batch.AddCustomer(input)
+	case "update":
+		var input EditCustomerRequest
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		return batch.EditCustomer(input.FirstName, input.LastName, input.Email, input.PhoneNumber, input.PersonalID)
+	case "delete":
+		var input PersonalIDRequest
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		if utils.IntLength(input.PersonalID) != 11 {
+			return nil, errors.New("personalID must be exactly 11 digits")
+		}
+
+		return input, batch.DeleteCustomer(input.PersonalID)
+	default:
+		return nil, unknownAction(operation.Action)
+	}
+}
+
+func applyVehicleOperation(batch *vehicle.Batch, branchExists func(int) error, operation BatchOperation) (any, error) {
+	switch operation.Action {
+	case "create", "update":
+		var input vehicle.Vehicle
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		if err := branchExists(input.BranchID); err != nil {
+			return nil, err
+		}
+
+		if operation.Action == "create" {
+			return batch.AddVehicle(input)
+		}
+
+		return batch.EditVehicle(input)
+	case "delete":
+		var input PlateNumberRequest
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		return input, batch.DeleteVehicle(input.PlateNumber)
+	default:
+		return nil, unknownAction(operation.Action)
+	}
+}
+
+func applyEmployeeOperation(batch *employee.Batch, branchExists func(int) error, operation BatchOperation) (any, error) {
+	switch operation.Action {
+	case "create":
+		var input employee.Employee
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		if err := branchExists(input.BranchID); err != nil {
+			return nil, err
+		}
+
+		return batch.AddEmployee(input)
+	case "update":
+		var input EditEmployeeRequest
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		return batch.EditEmployeeContacts(input.Email, input.PhoneNumber, input.Address, input.PersonalID)
+	case "delete":
+		var input PersonalIDRequest
+		if err := decodeOperationData(operation.Data, &input); err != nil {
+			return nil, err
+		}
+
+		return input, batch.DeleteEmployee(input.PersonalID)
+	default:
+		return nil, unknownAction(operation.Action)
+	}
+}
@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/api"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const batchOperations = `[
+	{"Action": "create", "Resource": "customers", "Data": {"FirstName": "Liis", "LastName": "Saar", "PersonalID": 49001010002, "PhoneNumber": "5551002", "Email": "liis.saar@example.com"}},
+	{"Action": "update", "Resource": "customers", "Data": {"PersonalID": 49001010001, "FirstName": "Maria", "LastName": "Kuusk"}},
+	{"Action": "create", "Resource": "vehicles", "Data": {"PlateNumber": "456DEF", "Make": "Toyota", "Model": "Corolla", "Year": 2020, "FuelType": "Petrol", "Gearbox": "Automatic", "Color": "White", "Body": "Sedan"}},
+	{"Action": "delete", "Resource": "vehicles", "Data": {"PlateNumber": "123ABC"}},
+	{"Action": "create", "Resource": "employees", "Data": {"FirstName": "Jaan", "LastName": "Kask", "PersonalID": 38001010000, "DateOfBirth": "01.01.1980", "Email": "jaan.kask@example.com", "PhoneNumber": "5551234", "Address": "Tartu mnt 1, Tallinn"}},
+	{"Action": "delete", "Resource": "customers", "Data": {"PersonalID": 49001010009}}
+]`
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		rollbackOnError bool
+		wantCommitted   bool
+	}{
+		{"rollback on error", true, false},
+		{"keep going on error", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storages, cfg := newTestStorages(t)
+			if err := storages.Customers.GetStorage().Save(customer.Customers{{FirstName: "Mari", LastName: "Tamm", PersonalID: 49001010001, CreatedAt: time.Now()}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := storages.Vehicles.GetStorage().Save(vehicle.Vehicles{"123ABC": {PlateNumber: "123ABC", Make: "Toyota", Model: "Corolla"}}); err != nil {
+				t.Fatal(err)
+			}
+			handler := storages.NewAPIServer(cfg).Handler()
+
+			// Count the loads and saves of every file once the server has
+			// set up its own observer.
+			counts := map[string]int{}
+			storage.SetObserver(func(operation, fileName string, _ time.Duration) {
+				counts[operation+" "+filepath.Base(fileName)]++
+			})
+			t.Cleanup(func() { storage.SetObserver(nil) })
+
+			body := `{"RollbackOnError": ` + strconv.FormatBool(test.rollbackOnError) + `, "Operations": ` + batchOperations + `}`
+			req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+			}
+
+			var response api.BatchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			if response.Committed != test.wantCommitted || response.Succeeded != 5 || response.Failed != 1 || len(response.Results) != 6 {
+				t.Fatalf("response = %+v", response)
+			}
+			for idx, result := range response.Results {
+				if result.Index != idx || (result.Error != "") != (idx == 5) {
+					t.Errorf("result %d = %+v, want only the last operation to fail", idx, result)
+				}
+			}
+
+			for _, file := range []string{"customers.json", "vehicles.json", "employees.json"} {
+				if loads := counts["load "+file]; loads != 1 {
+					t.Errorf("%s loaded %d times, want once", file, loads)
+				}
+
+				wantSaves := 0
+				if test.wantCommitted {
+					wantSaves = 1
+				}
+				if saves := counts["save "+file]; saves != wantSaves {
+					t.Errorf("%s saved %d times, want %d", file, saves, wantSaves)
+				}
+			}
+
+			storage.SetObserver(nil)
+
+			customers, err := storages.Customers.GetCustomers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			vehicles, err := storages.Vehicles.GetVehicles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			employees, err := storages.Employees.GetEmployees()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, kept := vehicles["123ABC"]
+			applied := len(customers) == 2 && customers[0].FirstName == "Maria" && len(vehicles) == 1 && !kept && len(employees) == 1
+			unchanged := len(customers) == 1 && customers[0].FirstName == "Mari" && len(vehicles) == 1 && kept && len(employees) == 0
+
+			if test.wantCommitted && !applied {
+				t.Errorf("batch not applied: customers %+v, vehicles %+v, employees %+v", customers, vehicles, employees)
+			}
+			if !test.wantCommitted && !unchanged {
+				t.Errorf("batch not rolled back: customers %+v, vehicles %+v, employees %+v", customers, vehicles, employees)
+			}
+		})
+	}
+}
+
+func TestBatchRejectsEmptyBatch(t *testing.T) {
+	storages, cfg := newTestStorages(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{"Operations": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	storages.NewAPIServer(cfg).Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/branch"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *APIServer) handleBranch(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetBranches(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleAddBranch(w, r)
+	}
+	if r.Method == "DELETE" {
+		return s.handleDeleteBranch(w, r)
+	}
+	if r.Method == "PUT" {
+		return s.handleEditBranch(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleGetBranches(w http.ResponseWriter, _ *http.Request) error {
+	branches, err := s.branchStorage.GetBranches()
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, branches)
+}
+
+func (s *APIServer) handleAddBranch(w http.ResponseWriter, r *http.Request) error {
+	var newBranch branch.Branch
+	if err := decodeJSON(r, &newBranch); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	branch, err := s.branchStorage.AddBranch(newBranch)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, branch)
+}
+
+func (s *APIServer) handleEditBranch(w http.ResponseWriter, r *http.Request) error {
+	var editBranch branch.Branch
+	if err := decodeJSON(r, &editBranch); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	branch, err := s.branchStorage.EditBranch(editBranch)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, branch)
+}
+
+func (s *APIServer) handleDeleteBranch(w http.ResponseWriter, r *http.Request) error {
+	var branchID IDRequest
+	if err := decodeJSON(r, &branchID); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	err := s.transaction(func(tx *APIServer) error {
+		vehicles, err := tx.vehicleStorage.GetVehicles()
+		if err != nil {
+			return err
+		}
+
+		for _, vehicle := range vehicles {
+			if vehicle.BranchID == branchID.ID {
+				return fmt.Errorf("branch with ID %d still has vehicles assigned", branchID.ID)
+			}
+		}
+
+		employees, err := tx.employeeStorage.GetEmployees()
+		if err != nil {
+			return err
+		}
+
+		for _, employee := range employees {
+			if employee.BranchID == branchID.ID {
+				return fmt.Errorf("branch with ID %d still has employees assigned", branchID.ID)
+			}
+		}
+
+		return tx.branchStorage.DeleteBranch(branchID.ID)
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, CustomResponse{Response: "branch deleted"})
+}
+
+type AssignBranchRequest struct {
+	BranchID int `json:"BranchID"`
+}
+
+func (s *APIServer) handleAssignEmployeeBranch(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	personalID, err := strconv.ParseInt(mux.Vars(r)["personalID"], 10, 64)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var input AssignBranchRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var assigned employee.Employee
+	err = s.transaction(func(tx *APIServer) error {
+		if err := tx.ensureBranchExists(input.BranchID); err != nil {
+			return err
+		}
+
+		assigned, err = tx.employeeStorage.SetBranch(personalID, input.BranchID)
+		return err
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, assigned)
+}
+
+func (s *APIServer) ensureBranchExists(branchID int) error {
+	if branchID == 0 {
+		return nil
+	}
+
+	_, err := s.branchStorage.GetBranch(branchID)
+	return err
+}
+
+func branchFilter(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("branch")
+	if value == "" {
+		return 0, nil
+	}
+
+	branchID, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid branch filter %q", value)
+	}
+
+	return branchID, nil
+}
@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/app"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/branch"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+)
+
+const (
+	branchCustomerID = 49001010005
+	branchEmployeeID = 38001010005
+)
+
+// newBranchServer seeds two branches, with vehicle 123ABC and an employee
+// at branch 1 and vehicle 456DEF at branch 2. Branch 3 is empty.
+func newBranchServer(t *testing.T) (*app.Storages, http.Handler) {
+	t.Helper()
+
+	storages, cfg := newTestStorages(t)
+
+	if err := storages.Branches.GetStorage().Save(branch.Branches{
+		{ID: 1, Name: "Tallinn", Address: "Tartu mnt 1, Tallinn", OpeningHours: "08-20"},
+		{ID: 2, Name: "Tartu", Address: "Riia 10, Tartu", OpeningHours: "09-18"},
+		{ID: 3, Name: "Narva", Address: "Puškini 5, Narva", OpeningHours: "10-16"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Vehicles.GetStorage().Save(vehicle.Vehicles{
+		"123ABC": {PlateNumber: "123ABC", Make: "Toyota", Model: "Corolla", BranchID: 1},
+		"456DEF": {PlateNumber: "456DEF", Make: "Skoda", Model: "Octavia", BranchID: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Employees.GetStorage().Save(employee.Employees{{FirstName: "Jaan", LastName: "Kask", PersonalID: branchEmployeeID, BranchID: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Customers.GetStorage().Save(customer.Customers{{FirstName: "Mari", LastName: "Tamm", PersonalID: branchCustomerID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return storages, storages.NewAPIServer(cfg).Handler()
+}
+
+func serve(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Employee-ID", strconv.Itoa(branchEmployeeID))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestBranchFilter(t *testing.T) {
+	_, handler := newBranchServer(t)
+
+	tests := []struct {
+		query        string
+		wantVehicles []string
+		wantStaff    int
+	}{
+		{"", []string{"123ABC", "456DEF"}, 1},
+		{"?branch=1", []string{"123ABC"}, 1},
+		{"?branch=2", []string{"456DEF"}, 0},
+		{"?branch=3", []string{}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rec := serve(handler, http.MethodGet, "/vehicles"+test.query, "")
+			var vehicles vehicle.Vehicles
+			if err := json.Unmarshal(rec.Body.Bytes(), &vehicles); err != nil {
+				t.Fatal(err)
+			}
+			if len(vehicles) != len(test.wantVehicles) {
+				t.Errorf("vehicles = %v, want %v", vehicles, test.wantVehicles)
+			}
+			for _, plate := range test.wantVehicles {
+				if _, ok := vehicles[plate]; !ok {
+					t.Errorf("vehicles = %v, missing %s", vehicles, plate)
+				}
+			}
+
+			rec = serve(handler, http.MethodGet, "/employees"+test.query, "")
+			var employees employee.Employees
+			if err := json.Unmarshal(rec.Body.Bytes(), &employees); err != nil {
+				t.Fatal(err)
+			}
+			if len(employees) != test.wantStaff {
+				t.Errorf("employees = %+v, want %d", employees, test.wantStaff)
+			}
+		})
+	}
+
+	for _, target := range []string{"/vehicles?branch=north", "/employees?branch=north"} {
+		if rec := serve(handler, http.MethodGet, target, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want %d", target, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestDeleteBranch(t *testing.T) {
+	tests := []struct {
+		name       string
+		branchID   int
+		wantStatus int
+		wantError  string
+	}{
+		{"with vehicles and employees", 1, http.StatusBadRequest, "vehicles assigned"},
+		{"with vehicles", 2, http.StatusBadRequest, "vehicles assigned"},
+		{"empty", 3, http.StatusOK, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storages, handler := newBranchServer(t)
+
+			rec := serve(handler, http.MethodDelete, "/branches", `{"ID": `+strconv.Itoa(test.branchID)+`}`)
+			if rec.Code != test.wantStatus || !strings.Contains(rec.Body.String(), test.wantError) {
+				t.Fatalf("status = %d, want %d with %q: %s", rec.Code, test.wantStatus, test.wantError, rec.Body)
+			}
+
+			_, err := storages.Branches.GetBranch(test.branchID)
+			if deleted := err != nil; deleted != (test.wantStatus == http.StatusOK) {
+				t.Errorf("branch deleted = %t, want %t", deleted, test.wantStatus == http.StatusOK)
+			}
+		})
+	}
+
+	t.Run("with employees", func(t *testing.T) {
+		storages, handler := newBranchServer(t)
+		if err := storages.Vehicles.SetBranch("123ABC", 2); err != nil {
+			t.Fatal(err)
+		}
+
+		rec := serve(handler, http.MethodDelete, "/branches", `{"ID": 1}`)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "employees assigned") {
+			t.Fatalf("status = %d, want %d for assigned employees: %s", rec.Code, http.StatusBadRequest, rec.Body)
+		}
+	})
+}
+
+func TestOneWayFee(t *testing.T) {
+	tests := []struct {
+		name           string
+		returnBranchID int
+		wantFee        float64
+		wantBranchID   int
+	}{
+		{"returned to the pickup branch", 1, 0, 1},
+		{"returned by default to the pickup branch", 0, 0, 1},
+		{"returned to another branch", 2, 50, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storages, handler := newBranchServer(t)
+
+			rec := serve(handler, http.MethodPost, "/customers/"+strconv.Itoa(branchCustomerID)+"/vehicles", `{"PlateNumber": "123ABC", "Odometer": 1000, "FuelLevel": 80}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("checkout status = %d: %s", rec.Code, rec.Body)
+			}
+
+			body := `{"Odometer": 1100, "FuelLevel": 80, "ReturnBranchID": ` + strconv.Itoa(test.returnBranchID) + `}`
+			rec = serve(handler, http.MethodPost, "/customers/"+strconv.Itoa(branchCustomerID)+"/123ABC/delete-vehicle", body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("check-in status = %d: %s", rec.Code, rec.Body)
+			}
+
+			var returned rental.Rental
+			if err := json.Unmarshal(rec.Body.Bytes(), &returned); err != nil {
+				t.Fatal(err)
+			}
+			if returned.PickupBranchID != 1 || returned.ReturnBranchID != test.wantBranchID || returned.OneWayFee != test.wantFee {
+				t.Errorf("rental from branch %d to %d with one-way fee %.2f, want 1 to %d with %.2f",
+					returned.PickupBranchID, returned.ReturnBranchID, returned.OneWayFee, test.wantBranchID, test.wantFee)
+			}
+
+			moved, err := storages.Vehicles.GetVehicle("123ABC")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved.BranchID != test.wantBranchID {
+				t.Errorf("vehicle at branch %d, want %d", moved.BranchID, test.wantBranchID)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+)
+
+type corsPolicy struct {
+	config.CORS
+	anyOrigin bool
+}
+
+func newCORSPolicy(cors config.CORS) *corsPolicy {
+	return &corsPolicy{CORS: cors, anyOrigin: slices.Contains(cors.AllowedOrigins, "*")}
+}
+
+func (cp *corsPolicy) originAllowed(origin string) bool {
+	return cp.anyOrigin || slices.Contains(cp.AllowedOrigins, origin)
+}
+
+func (cp *corsPolicy) methodAllowed(method string) bool {
+	return slices.Contains(cp.AllowedMethods, strings.ToUpper(method))
+}
+
+func (cp *corsPolicy) headersAllowed(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+
+		if !slices.ContainsFunc(cp.AllowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cp *corsPolicy) setOriginHeaders(w http.ResponseWriter, origin string) {
+	if cp.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if cp.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (s *APIServer) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := s.cors.originAllowed(origin)
+
+		requestedMethod := r.Header.Get("Access-Control-Request-Method")
+		if r.Method != http.MethodOptions || requestedMethod == "" {
+			if allowed {
+				s.cors.setOriginHeaders(w, origin)
+				if len(s.cors.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(s.cors.ExposedHeaders, ", "))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+		requestedHeaders := r.Header.Get("Access-Control-Request-Headers")
+
+		if !allowed {
+			WriteJSON(w, http.StatusForbidden, APIError{Error: "origin not allowed: " + origin})
+			return
+		}
+
+		if !s.cors.methodAllowed(requestedMethod) {
+			WriteJSON(w, http.StatusForbidden, APIError{Error: "method not allowed for cross-origin requests: " + requestedMethod})
+			return
+		}
+
+		if !s.cors.headersAllowed(requestedHeaders) {
+			WriteJSON(w, http.StatusForbidden, APIError{Error: "headers not allowed for cross-origin requests: " + requestedHeaders})
+			return
+		}
+
+		s.cors.setOriginHeaders(w, origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(s.cors.AllowedMethods, ", "))
+		if requestedHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(s.cors.AllowedHeaders, ", "))
+		}
+		if s.cors.MaxAge.Duration > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(s.cors.MaxAge.Seconds())))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+)
+
+const allowedOrigin = "https://desk.example.com"
+
+func newCORSServer(t *testing.T) http.Handler {
+	t.Helper()
+
+	storages, cfg := newTestStoragesWith(t, func(cfg *config.Config) {
+		cfg.Server.CORS.AllowedOrigins = []string{allowedOrigin}
+		cfg.Server.CORS.MaxAge = config.Duration{Duration: 5 * time.Minute}
+	})
+
+	return storages.NewAPIServer(cfg).Handler()
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := newCORSServer(t)
+
+	tests := []struct {
+		name       string
+		origin     string
+		method     string
+		headers    string
+		wantStatus int
+	}{
+		{"allowed", allowedOrigin, http.MethodPost, "Content-Type, x-employee-id", http.StatusNoContent},
+		{"allowed without headers", allowedOrigin, http.MethodDelete, "", http.StatusNoContent},
+		{"disallowed origin", "https://evil.example.com", http.MethodPost, "Content-Type", http.StatusForbidden},
+		{"disallowed method", allowedOrigin, http.MethodPatch, "Content-Type", http.StatusForbidden},
+		{"disallowed header", allowedOrigin, http.MethodPost, "Content-Type, X-Debug", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/customers/49001010001/vehicles", nil)
+			req.Header.Set("Origin", test.origin)
+			req.Header.Set("Access-Control-Request-Method", test.method)
+			if test.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", test.headers)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, test.wantStatus, rec.Body)
+			}
+
+			vary := rec.Header().Values("Vary")
+			for _, header := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+				if !slices.Contains(vary, header) {
+					t.Errorf("Vary = %v, missing %s", vary, header)
+				}
+			}
+
+			allowOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+			if test.wantStatus != http.StatusNoContent {
+				if allowOrigin != "" {
+					t.Errorf("rejected preflight allowed origin %q", allowOrigin)
+				}
+				return
+			}
+
+			if allowOrigin != allowedOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", allowOrigin, allowedOrigin)
+			}
+			if methods := rec.Header().Get("Access-Control-Allow-Methods"); methods != "GET, POST, PUT, DELETE" {
+				t.Errorf("Access-Control-Allow-Methods = %q", methods)
+			}
+			if maxAge := rec.Header().Get("Access-Control-Max-Age"); maxAge != "300" {
+				t.Errorf("Access-Control-Max-Age = %q, want 300", maxAge)
+			}
+
+			allowHeaders := rec.Header().Get("Access-Control-Allow-Headers")
+			if (test.headers != "") != (allowHeaders != "") {
+				t.Errorf("Access-Control-Allow-Headers = %q for requested headers %q", allowHeaders, test.headers)
+			}
+		})
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	handler := newCORSServer(t)
+
+	tests := []struct {
+		name            string
+		origin          string
+		wantAllowOrigin string
+		wantVary        bool
+	}{
+		{"allowed origin", allowedOrigin, allowedOrigin, true},
+		{"disallowed origin", "https://evil.example.com", "", true},
+		{"same origin", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/customers", nil)
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+
+			if allowOrigin := rec.Header().Get("Access-Control-Allow-Origin"); allowOrigin != test.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", allowOrigin, test.wantAllowOrigin)
+			}
+			if vary := slices.Contains(rec.Header().Values("Vary"), "Origin"); vary != test.wantVary {
+				t.Errorf("Vary: Origin set = %t, want %t", vary, test.wantVary)
+			}
+
+			exposed := rec.Header().Get("Access-Control-Expose-Headers")
+			if (test.wantAllowOrigin != "") != (exposed != "") {
+				t.Errorf("Access-Control-Expose-Headers = %q", exposed)
+			}
+		})
+	}
+}
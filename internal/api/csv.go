@@ -0,0 +1,558 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+)
+
+const (
+	importAllOrNothing = "all-or-nothing"
+	importBestEffort   = "best-effort"
+)
+
+var (
+	vehicleColumns  = []string{"PlateNumber", "Make", "Model", "Year", "FuelType", "Gearbox", "Color", "Body", "BranchID"}
+	customerColumns = []string{"PersonalID", "FirstName", "LastName", "PhoneNumber", "Email"}
+	employeeColumns = []string{"PersonalID", "FirstName", "LastName", "DateOfBirth", "Email", "PhoneNumber", "Address", "BranchID"}
+	optionalColumns = []string{"BranchID"}
+)
+
+// ImportOptions are the query options of an import. A dry run only reports
+// what would be imported; all-or-nothing commits nothing when a row fails.
+type ImportOptions struct {
+	DryRun       bool
+	AllOrNothing bool
+}
+
+// Commit tells whether rows are saved given how many of them failed.
+func (opts ImportOptions) Commit(failed int) bool {
+	return !opts.DryRun && !(opts.AllOrNothing && failed > 0)
+}
+
+type ImportRow struct {
+	Line  int    `json:"Line"`
+	Key   string `json:"Key"`
+	Error string `json:"Error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun    bool
+	Mode      string
+	Committed bool
+	Total     int
+	Valid     int
+	Failed    int
+	Rows      []ImportRow
+}
+
+type csvRecord struct {
+	line   int
+	values map[string]string
+}
+
+func normalizeColumn(name string) string {
+	return strings.Map(func(char rune) rune {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			return unicode.ToLower(char)
+		}
+		return -1
+	}, name)
+}
+
+// columnMapping resolves CSV headers to field names. Headers match fields
+// ignoring case, spaces and punctuation; ?map=Header:Field overrides that
+// and ?map=Header:- ignores a column.
+func columnMapping(r *http.Request, header, fields []string) ([]string, error) {
+	explicit := map[string]string{}
+	for _, value := range r.URL.Query()["map"] {
+		column, field, ok := strings.Cut(value, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid column mapping %q, expected Header:Field", value)
+		}
+
+		if field != "-" && !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("column mapping %q names unknown field %q, expected one of %s", value, field, strings.Join(fields, ", "))
+		}
+
+		explicit[normalizeColumn(column)] = field
+	}
+
+	mapping := make([]string, len(header))
+	seen := map[string]bool{}
+	unknown := []string{}
+
+	for idx, column := range header {
+		field, ok := explicit[normalizeColumn(column)]
+		if !ok {
+			for _, candidate := range fields {
+				if normalizeColumn(candidate) == normalizeColumn(column) {
+					field, ok = candidate, true
+				}
+			}
+		}
+
+		if !ok {
+			unknown = append(unknown, column)
+			continue
+		}
+
+		if field == "-" {
+			continue
+		}
+
+		if seen[field] {
+			return nil, fmt.Errorf("field %s is mapped from more than one column", field)
+		}
+
+		seen[field] = true
+		mapping[idx] = field
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown columns %s, map them with ?map=Header:Field or ignore them with ?map=Header:-", strings.Join(unknown, ", "))
+	}
+
+	missing := []string{}
+	for _, field := range fields {
+		if !seen[field] && !slices.Contains(optionalColumns, field) {
+			missing = append(missing, field)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing columns %s", strings.Join(missing, ", "))
+	}
+
+	return mapping, nil
+}
+
+func readCSV(r *http.Request, fields []string) ([]csvRecord, error) {
+	reader := csv.NewReader(r.Body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("CSV body is empty, expected a header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	mapping, err := columnMapping(r, header, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []csvRecord{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		values := map[string]string{}
+		for idx, value := range row {
+			if mapping[idx] != "" {
+				values[mapping[idx]] = strings.TrimSpace(value)
+			}
+		}
+
+		records = append(records, csvRecord{line: line, values: values})
+	}
+
+	return records, nil
+}
+
+func importOptions(r *http.Request) (ImportOptions, string, error) {
+	query := r.URL.Query()
+	opts := ImportOptions{AllOrNothing: true}
+
+	mode := query.Get("mode")
+	switch mode {
+	case "", importAllOrNothing:
+		mode = importAllOrNothing
+	case importBestEffort:
+		opts.AllOrNothing = false
+	default:
+		return opts, "", fmt.Errorf("invalid import mode %q, expected %s or %s", mode, importAllOrNothing, importBestEffort)
+	}
+
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, "", fmt.Errorf("invalid dry_run value %q", value)
+		}
+		opts.DryRun = dryRun
+	}
+
+	return opts, mode, nil
+}
+
+func optionalInt(values map[string]string, field string) (int, error) {
+	if values[field] == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(values[field])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", field, values[field])
+	}
+
+	return value, nil
+}
+
+func personalIDValue(values map[string]string) (int64, error) {
+	personalID, err := strconv.ParseInt(values["PersonalID"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PersonalID %q", values["PersonalID"])
+	}
+
+	return personalID, nil
+}
+
+func vehicleFromCSV(values map[string]string) (vehicle.Vehicle, error) {
+	year, err := strconv.Atoi(values["Year"])
+	if err != nil {
+		return vehicle.Vehicle{}, fmt.Errorf("invalid Year %q", values["Year"])
+	}
+
+	branchID, err := optionalInt(values, "BranchID")
+	if err != nil {
+		return vehicle.Vehicle{}, err
+	}
+
+	return vehicle.Vehicle{
+		PlateNumber: values["PlateNumber"],
+		Make:        values["Make"],
+		Model:       values["Model"],
+		Year:        year,
+		FuelType:    values["FuelType"],
+		Gearbox:     values["Gearbox"],
+		Color:       values["Color"],
+		Body:        values["Body"],
+		BranchID:    branchID,
+	}, nil
+}
+
+func customerFromCSV(values map[string]string) (customer.Customer, error) {
+	personalID, err := personalIDValue(values)
+	if err != nil {
+		return customer.Customer{}, err
+	}
+
+	return customer.Customer{
+		PersonalID:  personalID,
+		FirstName:   values["FirstName"],
+		LastName:    values["LastName"],
+		PhoneNumber: values["PhoneNumber"],
+		Email:       values["Email"],
+	}, nil
+}
+
+func employeeFromCSV(values map[string]string) (employee.Employee, error) {
+	personalID, err := personalIDValue(values)
+	if err != nil {
+		return employee.Employee{}, err
+	}
+
+	branchID, err := optionalInt(values, "BranchID")
+	if err != nil {
+		return employee.Employee{}, err
+	}
+
+	return employee.Employee{
+		PersonalID:  personalID,
+		FirstName:   values["FirstName"],
+		LastName:    values["LastName"],
+		DateOfBirth: values["DateOfBirth"],
+		Email:       values["Email"],
+		PhoneNumber: values["PhoneNumber"],
+		Address:     values["Address"],
+		BranchID:    branchID,
+	}, nil
+}
+
+// importRecords converts the CSV records and hands the convertible ones to
+// store. Conversion failures count as failed rows, so in all-or-nothing mode
+// they keep store from committing anything.
+func importRecords[T any](records []csvRecord, keyField string, opts ImportOptions, mode string, convert func(map[string]string) (T, error), store func([]T, func(int) bool) ([]error, error)) (ImportReport, error) {
+	report := ImportReport{DryRun: opts.DryRun, Mode: mode, Total: len(records), Rows: make([]ImportRow, len(records))}
+
+	inputs := []T{}
+	positions := []int{}
+	for idx, record := range records {
+		report.Rows[idx] = ImportRow{Line: record.line, Key: record.values[keyField]}
+
+		input, err := convert(record.values)
+		if err != nil {
+			report.Rows[idx].Error = err.Error()
+			report.Failed++
+			continue
+		}
+
+		inputs = append(inputs, input)
+		positions = append(positions, idx)
+	}
+
+	storeOpts := opts
+	if opts.AllOrNothing && report.Failed > 0 {
+		storeOpts.DryRun = true
+	}
+
+	rowErrors, err := store(inputs, storeOpts.Commit)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	for idx, rowErr := range rowErrors {
+		if rowErr != nil {
+			report.Rows[positions[idx]].Error = rowErr.Error()
+			report.Failed++
+		}
+	}
+
+	report.Valid = report.Total - report.Failed
+	report.Committed = opts.Commit(report.Failed) && report.Valid > 0
+
+	return report, nil
+}
+
+func (s *APIServer) branchCheck() (func(int) error, error) {
+	branches, err := s.branchStorage.GetBranches()
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[int]bool{}
+	for _, branch := range branches {
+		known[branch.ID] = true
+	}
+
+	return func(branchID int) error {
+		if branchID != 0 && !known[branchID] {
+			return fmt.Errorf("branch with ID %d not found", branchID)
+		}
+		return nil
+	}, nil
+}
+
+func (s *APIServer) handleImportVehicles(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	opts, mode, err := importOptions(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	records, err := readCSV(r, vehicleColumns)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var report ImportReport
+	err = s.transaction(func(tx *APIServer) error {
+		branchExists, err := tx.branchCheck()
+		if err != nil {
+			return err
+		}
+
+		report, err = importRecords(records, "PlateNumber", opts, mode, vehicleFromCSV, func(inputs []vehicle.Vehicle, commit func(int) bool) ([]error, error) {
+			return tx.vehicleStorage.ImportVehicles(inputs, commit, func(v vehicle.Vehicle) error { return branchExists(v.BranchID) })
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, report)
+}
+
+func (s *APIServer) handleImportCustomers(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	opts, mode, err := importOptions(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	records, err := readCSV(r, customerColumns)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var report ImportReport
+	err = s.transaction(func(tx *APIServer) error {
+		report, err = importRecords(records, "PersonalID", opts, mode, customerFromCSV, tx.customerStorage.ImportCustomers)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, report)
+}
+
+func (s *APIServer) handleImportEmployees(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	opts, mode, err := importOptions(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	records, err := readCSV(r, employeeColumns)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var report ImportReport
+	err = s.transaction(func(tx *APIServer) error {
+		branchExists, err := tx.branchCheck()
+		if err != nil {
+			return err
+		}
+
+		report, err = importRecords(records, "PersonalID", opts, mode, employeeFromCSV, func(inputs []employee.Employee, commit func(int) bool) ([]error, error) {
+			return tx.employeeStorage.ImportEmployees(inputs, commit, func(e employee.Employee) error { return branchExists(e.BranchID) })
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return WriteJSON(w, http.StatusOK, report)
+}
+
+func exportFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		return "csv", nil
+	case "json":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q, expected csv or json", format)
+	}
+}
+
+func writeCSV(w http.ResponseWriter, fileName string, header []string, rows [][]string) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+func (s *APIServer) handleExportVehicles(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	vehicles, err := s.vehicleStorage.GetVehicles()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		return WriteJSON(w, http.StatusOK, vehicles)
+	}
+
+	rows := [][]string{}
+	for _, plate := range slices.Sorted(maps.Keys(vehicles)) {
+		v := vehicles[plate]
+		rows = append(rows, []string{v.PlateNumber, v.Make, v.Model, strconv.Itoa(v.Year), v.FuelType, v.Gearbox, v.Color, v.Body, strconv.Itoa(v.BranchID)})
+	}
+
+	return writeCSV(w, "vehicles.csv", vehicleColumns, rows)
+}
+
+func (s *APIServer) handleExportCustomers(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	customers, err := s.customerStorage.GetCustomers()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		return WriteJSON(w, http.StatusOK, customers)
+	}
+
+	rows := [][]string{}
+	for _, c := range customers {
+		rows = append(rows, []string{strconv.FormatInt(c.PersonalID, 10), c.FirstName, c.LastName, c.PhoneNumber, c.Email})
+	}
+
+	return writeCSV(w, "customers.csv", customerColumns, rows)
+}
+
+func (s *APIServer) handleExportEmployees(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	employees, err := s.employeeStorage.GetEmployees()
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		return WriteJSON(w, http.StatusOK, employees)
+	}
+
+	rows := [][]string{}
+	for _, e := range employees {
+		rows = append(rows, []string{strconv.FormatInt(e.PersonalID, 10), e.FirstName, e.LastName, e.DateOfBirth, e.Email, e.PhoneNumber, e.Address, strconv.Itoa(e.BranchID)})
+	}
+
+	return writeCSV(w, "employees.csv", employeeColumns, rows)
+}
@@ -0,0 +1,114 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/api"
+)
+
+const customersCSV = `PersonalID,First Name,Last Name,Phone Number,Email
+39001010001,Mari,Tamm,5551001,mari.tamm@example.com
+39001010002,Jaan,Kask,5551002,not-an-email
+39001010003,Liis,Saar,5551003,liis.saar@example.com
+`
+
+func importCustomers(t *testing.T, handler http.Handler, query, body string) (int, api.ImportReport) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/customers/import"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var report api.ImportReport
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rec.Code, report
+}
+
+func TestImportCustomers(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		wantCommitted bool
+		wantStored    int
+	}{
+		{"all or nothing keeps everything out", "", false, 0},
+		{"best effort stores the valid rows", "?mode=best-effort", true, 2},
+		{"dry run stores nothing", "?mode=best-effort&dry_run=true", false, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storages, cfg := newTestStorages(t)
+			handler := storages.NewAPIServer(cfg).Handler()
+
+			code, report := importCustomers(t, handler, test.query, customersCSV)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", code, http.StatusOK)
+			}
+
+			if report.Total != 3 || report.Valid != 2 || report.Failed != 1 || report.Committed != test.wantCommitted {
+				t.Errorf("report = %+v", report)
+			}
+
+			for idx, row := range report.Rows {
+				failed := row.Error != ""
+				if failed != (idx == 1) {
+					t.Errorf("row %+v, want only line 3 to fail", row)
+				}
+			}
+			if row := report.Rows[1]; row.Line != 3 || row.Key != "39001010002" {
+				t.Errorf("failed row = %+v, want line 3 with its personal ID", row)
+			}
+
+			customers, err := storages.Customers.GetCustomers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(customers) != test.wantStored {
+				t.Errorf("stored %d customers, want %d", len(customers), test.wantStored)
+			}
+		})
+	}
+}
+
+func TestImportCustomersColumnMapping(t *testing.T) {
+	storages, cfg := newTestStorages(t)
+	handler := storages.NewAPIServer(cfg).Handler()
+
+	body := "ID,Given,Family,Phone,Mail,Notes\n39001010001,Mari,Tamm,5551001,mari.tamm@example.com,regular\n"
+
+	if code, _ := importCustomers(t, handler, "", body); code != http.StatusBadRequest {
+		t.Fatalf("status without mapping = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	query := "?map=ID:PersonalID&map=Given:FirstName&map=Family:LastName&map=Phone:PhoneNumber&map=Mail:Email&map=Notes:-"
+	code, report := importCustomers(t, handler, query, body)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if !report.Committed || report.Valid != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	customer, err := storages.Customers.GetCustomer(39001010001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.FirstName != "Mari" || customer.Email != "mari.tamm@example.com" {
+		t.Errorf("customer = %+v", customer)
+	}
+
+	if code, _ := importCustomers(t, handler, "?map=ID:Nickname", body); code != http.StatusBadRequest {
+		t.Errorf("status for a mapping to an unknown field = %d, want %d", code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,35 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+)
+
+func TestEditCustomerKeepsNamesInPlace(t *testing.T) {
+	storages, cfg := newTestStorages(t)
+	if err := storages.Customers.GetStorage().Save(customer.Customers{{FirstName: "Mari", LastName: "Tamm", PersonalID: 49001010000}}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest("PUT", "/customers", strings.NewReader(`{"PersonalID":49001010000,"FirstName":"Maria","LastName":"Kuusk"}`))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	storages.NewAPIServer(cfg).Handler().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	edited, err := storages.Customers.GetCustomer(49001010000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.FirstName != "Maria" || edited.LastName != "Kuusk" {
+		t.Fatalf("name = %s %s, want Maria Kuusk", edited.FirstName, edited.LastName)
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/damage"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *APIServer) handleVehicleDamage(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetVehicleDamages(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleAddVehicleDamage(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleGetVehicleDamages(w http.ResponseWriter, r *http.Request) error {
+	plateNumber := mux.Vars(r)["plateNumber"]
+
+	if _, err := s.vehicleStorage.GetVehicle(plateNumber); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	damages, err := s.damageStorage.GetDamages(plateNumber)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, damages)
+}
+
+func (s *APIServer) handleAddVehicleDamage(w http.ResponseWriter, r *http.Request) error {
+	plateNumber := mux.Vars(r)["plateNumber"]
+
+	if _, err := s.vehicleStorage.GetVehicle(plateNumber); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	if err := r.ParseMultipartForm(s.serverConfig.MaxUploadBytes); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return WriteJSON(w, http.StatusRequestEntityTooLarge, APIError{Error: fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit)})
+		}
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	newDamage := damage.Damage{
+		PlateNumber: plateNumber,
+		Location:    r.FormValue("Location"),
+		Severity:    r.FormValue("Severity"),
+		Description: r.FormValue("Description"),
+	}
+
+	if value := r.FormValue("RepairCostEstimate"); value != "" {
+		estimate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return WriteJSON(w, http.StatusBadRequest, APIError{Error: "invalid input: repair cost estimate must be a number"})
+		}
+		newDamage.RepairCostEstimate = estimate
+	}
+
+	if value := r.FormValue("RentalID"); value != "" {
+		rentalID, err := strconv.Atoi(value)
+		if err != nil {
+			return WriteJSON(w, http.StatusBadRequest, APIError{Error: "invalid input: rental ID must be a number"})
+		}
+		newDamage.RentalID = rentalID
+	}
+
+	if err := s.damageStorage.ValidateDamage(newDamage); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if r.MultipartForm != nil {
+		for _, header := range r.MultipartForm.File["photos"] {
+			fileName, err := s.savePhoto(plateNumber, header)
+			if err != nil {
+				return writeError(w, errors.Join(err, s.damageStorage.RemovePhotos(plateNumber, newDamage.Photos)))
+			}
+
+			newDamage.Photos = append(newDamage.Photos, fileName)
+		}
+	}
+
+	var added damage.Damage
+	err := s.transaction(func(tx *APIServer) (err error) {
+		if _, err := tx.vehicleStorage.GetVehicle(plateNumber); err != nil {
+			return notFound(err)
+		}
+
+		if newDamage.RentalID != 0 {
+			rental, err := tx.rentalStorage.GetRental(newDamage.RentalID)
+			if err != nil {
+				return err
+			}
+
+			if rental.PlateNumber != plateNumber {
+				return fmt.Errorf("rental %d is not for vehicle %v", newDamage.RentalID, plateNumber)
+			}
+		}
+
+		added, err = tx.damageStorage.AddDamage(newDamage)
+		return err
+	})
+	if err != nil {
+		return writeError(w, errors.Join(err, s.damageStorage.RemovePhotos(plateNumber, newDamage.Photos)))
+	}
+
+	return WriteJSON(w, http.StatusOK, added)
+}
+
+func (s *APIServer) handleRepairVehicleDamage(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	vars := mux.Vars(r)
+
+	damageID, err := strconv.Atoi(vars["damageID"])
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	damage, err := s.damageStorage.RepairDamage(vars["plateNumber"], damageID)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, damage)
+}
+
+func (s *APIServer) handleGetDamagePhoto(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	vars := mux.Vars(r)
+
+	path, err := s.damageStorage.PhotoPath(vars["plateNumber"], vars["photo"])
+	if err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	http.ServeFile(w, r, path)
+	return nil
+}
+
+func (s *APIServer) savePhoto(plateNumber string, header *multipart.FileHeader) (string, error) {
+	photo, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer photo.Close()
+
+	return s.damageStorage.SavePhoto(plateNumber, photo)
+}
+
+func (s *APIServer) ensureNoOpenDamages(plateNumber string) error {
+	open, err := s.damageStorage.HasOpenDamages(plateNumber)
+	if err != nil {
+		return err
+	}
+
+	if open {
+		return &statusError{status: http.StatusConflict, err: fmt.Errorf("vehicle %v has open damage reports and cannot be assigned to a customer", plateNumber)}
+	}
+
+	return nil
+}
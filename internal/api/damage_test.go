@@ -0,0 +1,138 @@
+package api_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/app"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/damage"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+)
+
+func newTestStorages(t *testing.T) (*app.Storages, config.Config) {
+	t.Helper()
+
+	return newTestStoragesWith(t, nil)
+}
+
+// newTestStoragesWith opens storages in a temporary directory, letting
+// configure adjust the configuration first.
+func newTestStoragesWith(t *testing.T, configure func(*config.Config)) (*app.Storages, config.Config) {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DataDir = dir
+	cfg.DamagePhotoDir = filepath.Join(dir, "damage_photos")
+	cfg.Documents.Dir = filepath.Join(dir, "documents")
+	cfg.Backup.Dir = filepath.Join(dir, "backups")
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	storages, err := app.OpenStorages(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return storages, cfg
+}
+
+func damageForm(t *testing.T, fields map[string]string, photos int) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for range photos {
+		part, err := writer.CreateFormFile("photos", "scratch.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write(append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return body, writer.FormDataContentType()
+}
+
+func TestAddDamageLeavesNoPhotosWhenRejected(t *testing.T) {
+	storages, cfg := newTestStorages(t)
+	if err := storages.Vehicles.GetStorage().Save(vehicle.Vehicles{"123ABC": {PlateNumber: "123ABC", Make: "Toyota", Model: "Corolla"}}); err != nil {
+		t.Fatal(err)
+	}
+	handler := storages.NewAPIServer(cfg).Handler()
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+	}{
+		{"invalid report", map[string]string{"Location": "door", "Severity": "catastrophic", "Description": "dent"}},
+		{"unknown rental", map[string]string{"Location": "door", "Severity": "minor", "Description": "dent", "RentalID": "7"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, contentType := damageForm(t, test.fields, 2)
+			request := httptest.NewRequest("POST", "/vehicles/123ABC/damages", body)
+			request.Header.Set("Content-Type", contentType)
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", recorder.Code, recorder.Body)
+			}
+
+			photos, err := os.ReadDir(filepath.Join(cfg.DamagePhotoDir, "123ABC"))
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if len(photos) != 0 {
+				t.Fatalf("%d photos left behind, want none", len(photos))
+			}
+		})
+	}
+}
+
+func TestCheckoutWithOpenDamageConflicts(t *testing.T) {
+	storages, cfg := newTestStorages(t)
+	if err := storages.Vehicles.GetStorage().Save(vehicle.Vehicles{"123ABC": {PlateNumber: "123ABC", Make: "Toyota", Model: "Corolla"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Employees.GetStorage().Save(employee.Employees{{FirstName: "Jaan", LastName: "Kask", PersonalID: 38001010000}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storages.Damages.AddDamage(damage.Damage{PlateNumber: "123ABC", Location: "door", Severity: "minor", Description: "dent"}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest("POST", "/customers/49001010000/vehicles", strings.NewReader(`{"PlateNumber":"123ABC","Odometer":1000,"FuelLevel":80}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Employee-ID", "38001010000")
+	recorder := httptest.NewRecorder()
+
+	storages.NewAPIServer(cfg).Handler().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409: %s", recorder.Code, recorder.Body)
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/blob"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/document"
+
+	"github.com/gorilla/mux"
+)
+
+// maxDocumentFormOverhead is what a document upload may carry on top of the
+// file itself, for the other form fields and the multipart framing.
+const maxDocumentFormOverhead = 1 << 20
+
+const documentDateLayout = "2006-01-02"
+
+func (s *APIServer) handleCustomerDocument(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetCustomerDocuments(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleUploadCustomerDocument(w, r)
+	}
+	if r.Method == "DELETE" {
+		return s.handleDeleteCustomerDocument(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleGetCustomerDocuments(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if _, err := s.customerStorage.GetCustomer(personalID); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	documents, err := s.documentStorage.GetDocuments(personalID)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, documents)
+}
+
+// handleUploadCustomerDocument stores the scan sent in the file field. Uploads
+// over the size limit are answered with 413, disallowed file types with 415.
+func (s *APIServer) handleUploadCustomerDocument(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if _, err := s.customerStorage.GetCustomer(personalID); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	employeeID, err := s.handlingEmployee(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	maxUploadSize := s.documentStorage.MaxSize() + maxDocumentFormOverhead
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return WriteJSON(w, http.StatusRequestEntityTooLarge, APIError{Error: fmt.Sprintf("%s: the limit is %d bytes", blob.ErrTooLarge, s.documentStorage.MaxSize())})
+		}
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	input := document.Document{
+		PersonalID: personalID,
+		Type:       r.FormValue("Type"),
+		UploadedBy: employeeID,
+	}
+
+	if value := r.FormValue("ExpiresAt"); value != "" {
+		expiresAt, err := time.Parse(documentDateLayout, value)
+		if err != nil {
+			return WriteJSON(w, http.StatusBadRequest, APIError{Error: fmt.Sprintf("invalid input: document expiry must be a date formatted as %s", documentDateLayout)})
+		}
+		input.ExpiresAt = &expiresAt
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: "invalid input: the document must be sent in the file field"})
+	}
+	defer file.Close()
+	input.FileName = header.Filename
+
+	// The customer is checked again in the transaction that records the
+	// document, so a customer deleted during the upload is not left with it.
+	var added document.Document
+	err = s.documentStorage.Upload(input, file, func(stored blob.Blob) error {
+		return s.transaction(func(tx *APIServer) error {
+			if _, err := tx.customerStorage.GetCustomer(personalID); err != nil {
+				return notFound(err)
+			}
+
+			added, err = tx.documentStorage.AddDocument(input, stored)
+			return err
+		})
+	})
+	if errors.Is(err, blob.ErrTooLarge) {
+		return WriteJSON(w, http.StatusRequestEntityTooLarge, APIError{Error: err.Error()})
+	}
+	if errors.Is(err, blob.ErrContentType) {
+		return WriteJSON(w, http.StatusUnsupportedMediaType, APIError{Error: err.Error()})
+	}
+	if err != nil {
+		return writeError(w, err)
+	}
+
+	return WriteJSON(w, http.StatusCreated, added)
+}
+
+func (s *APIServer) handleDeleteCustomerDocument(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var input IDRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if err := s.documentStorage.DeleteDocument(personalID, input.ID); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, CustomResponse{Response: "document deleted"})
+}
+
+// handleGetCustomerDocument serves the scan with the content type sniffed at
+// upload. The content digest doubles as the ETag.
+func (s *APIServer) handleGetCustomerDocument(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	documentID, err := strconv.Atoi(mux.Vars(r)["documentID"])
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: "invalid input: document ID must be a number"})
+	}
+
+	found, content, err := s.documentStorage.OpenDocument(personalID, documentID)
+	if err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", found.ContentType)
+	w.Header().Set("ETag", strconv.Quote(found.SHA256))
+	if found.FileName != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": found.FileName}))
+	}
+
+	http.ServeContent(w, r, "", found.UploadedAt, content)
+	return nil
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+)
+
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+	defaultEventWait  = 20 * time.Second
+	eventKeepAlive    = 15 * time.Second
+)
+
+// EventsResponse carries Reset when events after the requested cursor have
+// been dropped; Events then start at Reset.Oldest.
+type EventsResponse struct {
+	Events events.Events
+	Cursor int64
+	Reset  *StreamReset `json:",omitempty"`
+}
+
+// StreamReset is sent as a reset event when the events after the client's
+// cursor have been dropped by the retention limit. The client missed
+// everything up to Oldest and should reload its state.
+type StreamReset struct {
+	Cursor int64
+	Oldest int64
+}
+
+// missed reports the events dropped between cursor and the first of found.
+// Event IDs have no gaps, so a first event past the next ID means the ones in
+// between were dropped.
+func missed(cursor int64, found events.Events) *StreamReset {
+	if cursor > 0 && len(found) > 0 && found[0].ID > cursor+1 {
+		return &StreamReset{Cursor: cursor, Oldest: found[0].ID}
+	}
+
+	return nil
+}
+
+func parseCursor(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	cursor, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || cursor < 0 {
+		return 0, fmt.Errorf("invalid cursor %q, expected a non-negative event ID", value)
+	}
+
+	return cursor, nil
+}
+
+// maxEventWait keeps a long poll inside the server's write timeout.
+func (s *APIServer) maxEventWait() time.Duration {
+	limit := s.serverConfig.WriteTimeout.Duration - time.Second
+	if limit < 0 {
+		return 0
+	}
+
+	return limit
+}
+
+func (s *APIServer) handleEvents(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	query := r.URL.Query()
+
+	cursor, err := parseCursor(query.Get("since"))
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	limit := defaultEventLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxEventLimit {
+			return WriteJSON(w, http.StatusBadRequest, APIError{Error: fmt.Sprintf("limit must be between 1 and %d", maxEventLimit)})
+		}
+	}
+
+	wait := defaultEventWait
+	if value := query.Get("wait"); value != "" {
+		wait, err = time.ParseDuration(value)
+		if err != nil || wait < 0 {
+			return WriteJSON(w, http.StatusBadRequest, APIError{Error: "wait must be a non-negative duration such as 30s"})
+		}
+	}
+	wait = min(wait, s.maxEventWait())
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	for {
+		changed := s.events.Changed()
+
+		found, err := s.events.Since(cursor, limit)
+		if err != nil {
+			return WriteJSON(w, http.StatusInternalServerError, APIError{Error: err.Error()})
+		}
+
+		if len(found) > 0 {
+			return WriteJSON(w, http.StatusOK, EventsResponse{Events: found, Cursor: found[len(found)-1].ID, Reset: missed(cursor, found)})
+		}
+
+		select {
+		case <-changed:
+			continue
+		case <-timeout.C:
+		case <-r.Context().Done():
+		case <-s.shutdown:
+		}
+
+		return WriteJSON(w, http.StatusOK, EventsResponse{Events: found, Cursor: cursor})
+	}
+}
+
+func (s *APIServer) handleEventStream(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	value := r.URL.Query().Get("since")
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		value = lastEventID
+	}
+
+	cursor, err := parseCursor(value)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	controller := http.NewResponseController(w)
+
+	// A stream outlives the server's write timeout by design.
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, from here on failures can only be logged.
+	logger := logging.FromContext(r.Context())
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		changed := s.events.Changed()
+
+		found, err := s.events.Since(cursor, defaultEventLimit)
+		if err != nil {
+			logger.Error("reading events for stream failed", "error", err)
+			return nil
+		}
+
+		if reset := missed(cursor, found); reset != nil {
+			data, err := json.Marshal(reset)
+			if err != nil {
+				logger.Error("encoding stream reset failed", "error", err)
+				return nil
+			}
+
+			if _, err := fmt.Fprintf(w, "event: reset\ndata: %s\n\n", data); err != nil {
+				return nil
+			}
+		}
+
+		for _, event := range found {
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Error("encoding event for stream failed", "event", event.ID, "error", err)
+				return nil
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return nil
+			}
+			cursor = event.ID
+		}
+
+		if err := controller.Flush(); err != nil {
+			return nil
+		}
+
+		if len(found) == defaultEventLimit {
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case <-r.Context().Done():
+			return nil
+		case <-s.shutdown:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+func TestEventStreamResetsBehindRetention(t *testing.T) {
+	dir := t.TempDir()
+	outbox := events.NewOutbox(filepath.Join(dir, "events.json"), 2, storage.NewJournal(filepath.Join(dir, "journal.json")))
+	if err := storage.EnsureStorageFile(outbox.GetStorage(), events.Events{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		if err := outbox.Append(events.New(events.CustomerCreated, "customer/1", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &APIServer{events: outbox, shutdown: make(chan struct{})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	request := httptest.NewRequestWithContext(ctx, "GET", "/events/stream?since=1", nil)
+	recorder := httptest.NewRecorder()
+
+	if err := s.handleEventStream(recorder, request); err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+
+	body := recorder.Body.String()
+	reset := strings.Index(body, "event: reset\ndata: {\"Cursor\":1,\"Oldest\":4}\n\n")
+	if reset == -1 {
+		t.Fatalf("no reset event in %q", body)
+	}
+
+	if first := strings.Index(body, "id: 4\n"); first < reset {
+		t.Errorf("events must follow the reset, got %q", body)
+	}
+	if !strings.Contains(body, "id: 5\n") {
+		t.Errorf("event 5 missing from %q", body)
+	}
+}
+
+func TestLongPollResetsBehindRetention(t *testing.T) {
+	dir := t.TempDir()
+	outbox := events.NewOutbox(filepath.Join(dir, "events.json"), 2, storage.NewJournal(filepath.Join(dir, "journal.json")))
+	if err := storage.EnsureStorageFile(outbox.GetStorage(), events.Events{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		if err := outbox.Append(events.New(events.CustomerCreated, "customer/1", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &APIServer{events: outbox, shutdown: make(chan struct{})}
+
+	tests := []struct {
+		since     string
+		wantReset *StreamReset
+	}{
+		{"1", &StreamReset{Cursor: 1, Oldest: 4}},
+		{"3", nil},
+		{"0", nil},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		if err := s.handleEvents(recorder, httptest.NewRequest("GET", "/events?wait=0s&since="+test.since, nil)); err != nil {
+			t.Fatal(err)
+		}
+
+		var response EventsResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(response.Reset, test.wantReset) {
+			t.Errorf("since %s: reset = %+v, want %+v", test.since, response.Reset, test.wantReset)
+		}
+		if response.Cursor != 5 {
+			t.Errorf("since %s: cursor = %d, want 5", test.since, response.Cursor)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/ratelimit"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+type rateLimitGroup struct {
+	limiter *ratelimit.Limiter
+	key     string
+}
+
+func newRateLimitGroups(limits map[string]config.RateLimit) map[string]*rateLimitGroup {
+	groups := map[string]*rateLimitGroup{}
+	for name, limit := range limits {
+		if limit.RequestsPerSecond <= 0 {
+			groups[name] = nil
+			continue
+		}
+
+		key := limit.Key
+		if key == "" {
+			key = config.RateLimitByIP
+		}
+
+		groups[name] = &rateLimitGroup{limiter: ratelimit.New(limit.RequestsPerSecond, limit.Burst), key: key}
+	}
+
+	return groups
+}
+
+func routeGroup(r *http.Request) string {
+	template := strings.TrimPrefix(routeTemplate(r), "/")
+	group, _, _ := strings.Cut(template, "/")
+	return group
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// clientKey only trusts the identity headers once they check out, so a
+// client cannot get a fresh budget by sending made-up values.
+func (s *APIServer) clientKey(r *http.Request, key string) string {
+	switch key {
+	case config.RateLimitByAPIKey:
+		if apiKey := r.Header.Get(apiKeyHeader); apiKey != "" && slices.Contains(s.serverConfig.APIKeys, apiKey) {
+			return "api_key:" + apiKey
+		}
+	case config.RateLimitByUser:
+		employeeID, err := strconv.ParseInt(r.Header.Get(employeeHeader), 10, 64)
+		if err != nil {
+			break
+		}
+
+		if _, err := s.employeeStorage.GetEmployee(employeeID); err == nil {
+			return fmt.Sprintf("employee:%d@%s", employeeID, clientIP(r))
+		}
+	}
+
+	return "ip:" + clientIP(r)
+}
+
+func (s *APIServer) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group, ok := s.rateLimits[routeGroup(r)]
+		if !ok {
+			group = s.rateLimits[config.DefaultRateLimitGroup]
+		}
+
+		if group == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, wait := group.limiter.Allow(s.clientKey(r, group.key), time.Now())
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			WriteJSON(w, http.StatusTooManyRequests, APIError{Error: fmt.Sprintf("rate limit exceeded, retry after %ds", retryAfter)})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasMediaType(r *http.Request, want string) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == want
+}
+
+func isMultipart(r *http.Request) bool {
+	return hasMediaType(r, "multipart/form-data")
+}
+
+// bodyLimitMiddleware limits multipart uploads and CSV imports to
+// MaxUploadBytes and every other body to MaxBodyBytes.
+func (s *APIServer) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit := s.serverConfig.MaxBodyBytes
+		if isMultipart(r) || hasMediaType(r, "text/csv") {
+			limit = s.serverConfig.MaxUploadBytes
+		}
+
+		if r.ContentLength > limit {
+			WriteJSON(w, http.StatusRequestEntityTooLarge, APIError{Error: fmt.Sprintf("request body exceeds %d bytes", limit)})
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func decodeJSON(r *http.Request, value any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(value); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("request body exceeds %d bytes", maxBytesErr.Limit)
+		}
+		return err
+	}
+
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errors.New("request body must contain a single JSON value")
+	}
+
+	return nil
+}
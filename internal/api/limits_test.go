@@ -0,0 +1,88 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+func TestClientKeyTrustsOnlyVerifiedHeaders(t *testing.T) {
+	employees := employee.NewEmployeeStorage(filepath.Join(t.TempDir(), "employees.json"), nil)
+	if err := storage.EnsureStorageFile(employees.GetStorage(), employee.Employees{{FirstName: "Jaan", LastName: "Kask", PersonalID: 38001010000}}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &APIServer{serverConfig: config.Server{APIKeys: []string{"known"}}, employeeStorage: employees}
+
+	tests := []struct {
+		name   string
+		key    string
+		header string
+		value  string
+		want   string
+	}{
+		{"listed api key", config.RateLimitByAPIKey, apiKeyHeader, "known", "api_key:known"},
+		{"unlisted api key", config.RateLimitByAPIKey, apiKeyHeader, "made-up", "ip:192.0.2.1"},
+		{"existing employee", config.RateLimitByUser, employeeHeader, "38001010000", "employee:38001010000@192.0.2.1"},
+		{"unknown employee", config.RateLimitByUser, employeeHeader, "38001019999", "ip:192.0.2.1"},
+		{"malformed employee", config.RateLimitByUser, employeeHeader, "nobody", "ip:192.0.2.1"},
+		{"by ip", config.RateLimitByIP, employeeHeader, "38001010000", "ip:192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/customers", nil)
+			request.Header.Set(test.header, test.value)
+
+			if got := s.clientKey(request, test.key); got != test.want {
+				t.Errorf("clientKey() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBodyLimitCoversUploads(t *testing.T) {
+	s := &APIServer{serverConfig: config.Server{MaxBodyBytes: 16, MaxUploadBytes: 64}}
+	handler := s.bodyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		size        int
+		want        int
+	}{
+		{"json within limit", "application/json", 16, http.StatusOK},
+		{"json over limit", "application/json", 17, http.StatusRequestEntityTooLarge},
+		{"csv within upload limit", "text/csv", 64, http.StatusOK},
+		{"csv over upload limit", "text/csv", 65, http.StatusRequestEntityTooLarge},
+		{"multipart over upload limit", "multipart/form-data; boundary=x", 65, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, length := range []int64{int64(test.size), -1} {
+				request := httptest.NewRequest("POST", "/customers/import", strings.NewReader(strings.Repeat("a", test.size)))
+				request.Header.Set("Content-Type", test.contentType)
+				request.ContentLength = length
+				recorder := httptest.NewRecorder()
+
+				handler.ServeHTTP(recorder, request)
+
+				if recorder.Code != test.want {
+					t.Errorf("content length %d: status = %d, want %d", length, recorder.Code, test.want)
+				}
+			}
+		})
+	}
+}
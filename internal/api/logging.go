@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type handlerFunc func(s *APIServer, w http.ResponseWriter, r *http.Request) error
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, char := range id {
+		if char <= ' ' || char > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func requestActor(r *http.Request) string {
+	if employeeID := r.Header.Get(employeeHeader); employeeID != "" {
+		return "employee:" + employeeID
+	}
+
+	return "anonymous"
+}
+
+func (s *APIServer) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		logger := slog.Default().With("request_id", requestID)
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r.WithContext(logging.WithLogger(r.Context(), logger)))
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case recorder.status >= 500:
+			level = slog.LevelError
+		case recorder.status >= 400:
+			level = slog.LevelWarn
+		}
+
+		logger.Log(r.Context(), level, "request",
+			"method", r.Method,
+			"route", routeTemplate(r),
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"latency", time.Since(start),
+			"actor", requestActor(r),
+		)
+	})
+}
+
+func (s *APIServer) withContext(ctx context.Context) *APIServer {
+	scoped := *s
+	scoped.customerStorage = s.customerStorage.WithContext(ctx)
+	scoped.vehicleStorage = s.vehicleStorage.WithContext(ctx)
+	scoped.employeeStorage = s.employeeStorage.WithContext(ctx)
+	scoped.rentalStorage = s.rentalStorage.WithContext(ctx)
+	scoped.damageStorage = s.damageStorage.WithContext(ctx)
+	scoped.documentStorage = s.documentStorage.WithContext(ctx)
+	scoped.reservationStorage = s.reservationStorage.WithContext(ctx)
+	scoped.branchStorage = s.branchStorage.WithContext(ctx)
+	scoped.shiftStorage = s.shiftStorage.WithContext(ctx)
+	scoped.paymentStorage = s.paymentStorage.WithContext(ctx)
+	scoped.events = s.events.WithContext(ctx)
+	scoped.webhooks = s.webhooks.WithContext(ctx)
+	scoped.notifier = s.notifier.WithContext(ctx)
+	return &scoped
+}
+
+func (s *APIServer) handle(f handlerFunc) http.HandlerFunc {
+	return makeHTTPHandleFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return f(s.withContext(r.Context()), w, r)
+	})
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/metrics"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+type serverMetrics struct {
+	// registered makes sure the record gauge and the storage observer are
+	// set up once, however often the routes are built.
+	registered      sync.Once
+	registry        *metrics.Registry
+	requests        *metrics.CounterVec
+	requestDuration *metrics.HistogramVec
+	errors          *metrics.CounterVec
+	storageDuration *metrics.HistogramVec
+}
+
+func newServerMetrics() *serverMetrics {
+	registry := metrics.NewRegistry()
+
+	return &serverMetrics{
+		registry:        registry,
+		requests:        registry.NewCounterVec("rwapi_http_requests_total", "Total HTTP requests by method, route and status.", "method", "route", "status"),
+		requestDuration: registry.NewHistogramVec("rwapi_http_request_duration_seconds", "HTTP request latency by method and route.", metrics.DefaultBuckets, "method", "route"),
+		errors:          registry.NewCounterVec("rwapi_http_errors_total", "HTTP responses with an error status.", "status"),
+		storageDuration: registry.NewHistogramVec("rwapi_storage_operation_duration_seconds", "Duration of storage file loads and saves.", metrics.DefaultBuckets, "operation", "file"),
+	}
+}
+
+func (s *APIServer) registerMetrics() {
+	s.metrics.registered.Do(func() {
+		s.registerRecordGauge()
+		storage.SetObserver(s.observeStorage)
+	})
+}
+
+func (s *APIServer) registerRecordGauge() {
+	s.metrics.registry.NewGaugeFunc("rwapi_records", "Number of records stored per resource.", "resource", func() map[string]float64 {
+		counts := map[string]float64{}
+
+		if customers, err := s.customerStorage.GetCustomers(); err == nil {
+			counts["customers"] = float64(len(customers))
+		}
+
+		if vehicles, err := s.vehicleStorage.GetVehicles(); err == nil {
+			counts["vehicles"] = float64(len(vehicles))
+		}
+
+		if employees, err := s.employeeStorage.GetEmployees(); err == nil {
+			counts["employees"] = float64(len(employees))
+		}
+
+		return counts
+	})
+}
+
+func (s *APIServer) observeStorage(operation, fileName string, duration time.Duration) {
+	s.metrics.storageDuration.Observe(duration.Seconds(), operation, filepath.Base(fileName))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(data []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(data)
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := sr.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("response writer does not support hijacking")
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return "unmatched"
+}
+
+func (s *APIServer) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		route := routeTemplate(r)
+		status := strconv.Itoa(recorder.status)
+
+		s.metrics.requests.Inc(r.Method, route, status)
+		s.metrics.requestDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+
+		if recorder.status >= 400 {
+			s.metrics.errors.Inc(status)
+		}
+	})
+}
+
+type ReadinessCheck struct {
+	File  string `json:"file"`
+	Error string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status string           `json:"status"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+func checkStorageFile(fileName string) error {
+	file, err := os.OpenFile(fileName, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	probe, err := os.CreateTemp(filepath.Dir(fileName), ".readyz-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+
+	return os.Remove(probe.Name())
+}
+
+func (s *APIServer) handleHealthz(w http.ResponseWriter, _ *http.Request) error {
+	return WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *APIServer) handleReadyz(w http.ResponseWriter, _ *http.Request) error {
+	response := ReadinessResponse{Status: "ready", Checks: []ReadinessCheck{}}
+	status := http.StatusOK
+
+	for _, file := range s.files {
+		check := ReadinessCheck{File: filepath.Base(file.Name)}
+
+		if err := checkStorageFile(file.Name); err != nil {
+			check.Error = err.Error()
+			response.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+
+		response.Checks = append(response.Checks, check)
+	}
+
+	return WriteJSON(w, status, response)
+}
+
+func (s *APIServer) handleMetrics(w http.ResponseWriter, _ *http.Request) error {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	return s.metrics.registry.Write(w)
+}
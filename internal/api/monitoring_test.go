@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/api"
+)
+
+func TestReadyzChecksEveryStorageFile(t *testing.T) {
+	storages, cfg := newTestStorages(t)
+
+	recorder := httptest.NewRecorder()
+	storages.NewAPIServer(cfg).Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	var response api.ReadinessResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	files := storages.Files()
+	if len(response.Checks) != len(files) {
+		t.Fatalf("%d checks, want one per storage file (%d)", len(response.Checks), len(files))
+	}
+	for idx, file := range files {
+		if response.Checks[idx].File != filepath.Base(file.Name) {
+			t.Errorf("check %d is for %s, want %s", idx, response.Checks[idx].File, filepath.Base(file.Name))
+		}
+	}
+}
+
+func TestHandlerServesRecordAndStorageMetrics(t *testing.T) {
+	storages, cfg := newTestStorages(t)
+	server := storages.NewAPIServer(cfg)
+	server.Handler()
+	handler := server.Handler()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/customers", nil))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+	if count := strings.Count(body, "# TYPE rwapi_records "); count != 1 {
+		t.Errorf("rwapi_records is described %d times, want once", count)
+	}
+	if !strings.Contains(body, `rwapi_records{resource="customers"} 0`) {
+		t.Errorf("record gauge missing from\n%s", body)
+	}
+	if !strings.Contains(body, `rwapi_storage_operation_duration_seconds_count{operation="load",file="customers.json"}`) {
+		t.Errorf("storage load of customers.json not observed in\n%s", body)
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/notify"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationPreferenceRequest struct {
+	EmailOptOut bool
+}
+
+func (s *APIServer) handleEmails(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", notify.StatusPending, notify.StatusSent, notify.StatusFailed, notify.StatusCancelled:
+	default:
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: fmt.Sprintf("unknown status %q, expected %s, %s, %s or %s", status, notify.StatusPending, notify.StatusSent, notify.StatusFailed, notify.StatusCancelled)})
+	}
+
+	messages, err := s.notifier.GetMessages(status)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, messages)
+}
+
+func (s *APIServer) handleCustomerNotifications(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" && r.Method != "PUT" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if _, err := s.customerStorage.GetCustomer(personalID); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	if r.Method == "GET" {
+		preference, err := s.notifier.GetPreference(personalID)
+		if err != nil {
+			return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+		}
+
+		return WriteJSON(w, http.StatusOK, preference)
+	}
+
+	var input NotificationPreferenceRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	preference, err := s.notifier.SetPreference(personalID, input.EmailOptOut)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, preference)
+}
+
+func (s *APIServer) handleEmailTemplates(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	return WriteJSON(w, http.StatusOK, s.notifier.Templates().Names())
+}
+
+// handlePreviewEmailTemplate renders a template with sample data. format
+// picks the HTML or plain text body instead of the JSON with all parts.
+func (s *APIServer) handlePreviewEmailTemplate(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	name := mux.Vars(r)["name"]
+	if _, ok := s.notifier.Templates()[name]; !ok {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: fmt.Sprintf("email template %s not found", name)})
+	}
+
+	rendered, err := s.notifier.Templates().Render(name, notify.SampleData())
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return WriteJSON(w, http.StatusOK, rendered)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err = io.WriteString(w, rendered.HTML)
+		return err
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err = io.WriteString(w, rendered.Text)
+		return err
+	default:
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: fmt.Sprintf("unknown format %q, expected json, html or text", format)})
+	}
+}
@@ -0,0 +1,436 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/backup"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/branch"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/damage"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/document"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/payment"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/reservation"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/shift"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/notify"
+	"github.com/ZulfiPy/RWAPIGo/internal/webhook"
+
+	"github.com/gorilla/mux"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+type operationDoc struct {
+	method      string
+	path        string
+	tag         string
+	summary     string
+	query       []string
+	employee    bool
+	override    bool
+	request     any
+	requestType string
+	form        []string
+	files       string
+	file        string
+	status      int
+	response    any
+	contentType string
+}
+
+var queryDescriptions = map[string]string{
+	"branch":  "only return records belonging to this branch ID",
+	"from":    "start date, inclusive, formatted as 2006-01-02",
+	"to":      "end date, inclusive, formatted as 2006-01-02",
+	"mode":    "all-or-nothing (default) commits only when every row is valid, best-effort commits the valid rows",
+	"dry_run": "validate and report without saving anything",
+	"map":     "map a CSV column to a field as Header:Field, or ignore it with Header:-, may be repeated",
+	"format":  "exports: csv (default) or json; template previews: json (default), html or text",
+	"status":  "only return records with this status: pending, delivered or failed for webhook deliveries, pending, sent, failed or cancelled for emails",
+	"since":   "return events after this cursor, the Cursor of the previous response or 0 to start from the oldest retained event",
+	"limit":   "maximum number of events to return, 1 to 1000, default 100",
+	"wait":    "how long to wait for new events when there are none yet, such as 20s (the default), capped below the server write timeout",
+}
+
+var operationDocs = []operationDoc{
+	{method: "GET", path: "/healthz", tag: "monitoring", summary: "Liveness probe", response: map[string]string{}},
+	{method: "GET", path: "/readyz", tag: "monitoring", summary: "Readiness probe checking every storage file", response: ReadinessResponse{}},
+	{method: "GET", path: "/metrics", tag: "monitoring", summary: "Prometheus metrics", contentType: "text/plain"},
+	{method: "GET", path: "/openapi.json", tag: "documentation", summary: "This OpenAPI document", response: map[string]any{}},
+	{method: "GET", path: "/docs", tag: "documentation", summary: "Interactive API documentation", contentType: "text/html"},
+
+	{method: "GET", path: "/customers", tag: "customers", summary: "List customers", response: customer.Customers{}},
+	{method: "POST", path: "/customers", tag: "customers", summary: "Add a customer", request: customer.Customer{}, response: customer.Customer{}},
+	{method: "PUT", path: "/customers", tag: "customers", summary: "Edit a customer", request: EditCustomerRequest{}, response: CustomResponse{}},
+	{method: "DELETE", path: "/customers", tag: "customers", summary: "Delete a customer", request: PersonalIDRequest{}, response: CustomResponse{}},
+	{method: "POST", path: "/customers/import", tag: "customers", summary: "Import customers from CSV", query: []string{"mode", "dry_run", "map"}, requestType: "text/csv", response: ImportReport{}},
+	{method: "GET", path: "/customers/export", tag: "customers", summary: "Export customers as CSV or JSON", query: []string{"format"}, contentType: "text/csv"},
+	{method: "POST", path: "/customers/{personalID}/vehicles", tag: "rentals", summary: "Check a vehicle out to a customer", employee: true, override: true, request: CheckoutRequest{}, response: customer.Customer{}},
+	{method: "DELETE", path: "/customers/{personalID}/{plateNumber}/delete-vehicle", tag: "rentals", summary: "Check a rented vehicle back in", employee: true, request: CheckInRequest{}, response: rental.Rental{}},
+	{method: "GET", path: "/customers/{personalID}/payments", tag: "payments", summary: "List a customer's ledger entries", response: payment.Entries{}},
+	{method: "POST", path: "/customers/{personalID}/payments", tag: "payments", summary: "Post a charge, payment or refund; one the provider declines is recorded as failed and answered with 402", request: PaymentRequest{}, response: payment.Entry{}},
+	{method: "GET", path: "/customers/{personalID}/balance", tag: "payments", summary: "Get a customer's balance", response: payment.Balance{}},
+	{method: "GET", path: "/customers/{personalID}/risk-flags", tag: "customers", summary: "List a customer's risk flags, including expired ones", response: []customer.RiskFlag{}},
+	{method: "POST", path: "/customers/{personalID}/risk-flags", tag: "customers", summary: "Flag a customer so vehicles are only assigned with a manager override", employee: true, status: http.StatusCreated, request: RiskFlagRequest{}, response: customer.RiskFlag{}},
+	{method: "DELETE", path: "/customers/{personalID}/risk-flags", tag: "customers", summary: "Remove a risk flag", employee: true, request: IDRequest{}, response: CustomResponse{}},
+	{method: "GET", path: "/customers/{personalID}/documents", tag: "documents", summary: "List a customer's documents", response: document.Documents{}},
+	{method: "POST", path: "/customers/{personalID}/documents", tag: "documents", summary: "Upload an ID card, licence or signed contract scan; Type is id_card, licence or contract and ExpiresAt a 2006-01-02 date", employee: true, form: []string{"Type", "ExpiresAt"}, file: "file", status: http.StatusCreated, response: document.Document{}},
+	{method: "DELETE", path: "/customers/{personalID}/documents", tag: "documents", summary: "Delete a document", request: IDRequest{}, response: CustomResponse{}},
+	{method: "GET", path: "/customers/{personalID}/documents/{documentID}", tag: "documents", summary: "Download a document with its original content type", contentType: "application/octet-stream"},
+	{method: "GET", path: "/customers/{personalID}/notifications", tag: "notifications", summary: "Get a customer's email preference", response: notify.Preference{}},
+	{method: "PUT", path: "/customers/{personalID}/notifications", tag: "notifications", summary: "Opt a customer out of or back into email; opting out cancels the emails still queued for them", request: NotificationPreferenceRequest{}, response: notify.Preference{}},
+
+	{method: "GET", path: "/vehicles", tag: "vehicles", summary: "List vehicles keyed by plate number", query: []string{"branch"}, status: http.StatusAccepted, response: vehicle.Vehicles{}},
+	{method: "POST", path: "/vehicles", tag: "vehicles", summary: "Add a vehicle", request: vehicle.Vehicle{}, response: vehicle.Vehicle{}},
+	{method: "PUT", path: "/vehicles", tag: "vehicles", summary: "Edit a vehicle", request: vehicle.Vehicle{}, response: vehicle.Vehicle{}},
+	{method: "DELETE", path: "/vehicles", tag: "vehicles", summary: "Delete a vehicle", request: PlateNumberRequest{}, response: CustomResponse{}},
+	{method: "POST", path: "/vehicles/import", tag: "vehicles", summary: "Import vehicles from CSV", query: []string{"mode", "dry_run", "map"}, requestType: "text/csv", response: ImportReport{}},
+	{method: "GET", path: "/vehicles/export", tag: "vehicles", summary: "Export vehicles as CSV or JSON", query: []string{"format"}, contentType: "text/csv"},
+	{method: "GET", path: "/vehicles/{plateNumber}/damages", tag: "damages", summary: "List a vehicle's damage reports", response: damage.Damages{}},
+	{method: "POST", path: "/vehicles/{plateNumber}/damages", tag: "damages", summary: "Report damage with optional photos", form: []string{"Location", "Severity", "Description", "RepairCostEstimate", "RentalID"}, files: "photos", response: damage.Damage{}},
+	{method: "POST", path: "/vehicles/{plateNumber}/damages/{damageID}/repair", tag: "damages", summary: "Mark a damage report as repaired", response: damage.Damage{}},
+	{method: "GET", path: "/vehicles/{plateNumber}/damages/photos/{photo}", tag: "damages", summary: "Download a damage photo", contentType: "image/*"},
+
+	{method: "GET", path: "/employees", tag: "employees", summary: "List employees", query: []string{"branch"}, response: employee.Employees{}},
+	{method: "POST", path: "/employees", tag: "employees", summary: "Add an employee", request: employee.Employee{}, response: employee.Employee{}},
+	{method: "PUT", path: "/employees", tag: "employees", summary: "Edit an employee's contact details", request: EditEmployeeRequest{}, response: employee.Employee{}},
+	{method: "DELETE", path: "/employees", tag: "employees", summary: "Delete an employee", request: PersonalIDRequest{}},
+	{method: "POST", path: "/employees/import", tag: "employees", summary: "Import employees from CSV", query: []string{"mode", "dry_run", "map"}, requestType: "text/csv", response: ImportReport{}},
+	{method: "GET", path: "/employees/export", tag: "employees", summary: "Export employees as CSV or JSON", query: []string{"format"}, contentType: "text/csv"},
+	{method: "POST", path: "/employees/{personalID}/branch", tag: "employees", summary: "Assign an employee to a branch", request: AssignBranchRequest{}, response: employee.Employee{}},
+	{method: "GET", path: "/employees/{personalID}/shifts", tag: "shifts", summary: "List an employee's shifts", query: []string{"from", "to", "branch"}, response: shift.Shifts{}},
+	{method: "POST", path: "/employees/{personalID}/shifts", tag: "shifts", summary: "Schedule a shift", request: shift.Shift{}, response: shift.Shift{}},
+	{method: "DELETE", path: "/employees/{personalID}/shifts", tag: "shifts", summary: "Delete a shift", request: IDRequest{}, response: CustomResponse{}},
+	{method: "GET", path: "/employees/{personalID}/activity", tag: "shifts", summary: "Summarise an employee's rentals and hours", query: []string{"from", "to"}, response: EmployeeActivity{}},
+
+	{method: "GET", path: "/branches", tag: "branches", summary: "List branches", response: branch.Branches{}},
+	{method: "POST", path: "/branches", tag: "branches", summary: "Add a branch", request: branch.Branch{}, response: branch.Branch{}},
+	{method: "PUT", path: "/branches", tag: "branches", summary: "Edit a branch", request: branch.Branch{}, response: branch.Branch{}},
+	{method: "DELETE", path: "/branches", tag: "branches", summary: "Delete a branch without vehicles or employees", request: IDRequest{}, response: CustomResponse{}},
+
+	{method: "GET", path: "/rentals/overdue", tag: "rentals", summary: "List active rentals past their due-back time with the late fee so far, latest first", response: []rental.OverdueRental{}},
+
+	{method: "POST", path: "/batch", tag: "batch", summary: "Create, update and delete customers, vehicles and employees in one request", request: BatchRequest{}, response: BatchResponse{}},
+
+	{method: "GET", path: "/reservations", tag: "reservations", summary: "List reservations", query: []string{"branch"}, response: reservation.Reservations{}},
+	{method: "POST", path: "/reservations", tag: "reservations", summary: "Reserve a vehicle class", request: reservation.Reservation{}, response: reservation.Reservation{}},
+	{method: "POST", path: "/reservations/{reservationID}/pickup", tag: "reservations", summary: "Convert a reservation into a rental", employee: true, override: true, request: PickupRequest{}, response: reservation.Reservation{}},
+	{method: "POST", path: "/reservations/{reservationID}/cancel", tag: "reservations", summary: "Cancel a reservation", response: reservation.Reservation{}},
+
+	{method: "GET", path: "/events", tag: "events", summary: "Long-poll for domain events after a cursor; Reset reports events already dropped by retention", query: []string{"since", "limit", "wait"}, response: EventsResponse{}},
+	{method: "GET", path: "/events/stream", tag: "events", summary: "Stream domain events as Server-Sent Events, resuming from since or Last-Event-ID; a reset event reports events already dropped by retention", query: []string{"since"}, contentType: "text/event-stream"},
+
+	{method: "GET", path: "/webhooks", tag: "webhooks", summary: "List webhook subscriptions, without their secrets", response: webhook.Subscriptions{}},
+	{method: "POST", path: "/webhooks", tag: "webhooks", summary: "Subscribe a URL to event types, generating a signing secret when none is given. Loopback, link-local and private targets are rejected unless Webhooks.AllowPrivateTargets is set", status: http.StatusCreated, request: webhook.Subscription{}, response: webhook.Subscription{}},
+	{method: "PUT", path: "/webhooks", tag: "webhooks", summary: "Edit a subscription, rotating the secret only when one is given", request: webhook.Subscription{}, response: webhook.Subscription{}},
+	{method: "DELETE", path: "/webhooks", tag: "webhooks", summary: "Delete a subscription", request: IDRequest{}, response: CustomResponse{}},
+	{method: "GET", path: "/webhooks/{webhookID}/deliveries", tag: "webhooks", summary: "List a subscription's deliveries and their attempts, newest first", query: []string{"status"}, response: webhook.Deliveries{}},
+	{method: "POST", path: "/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", tag: "webhooks", summary: "Queue a delivery to be sent again", status: http.StatusAccepted, response: webhook.Delivery{}},
+
+	{method: "GET", path: "/notifications/emails", tag: "notifications", summary: "List queued and sent emails, newest first", query: []string{"status"}, response: notify.Messages{}},
+	{method: "GET", path: "/notifications/templates", tag: "notifications", summary: "List email template names", response: []string{}},
+	{method: "GET", path: "/notifications/templates/{name}/preview", tag: "notifications", summary: "Render an email template with sample data", query: []string{"format"}, response: notify.Rendered{}},
+
+	{method: "GET", path: "/admin/backups", tag: "admin", summary: "List backups, newest first", response: []backup.Snapshot{}},
+	{method: "POST", path: "/admin/backups", tag: "admin", summary: "Take a backup of every storage file, damage photo and document now", status: http.StatusCreated, response: backup.Snapshot{}},
+}
+
+func checkRouteDocs(router *mux.Router, docs []operationDoc) error {
+	documented := map[string]bool{}
+	for _, doc := range docs {
+		documented[doc.path] = true
+	}
+
+	registered := map[string]bool{}
+	undocumented := []string{}
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		registered[template] = true
+		if !documented[template] {
+			undocumented = append(undocumented, template)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	unrouted := []string{}
+	for path := range documented {
+		if !registered[path] {
+			unrouted = append(unrouted, path)
+		}
+	}
+	sort.Strings(unrouted)
+
+	if len(undocumented) > 0 {
+		return fmt.Errorf("routes missing from the OpenAPI document: %s", strings.Join(undocumented, ", "))
+	}
+
+	if len(unrouted) > 0 {
+		return fmt.Errorf("OpenAPI document describes unregistered routes: %s", strings.Join(unrouted, ", "))
+	}
+
+	return nil
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	if pkg == "api" || strings.HasPrefix(strings.ToLower(t.Name()), pkg) {
+		return t.Name()
+	}
+
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + t.Name()
+}
+
+func (sb *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	if t == rawMessageType {
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"anyOf": []any{sb.schema(t.Elem()), map[string]any{"type": "null"}}}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return sb.object(t)
+		}
+
+		name := componentName(t)
+		if _, ok := sb.components[name]; !ok {
+			sb.components[name] = nil
+			sb.components[name] = sb.object(t)
+		}
+
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": sb.schema(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": sb.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (sb *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	sb.addFields(t, properties)
+
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func (sb *schemaBuilder) addFields(t reflect.Type, properties map[string]any) {
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			sb.addFields(field.Type, properties)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = sb.schema(field.Type)
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func errorResponse(sb *schemaBuilder, description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": sb.schema(reflect.TypeOf(APIError{}))}},
+	}
+}
+
+func (sb *schemaBuilder) operation(doc operationDoc) map[string]any {
+	parameters := []any{}
+
+	for _, match := range pathParam.FindAllStringSubmatch(doc.path, -1) {
+		parameters = append(parameters, map[string]any{"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+
+	for _, name := range doc.query {
+		parameters = append(parameters, map[string]any{"name": name, "in": "query", "description": queryDescriptions[name], "schema": map[string]any{"type": "string"}})
+	}
+
+	if doc.employee {
+		parameters = append(parameters, map[string]any{"name": employeeHeader, "in": "header", "required": true, "description": "personal ID of the employee handling the vehicle", "schema": map[string]any{"type": "string"}})
+	}
+
+	if doc.override {
+		parameters = append(parameters, map[string]any{"name": managerOverrideHeader, "in": "header", "description": "personal ID of a manager approving a rental to a customer with risk flags", "schema": map[string]any{"type": "string"}})
+	}
+
+	status := doc.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case doc.response != nil:
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": sb.schema(reflect.TypeOf(doc.response))}}
+	case doc.contentType != "":
+		success["content"] = map[string]any{doc.contentType: map[string]any{"schema": map[string]any{"type": "string"}}}
+	}
+
+	operation := map[string]any{
+		"tags":        []string{doc.tag},
+		"summary":     doc.summary,
+		"operationId": operationID(doc),
+		"parameters":  parameters,
+		"responses": map[string]any{
+			strconv.Itoa(status): success,
+			"400":                errorResponse(sb, "Invalid request"),
+			"429":                errorResponse(sb, "Rate limit exceeded, see the Retry-After header"),
+		},
+	}
+
+	if doc.request != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": sb.schema(reflect.TypeOf(doc.request))}},
+		}
+	}
+
+	if doc.requestType != "" {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{doc.requestType: map[string]any{"schema": map[string]any{"type": "string"}}},
+		}
+	}
+
+	if len(doc.form) > 0 || doc.files != "" || doc.file != "" {
+		properties := map[string]any{}
+		for _, field := range doc.form {
+			properties[field] = map[string]any{"type": "string"}
+		}
+		if doc.files != "" {
+			properties[doc.files] = map[string]any{"type": "array", "items": map[string]any{"type": "string", "contentMediaType": "application/octet-stream"}}
+		}
+		if doc.file != "" {
+			properties[doc.file] = map[string]any{"type": "string", "contentMediaType": "application/octet-stream"}
+		}
+
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"multipart/form-data": map[string]any{"schema": map[string]any{"type": "object", "properties": properties}}},
+		}
+	}
+
+	return operation
+}
+
+func operationID(doc operationDoc) string {
+	parts := []string{strings.ToLower(doc.method)}
+	for _, segment := range strings.Split(doc.path, "/") {
+		segment = strings.Trim(segment, "{}")
+		segment = strings.NewReplacer("-", "", ".", "").Replace(segment)
+		if segment != "" {
+			parts = append(parts, strings.ToUpper(segment[:1])+segment[1:])
+		}
+	}
+
+	return strings.Join(parts, "")
+}
+
+func openAPIDocument(docs []operationDoc) map[string]any {
+	sb := &schemaBuilder{components: map[string]any{}}
+	paths := map[string]any{}
+
+	for _, doc := range docs {
+		item, ok := paths[doc.path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[doc.path] = item
+		}
+
+		item[strings.ToLower(doc.method)] = sb.operation(doc)
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "RWAPIGolang",
+			"description": "Rental workshop API for customers, vehicles, employees, branches, reservations and payments.",
+			"version":     "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": sb.components},
+	}
+}
+
+func (s *APIServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	return WriteJSON(w, http.StatusOK, openAPIDocument(operationDocs))
+}
+
+func (s *APIServer) handleDocs(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := w.Write(docsPage)
+	return err
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	s := &APIServer{serverConfig: config.Default().Server, metrics: newServerMetrics()}
+
+	if err := checkRouteDocs(s.routes(), operationDocs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOperationsAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, doc := range operationDocs {
+		key := doc.method + " " + doc.path
+		if seen[key] {
+			t.Errorf("%s is documented twice", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestModelSchemas(t *testing.T) {
+	document := openAPIDocument(operationDocs)
+	schemas := document["components"].(map[string]any)["schemas"].(map[string]any)
+
+	tests := []struct {
+		name   string
+		fields map[string]map[string]any
+	}{
+		{
+			name: "Customer",
+			fields: map[string]map[string]any{
+				"FirstName":      {"type": "string"},
+				"LastName":       {"type": "string"},
+				"PersonalID":     {"type": "integer", "format": "int64"},
+				"PhoneNumber":    {"type": "string"},
+				"Email":          {"type": "string"},
+				"RentedVehicles": {"type": "array", "items": map[string]any{"$ref": "#/components/schemas/Vehicle"}},
+				"RiskFlags":      {"type": "array", "items": map[string]any{"$ref": "#/components/schemas/CustomerRiskFlag"}},
+				"CreatedAt":      {"type": "string", "format": "date-time"},
+				"LastEditedAt":   {"anyOf": []any{map[string]any{"type": "string", "format": "date-time"}, map[string]any{"type": "null"}}},
+			},
+		},
+		{
+			name: "Vehicle",
+			fields: map[string]map[string]any{
+				"PlateNumber": {"type": "string"},
+				"Make":        {"type": "string"},
+				"Model":       {"type": "string"},
+				"Year":        {"type": "integer"},
+				"FuelType":    {"type": "string"},
+				"Gearbox":     {"type": "string"},
+				"Color":       {"type": "string"},
+				"Body":        {"type": "string"},
+				"BranchID":    {"type": "integer"},
+			},
+		},
+		{
+			name: "Employee",
+			fields: map[string]map[string]any{
+				"FirstName":   {"type": "string"},
+				"LastName":    {"type": "string"},
+				"PersonalID":  {"type": "integer", "format": "int64"},
+				"DateOfBirth": {"type": "string"},
+				"Email":       {"type": "string"},
+				"PhoneNumber": {"type": "string"},
+				"Address":     {"type": "string"},
+				"BranchID":    {"type": "integer"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, ok := schemas[tt.name].(map[string]any)
+			if !ok {
+				t.Fatalf("schema %s is missing", tt.name)
+			}
+
+			if schema["type"] != "object" {
+				t.Errorf("type = %v, want object", schema["type"])
+			}
+
+			properties := schema["properties"].(map[string]any)
+			if len(properties) != len(tt.fields) {
+				t.Errorf("%d properties, want %d: %v", len(properties), len(tt.fields), properties)
+			}
+
+			for name, want := range tt.fields {
+				got, ok := properties[name]
+				if !ok {
+					t.Errorf("property %s is missing", name)
+					continue
+				}
+
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("property %s = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
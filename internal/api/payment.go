@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/payment"
+)
+
+type PaymentRequest struct {
+	Type        string  `json:"Type"`
+	Amount      float64 `json:"Amount"`
+	Reference   string  `json:"Reference"`
+	Description string  `json:"Description"`
+}
+
+func (s *APIServer) handleCustomerPayment(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetCustomerPayments(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleAddCustomerPayment(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleGetCustomerPayments(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if _, err := s.customerStorage.GetCustomer(personalID); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	entries, err := s.paymentStorage.GetEntries(personalID)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, entries)
+}
+
+func (s *APIServer) handleAddCustomerPayment(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var input PaymentRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var entry payment.Entry
+	err = s.transaction(func(tx *APIServer) error {
+		if _, err := tx.customerStorage.GetCustomer(personalID); err != nil {
+			return notFound(err)
+		}
+
+		switch input.Type {
+		case payment.TypeCharge:
+			entry, err = tx.paymentStorage.PostCharge(personalID, input.Amount, input.Description)
+		case payment.TypePayment:
+			entry, err = tx.paymentStorage.TakePayment(personalID, input.Amount, input.Description)
+		case payment.TypeRefund:
+			entry, err = tx.paymentStorage.Refund(personalID, input.Amount, input.Reference, input.Description)
+		default:
+			err = fmt.Errorf("invalid input: payment type may only be (%s / %s / %s)", payment.TypeCharge, payment.TypePayment, payment.TypeRefund)
+		}
+
+		return err
+	})
+	if err != nil {
+		return writeError(w, err)
+	}
+
+	// The provider is called once the entry has committed, read back how it
+	// went.
+	if entry.Status == payment.StatusPending {
+		if entry, err = s.paymentStorage.Outcome(entry.ID); err != nil {
+			return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+		}
+	}
+
+	if entry.Status == payment.StatusFailed {
+		return WriteJSON(w, http.StatusPaymentRequired, APIError{Error: entry.Failure})
+	}
+
+	return WriteJSON(w, http.StatusOK, entry)
+}
+
+func (s *APIServer) handleCustomerBalance(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if _, err := s.customerStorage.GetCustomer(personalID); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	balance, err := s.paymentStorage.GetBalance(personalID)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, balance)
+}
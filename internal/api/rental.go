@@ -0,0 +1,19 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func (s *APIServer) handleOverdueRentals(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	overdue, err := s.rentalStorage.GetOverdueRentals(s.clock())
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, overdue)
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/reservation"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *APIServer) handleReservation(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetReservations(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleAddReservation(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleGetReservations(w http.ResponseWriter, r *http.Request) error {
+	reservations, err := s.reservationStorage.GetReservations()
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	branchID, err := branchFilter(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if branchID != 0 {
+		filtered := reservation.Reservations{}
+		for _, reservation := range reservations {
+			if reservation.PickupBranchID == branchID {
+				filtered = append(filtered, reservation)
+			}
+		}
+		reservations = filtered
+	}
+
+	return WriteJSON(w, http.StatusOK, reservations)
+}
+
+func (s *APIServer) handleAddReservation(w http.ResponseWriter, r *http.Request) error {
+	var newReservation reservation.Reservation
+	if err := decodeJSON(r, &newReservation); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var added reservation.Reservation
+	err := s.transaction(func(tx *APIServer) error {
+		if _, err := tx.customerStorage.GetCustomer(newReservation.PersonalID); err != nil {
+			return err
+		}
+
+		if err := tx.ensureBranchExists(newReservation.PickupBranchID); err != nil {
+			return err
+		}
+
+		if err := tx.ensureBranchExists(newReservation.ReturnBranchID); err != nil {
+			return err
+		}
+
+		vehicles, err := tx.vehicleStorage.GetVehicles()
+		if err != nil {
+			return err
+		}
+
+		added, err = tx.reservationStorage.AddReservation(newReservation, vehicles)
+		return err
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, added)
+}
+
+func (s *APIServer) handleCancelReservation(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	reservationID, err := strconv.Atoi(mux.Vars(r)["reservationID"])
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	reservation, err := s.reservationStorage.CancelReservation(reservationID)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, reservation)
+}
+
+type PickupRequest struct {
+	PlateNumber string `json:"PlateNumber"`
+	VehicleReading
+}
+
+func (s *APIServer) handlePickupReservation(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	reservationID, err := strconv.Atoi(mux.Vars(r)["reservationID"])
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var input PickupRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if err := input.VehicleReading.validate(); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	employeeID, err := s.handlingEmployee(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	managerID, err := s.overridingManager(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var converted reservation.Reservation
+	err = s.transaction(func(tx *APIServer) error {
+		booking, err := tx.reservationStorage.GetReservation(reservationID)
+		if err != nil {
+			return err
+		}
+
+		if booking.Status != reservation.StatusActive {
+			return fmt.Errorf("reservation with ID %d is %s", reservationID, booking.Status)
+		}
+
+		if tx.reservationStorage.Expired(booking, tx.clock()) {
+			return fmt.Errorf("reservation with ID %d has expired, it had to be picked up by %s", reservationID, tx.reservationStorage.PickupDeadline(booking).Format(time.RFC3339))
+		}
+
+		plateNumber := input.PlateNumber
+		if plateNumber == "" {
+			plateNumber = booking.PlateNumber
+		}
+
+		pickedVehicle, err := tx.vehicleStorage.GetVehicle(plateNumber)
+		if err != nil {
+			return err
+		}
+
+		if !booking.Matches(pickedVehicle) {
+			return fmt.Errorf("vehicle %v does not match reservation %d", plateNumber, reservationID)
+		}
+
+		_, rental, err := tx.checkoutVehicle(booking.PersonalID, pickedVehicle, input.VehicleReading, &booking.ReturnAt, employeeID, managerID)
+		if err != nil {
+			return err
+		}
+
+		converted, err = tx.reservationStorage.ConvertReservation(reservationID, rental.ID)
+		return err
+	})
+	if err != nil {
+		return writeError(w, err)
+	}
+
+	return WriteJSON(w, http.StatusOK, converted)
+}
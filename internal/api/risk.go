@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+)
+
+const managerOverrideHeader = "X-Manager-Override"
+
+type RiskFlagRequest struct {
+	Reason    string     `json:"Reason"`
+	ExpiresAt *time.Time `json:"ExpiresAt"`
+}
+
+// overridingManager returns the manager named in the override header, or 0
+// when there is none.
+func (s *APIServer) overridingManager(r *http.Request) (int64, error) {
+	value := r.Header.Get(managerOverrideHeader)
+	if value == "" {
+		return 0, nil
+	}
+
+	personalID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input: %s header must be a personal ID", managerOverrideHeader)
+	}
+
+	if _, err := s.employeeStorage.GetEmployee(personalID); err != nil {
+		return 0, err
+	}
+
+	return personalID, nil
+}
+
+// checkCustomerRisk rejects customers with active risk flags unless a manager
+// overrides them. An override is recorded as an event and its manager is
+// returned so the rental keeps it too.
+func (s *APIServer) checkCustomerRisk(renter customer.Customer, plateNumber string, employeeID, managerID int64) (int64, error) {
+	err := renter.CheckRisk(s.clock())
+	if err == nil {
+		return 0, nil
+	}
+
+	var flagged *customer.FlaggedError
+	if !errors.As(err, &flagged) {
+		return 0, err
+	}
+
+	if managerID == 0 {
+		return 0, forbidden(fmt.Errorf("%w; a manager can approve the rental with the %s header", err, managerOverrideHeader))
+	}
+
+	err = s.customerStorage.OverrideRisk(customer.RiskOverride{
+		PersonalID:  renter.PersonalID,
+		PlateNumber: plateNumber,
+		ManagerID:   managerID,
+		EmployeeID:  employeeID,
+		Flags:       flagged.Flags,
+	})
+	if err != nil {
+		return 0, forbidden(err)
+	}
+
+	return managerID, nil
+}
+
+func (s *APIServer) handleCustomerRiskFlags(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetRiskFlags(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleAddRiskFlag(w, r)
+	}
+	if r.Method == "DELETE" {
+		return s.handleRemoveRiskFlag(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleGetRiskFlags(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	flags, err := s.customerStorage.GetRiskFlags(personalID)
+	if err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, flags)
+}
+
+func (s *APIServer) handleAddRiskFlag(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var input RiskFlagRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	employeeID, err := s.handlingEmployee(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var flag customer.RiskFlag
+	err = s.transaction(func(tx *APIServer) (err error) {
+		flag, err = tx.customerStorage.AddRiskFlag(personalID, customer.RiskFlag{
+			Reason:    input.Reason,
+			SetBy:     employeeID,
+			ExpiresAt: input.ExpiresAt,
+		})
+		return err
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusCreated, flag)
+}
+
+func (s *APIServer) handleRemoveRiskFlag(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var input IDRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	employeeID, err := s.handlingEmployee(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	err = s.transaction(func(tx *APIServer) error {
+		return tx.customerStorage.RemoveRiskFlag(personalID, input.ID, employeeID)
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, CustomResponse{Response: "risk flag removed"})
+}
@@ -0,0 +1,151 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/app"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+)
+
+const (
+	riskCustomerID = 49001010000
+	riskClerkID    = 38001010001
+	riskManagerID  = 38001010002
+)
+
+func newRiskServer(t *testing.T, flags []customer.RiskFlag) (*app.Storages, http.Handler) {
+	t.Helper()
+
+	storages, cfg := newTestStoragesWith(t, func(cfg *config.Config) {
+		cfg.RiskPolicy.OverrideManagers = []int64{riskManagerID}
+	})
+
+	if err := storages.Employees.GetStorage().Save(employee.Employees{
+		{FirstName: "Jaan", LastName: "Kask", PersonalID: riskClerkID},
+		{FirstName: "Tiina", LastName: "Mets", PersonalID: riskManagerID},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Customers.GetStorage().Save(customer.Customers{{FirstName: "Mari", LastName: "Tamm", PersonalID: riskCustomerID, RiskFlags: flags}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Vehicles.GetStorage().Save(vehicle.Vehicles{"123ABC": {PlateNumber: "123ABC", Make: "Toyota", Model: "Corolla"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return storages, storages.NewAPIServer(cfg).Handler()
+}
+
+func checkout(t *testing.T, handler http.Handler, managerID int64, dueBackAt time.Time) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body := `{"PlateNumber": "123ABC", "Odometer": 1000, "FuelLevel": 80, "DueBackAt": "` + dueBackAt.Format(time.RFC3339) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/customers/"+strconv.Itoa(riskCustomerID)+"/vehicles", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Employee-ID", strconv.Itoa(riskClerkID))
+	if managerID != 0 {
+		req.Header.Set("X-Manager-Override", strconv.FormatInt(managerID, 10))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func overrideEvents(t *testing.T, storages *app.Storages) int {
+	t.Helper()
+
+	found, err := storages.Events.Since(0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overrides := 0
+	for _, event := range found {
+		if event.Type == events.RiskFlagOverridden {
+			overrides++
+		}
+	}
+
+	return overrides
+}
+
+func TestCheckoutRiskFlags(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	flag := customer.RiskFlag{ID: 1, Reason: "unpaid damages", SetBy: riskClerkID, SetAt: past.Add(-time.Hour)}
+	expired := customer.RiskFlag{ID: 1, Reason: "late return", SetBy: riskClerkID, SetAt: past.Add(-time.Hour), ExpiresAt: &past}
+	dueBackAt := time.Now().Add(48 * time.Hour)
+
+	tests := []struct {
+		name          string
+		flags         []customer.RiskFlag
+		managerID     int64
+		wantStatus    int
+		wantOverrides int
+	}{
+		{"flagged customer", []customer.RiskFlag{flag}, 0, http.StatusForbidden, 0},
+		{"expired flag", []customer.RiskFlag{expired}, 0, http.StatusOK, 0},
+		{"override by a non-manager", []customer.RiskFlag{flag}, riskClerkID, http.StatusForbidden, 0},
+		{"override by an unknown employee", []customer.RiskFlag{flag}, 38001019999, http.StatusBadRequest, 0},
+		{"override by a manager", []customer.RiskFlag{flag}, riskManagerID, http.StatusOK, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storages, handler := newRiskServer(t, test.flags)
+
+			rec := checkout(t, handler, test.managerID, dueBackAt)
+			if rec.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, test.wantStatus, rec.Body)
+			}
+
+			if overrides := overrideEvents(t, storages); overrides != test.wantOverrides {
+				t.Errorf("%d override events, want %d", overrides, test.wantOverrides)
+			}
+
+			rentals, err := storages.Rentals.GetRentals()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.wantStatus != http.StatusOK {
+				if len(rentals) != 0 {
+					t.Errorf("rentals = %+v, want none", rentals)
+				}
+				return
+			}
+
+			if len(rentals) != 1 {
+				t.Fatalf("rentals = %+v, want one", rentals)
+			}
+			if rentals[0].RiskOverrideBy != test.managerID {
+				t.Errorf("rental override by %d, want %d", rentals[0].RiskOverrideBy, test.managerID)
+			}
+		})
+	}
+}
+
+func TestRiskOverrideRolledBackWithCheckout(t *testing.T) {
+	storages, handler := newRiskServer(t, []customer.RiskFlag{{ID: 1, Reason: "unpaid damages", SetBy: riskClerkID, SetAt: time.Now()}})
+
+	// The due-back time fails the checkout after the override was recorded,
+	// so the override event goes with the rest of the transaction.
+	rec := checkout(t, handler, riskManagerID, time.Now().Add(-time.Hour))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "due-back") {
+		t.Fatalf("status = %d, want %d for the due-back time: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+
+	if overrides := overrideEvents(t, storages); overrides != 0 {
+		t.Errorf("%d override events after a failed checkout, want 0", overrides)
+	}
+}
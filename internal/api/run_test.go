@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/api"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func TestRunDrainsOnShutdown(t *testing.T) {
+	addr := freeAddr(t)
+	storages, cfg := newTestStoragesWith(t, func(cfg *config.Config) {
+		cfg.ListenAddr = addr
+		cfg.Server.RateLimits = nil
+		cfg.Server.ShutdownTimeout = config.Duration{Duration: 5 * time.Second}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- storages.NewAPIServer(cfg).Run(ctx) }()
+
+	// Shutdown only treats connections that never sent a request as idle
+	// after a few seconds, so do not leave spare keep-alive dials behind.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	base := "http://" + addr
+	var err error
+	for range 50 {
+		var resp *http.Response
+		if resp, err = client.Get(base + "/customers"); err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("server did not start: %v", err)
+	}
+
+	body := `{"FirstName": "Mari", "LastName": "Tamm", "PersonalID": 49001010001, "PhoneNumber": "5551001", "Email": "mari.tamm@example.com"}`
+	resp, err := client.Post(base+"/customers", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("create status = %d", resp.StatusCode)
+	}
+
+	// A long poll waiting for newer events is answered, not cut off, when
+	// the server shuts down.
+	polled := make(chan *http.Response, 1)
+	pollErr := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(base + "/events?since=1000&wait=30s")
+		if err != nil {
+			pollErr <- err
+			return
+		}
+		polled <- resp
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+
+	select {
+	case resp := <-polled:
+		defer resp.Body.Close()
+		var events api.EventsResponse
+		if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&events) != nil {
+			t.Errorf("long poll status = %d, want a 200 with an events response", resp.StatusCode)
+		}
+	case err := <-pollErr:
+		t.Fatalf("long poll failed: %v", err)
+	}
+
+	if resp, err := client.Get(base + "/customers"); err == nil {
+		resp.Body.Close()
+		t.Error("server still accepting requests after Run returned")
+	}
+
+	storage.Flush()
+
+	saved := customer.Customers{}
+	if err := storages.Customers.GetStorage().Load(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].PersonalID != 49001010001 {
+		t.Errorf("customers on disk = %+v, want the one created before shutdown", saved)
+	}
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/shift"
+
+	"github.com/gorilla/mux"
+)
+
+const activityDateLayout = "2006-01-02"
+
+type EmployeeActivity struct {
+	PersonalID       int64
+	From             string
+	To               string
+	RentalsProcessed int
+	Checkouts        rental.Rentals
+	Returns          rental.Rentals
+	RevenueHandled   float64
+	Shifts           shift.Shifts
+	HoursWorked      float64
+}
+
+func (s *APIServer) handleEmployeeShift(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetEmployeeShifts(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleAddEmployeeShift(w, r)
+	}
+	if r.Method == "DELETE" {
+		return s.handleDeleteEmployeeShift(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func personalIDFromPath(r *http.Request) (int64, error) {
+	return strconv.ParseInt(mux.Vars(r)["personalID"], 10, 64)
+}
+
+func dateRange(r *http.Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+
+	if value := r.URL.Query().Get("from"); value != "" {
+		date, err := time.Parse(activityDateLayout, value)
+		if err != nil {
+			return from, to, errors.New("invalid input: from must be a date in YYYY-MM-DD format")
+		}
+		from = date
+	}
+
+	if value := r.URL.Query().Get("to"); value != "" {
+		date, err := time.Parse(activityDateLayout, value)
+		if err != nil {
+			return from, to, errors.New("invalid input: to must be a date in YYYY-MM-DD format")
+		}
+		to = date.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, errors.New("invalid input: to may not be before from")
+	}
+
+	return from, to, nil
+}
+
+func inRange(at, from, to time.Time) bool {
+	if !from.IsZero() && at.Before(from) {
+		return false
+	}
+
+	if !to.IsZero() && at.After(to) {
+		return false
+	}
+
+	return true
+}
+
+func (s *APIServer) handleGetEmployeeShifts(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	from, to, err := dateRange(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	shifts, err := s.shiftStorage.GetShifts(personalID, from, to)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	branchID, err := branchFilter(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if branchID != 0 {
+		filtered := shift.Shifts{}
+		for _, shift := range shifts {
+			if shift.BranchID == branchID {
+				filtered = append(filtered, shift)
+			}
+		}
+		shifts = filtered
+	}
+
+	return WriteJSON(w, http.StatusOK, shifts)
+}
+
+func (s *APIServer) handleAddEmployeeShift(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var newShift shift.Shift
+	if err := decodeJSON(r, &newShift); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+	newShift.PersonalID = personalID
+
+	var added shift.Shift
+	err = s.transaction(func(tx *APIServer) error {
+		if _, err := tx.employeeStorage.GetEmployee(personalID); err != nil {
+			return err
+		}
+
+		if _, err := tx.branchStorage.GetBranch(newShift.BranchID); err != nil {
+			return err
+		}
+
+		added, err = tx.shiftStorage.AddShift(newShift)
+		return err
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, added)
+}
+
+func (s *APIServer) handleDeleteEmployeeShift(w http.ResponseWriter, r *http.Request) error {
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var shiftID IDRequest
+	if err := decodeJSON(r, &shiftID); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	err = s.transaction(func(tx *APIServer) error {
+		if _, err := tx.shiftStorage.GetShift(personalID, shiftID.ID); err != nil {
+			return notFound(err)
+		}
+
+		return tx.shiftStorage.DeleteShift(personalID, shiftID.ID)
+	})
+	if err != nil {
+		return writeError(w, err)
+	}
+
+	return WriteJSON(w, http.StatusOK, CustomResponse{Response: "shift deleted"})
+}
+
+func (s *APIServer) handleEmployeeActivity(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	personalID, err := personalIDFromPath(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if _, err := s.employeeStorage.GetEmployee(personalID); err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	from, to, err := dateRange(r)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	rentals, err := s.rentalStorage.GetRentals()
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	shifts, err := s.shiftStorage.GetShifts(personalID, from, to)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	activity := EmployeeActivity{
+		PersonalID: personalID,
+		Checkouts:  rental.Rentals{},
+		Returns:    rental.Rentals{},
+		Shifts:     shifts,
+	}
+
+	if !from.IsZero() {
+		activity.From = from.Format(activityDateLayout)
+	}
+
+	if !to.IsZero() {
+		activity.To = to.Format(activityDateLayout)
+	}
+
+	for _, rental := range rentals {
+		if rental.CheckoutBy == personalID && inRange(rental.CheckoutAt, from, to) {
+			activity.Checkouts = append(activity.Checkouts, rental)
+		}
+
+		if rental.ReturnedBy == personalID && rental.ReturnedAt != nil && inRange(*rental.ReturnedAt, from, to) {
+			activity.Returns = append(activity.Returns, rental)
+			activity.RevenueHandled += rental.TotalCharges()
+		}
+	}
+
+	for _, shift := range shifts {
+		activity.HoursWorked += shift.Hours()
+	}
+
+	activity.RentalsProcessed = len(activity.Checkouts) + len(activity.Returns)
+	activity.RevenueHandled = math.Round(activity.RevenueHandled*100) / 100
+	activity.HoursWorked = math.Round(activity.HoursWorked*100) / 100
+
+	return WriteJSON(w, http.StatusOK, activity)
+}
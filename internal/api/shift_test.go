@@ -0,0 +1,180 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/api"
+	"github.com/ZulfiPy/RWAPIGo/internal/app"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/shift"
+)
+
+const (
+	shiftEmployeeID = 38001010006
+	otherEmployeeID = 38001010007
+)
+
+func at(value string) time.Time {
+	parsed, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		panic(err)
+	}
+
+	return parsed
+}
+
+func atPtr(value string) *time.Time {
+	parsed := at(value)
+	return &parsed
+}
+
+// newShiftServer seeds three shifts and three rentals handled by
+// shiftEmployeeID between 1 and 5 March 2030.
+func newShiftServer(t *testing.T) (*app.Storages, http.Handler) {
+	t.Helper()
+
+	storages, cfg := newTestStorages(t)
+
+	if err := storages.Employees.GetStorage().Save(employee.Employees{
+		{FirstName: "Jaan", LastName: "Kask", PersonalID: shiftEmployeeID},
+		{FirstName: "Tiina", LastName: "Mets", PersonalID: otherEmployeeID},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Shifts.GetStorage().Save(shift.Shifts{
+		{ID: 1, PersonalID: shiftEmployeeID, BranchID: 1, StartsAt: at("2030-03-01 08:00:00"), EndsAt: at("2030-03-01 16:00:00")},
+		{ID: 2, PersonalID: shiftEmployeeID, BranchID: 1, StartsAt: at("2030-03-02 09:00:00"), EndsAt: at("2030-03-02 13:30:00")},
+		{ID: 3, PersonalID: shiftEmployeeID, BranchID: 1, StartsAt: at("2030-03-05 08:00:00"), EndsAt: at("2030-03-05 12:00:00")},
+		{ID: 4, PersonalID: otherEmployeeID, BranchID: 1, StartsAt: at("2030-03-01 08:00:00"), EndsAt: at("2030-03-01 12:00:00")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storages.Rentals.GetStorage().Save(rental.Rentals{
+		{ID: 1, PlateNumber: "123ABC", CheckoutBy: shiftEmployeeID, CheckoutAt: at("2030-03-01 10:00:00"),
+			ReturnedBy: shiftEmployeeID, ReturnedAt: atPtr("2030-03-02 20:00:00"), OverageCharge: 10.25, RefuelCharge: 5},
+		{ID: 2, PlateNumber: "456DEF", CheckoutBy: otherEmployeeID, CheckoutAt: at("2030-02-28 10:00:00"),
+			ReturnedBy: shiftEmployeeID, ReturnedAt: atPtr("2030-03-02 23:30:00"), LateFee: 40},
+		{ID: 3, PlateNumber: "123ABC", CheckoutBy: shiftEmployeeID, CheckoutAt: at("2030-03-03 00:00:00"),
+			ReturnedBy: shiftEmployeeID, ReturnedAt: atPtr("2030-03-05 11:00:00"), OneWayFee: 50},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return storages, storages.NewAPIServer(cfg).Handler()
+}
+
+func rentalIDs(rentals rental.Rentals) []int {
+	ids := []int{}
+	for _, rental := range rentals {
+		ids = append(ids, rental.ID)
+	}
+
+	return ids
+}
+
+func TestEmployeeActivity(t *testing.T) {
+	_, handler := newShiftServer(t)
+
+	tests := []struct {
+		name          string
+		query         string
+		wantCheckouts []int
+		wantReturns   []int
+		wantRevenue   float64
+		wantShifts    int
+		wantHours     float64
+	}{
+		{"everything", "", []int{1, 3}, []int{1, 2, 3}, 105.25, 3, 16.5},
+		{"to includes the whole day", "?from=2030-03-01&to=2030-03-02", []int{1}, []int{1, 2}, 55.25, 2, 12.5},
+		{"from only", "?from=2030-03-03", []int{3}, []int{3}, 50, 1, 4},
+		{"single day", "?from=2030-03-02&to=2030-03-02", []int{}, []int{1, 2}, 55.25, 1, 4.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(handler, http.MethodGet, "/employees/"+strconv.Itoa(shiftEmployeeID)+"/activity"+test.query, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+
+			var activity api.EmployeeActivity
+			if err := json.Unmarshal(rec.Body.Bytes(), &activity); err != nil {
+				t.Fatal(err)
+			}
+
+			checkouts, returns := rentalIDs(activity.Checkouts), rentalIDs(activity.Returns)
+			if !slices.Equal(checkouts, test.wantCheckouts) || !slices.Equal(returns, test.wantReturns) {
+				t.Errorf("checkouts %v and returns %v, want %v and %v", checkouts, returns, test.wantCheckouts, test.wantReturns)
+			}
+			if activity.RentalsProcessed != len(test.wantCheckouts)+len(test.wantReturns) {
+				t.Errorf("rentals processed = %d", activity.RentalsProcessed)
+			}
+			if activity.RevenueHandled != test.wantRevenue {
+				t.Errorf("revenue = %.2f, want %.2f", activity.RevenueHandled, test.wantRevenue)
+			}
+			if len(activity.Shifts) != test.wantShifts || activity.HoursWorked != test.wantHours {
+				t.Errorf("%d shifts with %.2f hours, want %d with %.2f", len(activity.Shifts), activity.HoursWorked, test.wantShifts, test.wantHours)
+			}
+		})
+	}
+}
+
+func TestEmployeeActivityErrors(t *testing.T) {
+	_, handler := newShiftServer(t)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{"unknown employee", "/employees/38001019999/activity", http.StatusNotFound},
+		{"bad date", "/employees/" + strconv.Itoa(shiftEmployeeID) + "/activity?from=01.03.2030", http.StatusBadRequest},
+		{"to before from", "/employees/" + strconv.Itoa(shiftEmployeeID) + "/activity?from=2030-03-02&to=2030-03-01", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if rec := serve(handler, http.MethodGet, test.target, ""); rec.Code != test.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, test.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestDeleteEmployeeShift(t *testing.T) {
+	tests := []struct {
+		name       string
+		shiftID    int
+		wantStatus int
+		wantLeft   int
+	}{
+		{"own shift", 2, http.StatusOK, 3},
+		{"missing shift", 9, http.StatusNotFound, 4},
+		{"another employee's shift", 4, http.StatusNotFound, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storages, handler := newShiftServer(t)
+
+			rec := serve(handler, http.MethodDelete, "/employees/"+strconv.Itoa(shiftEmployeeID)+"/shifts", `{"ID": `+strconv.Itoa(test.shiftID)+`}`)
+			if rec.Code != test.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, test.wantStatus, rec.Body)
+			}
+
+			shifts := shift.Shifts{}
+			if err := storages.Shifts.GetStorage().Load(&shifts); err != nil {
+				t.Fatal(err)
+			}
+			if len(shifts) != test.wantLeft {
+				t.Errorf("%d shifts left, want %d", len(shifts), test.wantLeft)
+			}
+		})
+	}
+}
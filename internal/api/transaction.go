@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+func (s *APIServer) withTx(tx *storage.Tx) *APIServer {
+	scoped := *s
+	scoped.customerStorage = s.customerStorage.WithTx(tx)
+	scoped.vehicleStorage = s.vehicleStorage.WithTx(tx)
+	scoped.employeeStorage = s.employeeStorage.WithTx(tx)
+	scoped.rentalStorage = s.rentalStorage.WithTx(tx)
+	scoped.damageStorage = s.damageStorage.WithTx(tx)
+	scoped.documentStorage = s.documentStorage.WithTx(tx)
+	scoped.reservationStorage = s.reservationStorage.WithTx(tx)
+	scoped.branchStorage = s.branchStorage.WithTx(tx)
+	scoped.shiftStorage = s.shiftStorage.WithTx(tx)
+	scoped.paymentStorage = s.paymentStorage.WithTx(tx)
+	scoped.events = s.events.WithTx(tx)
+	scoped.webhooks = s.webhooks.WithTx(tx)
+	return &scoped
+}
+
+// transaction runs fn with every storage bound to one journaled transaction.
+// What fn saves is committed atomically when it returns nil and discarded
+// otherwise.
+func (s *APIServer) transaction(fn func(tx *APIServer) error) error {
+	tx, err := s.journal.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.withTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+func notFound(err error) error {
+	return &statusError{status: http.StatusNotFound, err: err}
+}
+
+func forbidden(err error) error {
+	return &statusError{status: http.StatusForbidden, err: err}
+}
+
+func writeError(w http.ResponseWriter, err error) error {
+	status := http.StatusBadRequest
+
+	var withStatus *statusError
+	if errors.As(err, &withStatus) {
+		status = withStatus.status
+	}
+
+	return WriteJSON(w, status, APIError{Error: err.Error()})
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/webhook"
+
+	"github.com/gorilla/mux"
+)
+
+// Secrets are only shown when a subscription is created.
+func redactSecret(sub webhook.Subscription) webhook.Subscription {
+	sub.Secret = ""
+	return sub
+}
+
+func (s *APIServer) handleWebhook(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == "GET" {
+		return s.handleGetWebhooks(w, r)
+	}
+	if r.Method == "POST" {
+		return s.handleAddWebhook(w, r)
+	}
+	if r.Method == "PUT" {
+		return s.handleEditWebhook(w, r)
+	}
+	if r.Method == "DELETE" {
+		return s.handleDeleteWebhook(w, r)
+	}
+	return fmt.Errorf("method %s not allowed", r.Method)
+}
+
+func (s *APIServer) handleGetWebhooks(w http.ResponseWriter, r *http.Request) error {
+	subscriptions, err := s.webhooks.GetSubscriptions()
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	for idx := range subscriptions {
+		subscriptions[idx] = redactSecret(subscriptions[idx])
+	}
+
+	return WriteJSON(w, http.StatusOK, subscriptions)
+}
+
+func (s *APIServer) handleAddWebhook(w http.ResponseWriter, r *http.Request) error {
+	var input webhook.Subscription
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if err := s.webhooks.CheckTarget(r.Context(), input.URL); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var created webhook.Subscription
+	err := s.transaction(func(tx *APIServer) (err error) {
+		created, err = tx.webhooks.AddSubscription(input)
+		return err
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusCreated, created)
+}
+
+func (s *APIServer) handleEditWebhook(w http.ResponseWriter, r *http.Request) error {
+	var input webhook.Subscription
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	if err := s.webhooks.CheckTarget(r.Context(), input.URL); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var edited webhook.Subscription
+	err := s.transaction(func(tx *APIServer) (err error) {
+		edited, err = tx.webhooks.EditSubscription(input)
+		return err
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, redactSecret(edited))
+}
+
+func (s *APIServer) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	var input IDRequest
+	if err := decodeJSON(r, &input); err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	err := s.transaction(func(tx *APIServer) error {
+		return tx.webhooks.DeleteSubscription(input.ID)
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, CustomResponse{Response: "webhook deleted"})
+}
+
+func (s *APIServer) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "GET" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	webhookID, err := strconv.Atoi(mux.Vars(r)["webhookID"])
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusDelivered, webhook.StatusFailed:
+	default:
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: fmt.Sprintf("unknown status %q, expected %s, %s or %s", status, webhook.StatusPending, webhook.StatusDelivered, webhook.StatusFailed)})
+	}
+
+	deliveries, err := s.webhooks.Deliveries(webhookID, status)
+	if err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusOK, deliveries)
+}
+
+func (s *APIServer) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	webhookID, err := strconv.Atoi(mux.Vars(r)["webhookID"])
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	deliveryID, err := strconv.ParseInt(mux.Vars(r)["deliveryID"], 10, 64)
+	if err != nil {
+		return WriteJSON(w, http.StatusBadRequest, APIError{Error: err.Error()})
+	}
+
+	var queued webhook.Delivery
+	err = s.transaction(func(tx *APIServer) (err error) {
+		queued, err = tx.webhooks.Redeliver(webhookID, deliveryID)
+		return err
+	})
+	if err != nil {
+		return WriteJSON(w, http.StatusNotFound, APIError{Error: err.Error()})
+	}
+
+	return WriteJSON(w, http.StatusAccepted, queued)
+}
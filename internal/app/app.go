@@ -0,0 +1,147 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/api"
+	"github.com/ZulfiPy/RWAPIGo/internal/backup"
+	"github.com/ZulfiPy/RWAPIGo/internal/blob"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/branch"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/damage"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/document"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/payment"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/reservation"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/shift"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/notify"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/internal/webhook"
+)
+
+type Storages struct {
+	Customers    *customer.CustomerStorage
+	Vehicles     *vehicle.VehicleStorage
+	Employees    *employee.EmployeeStorage
+	Rentals      *rental.RentalStorage
+	Damages      *damage.DamageStorage
+	Documents    *document.DocumentStorage
+	Reservations *reservation.ReservationStorage
+	Branches     *branch.BranchStorage
+	Shifts       *shift.ShiftStorage
+	Payments     *payment.PaymentStorage
+	Journal      *storage.Journal
+	Events       *events.Outbox
+	Webhooks     *webhook.Manager
+	Notifier     *notify.Notifier
+	Overdue      *rental.OverdueMonitor
+	Backups      *backup.Manager
+	Clock        rental.Clock
+}
+
+func OpenStorages(cfg config.Config) (*Storages, error) {
+	journal := storage.NewJournal(cfg.DataFile("journal.json"))
+	outbox := events.NewOutbox(cfg.DataFile("events.json"), cfg.EventRetention, journal)
+
+	st := &Storages{
+		Clock:        time.Now,
+		Customers:    customer.NewCustomerStorage(cfg.DataFile("customers.json"), customer.RiskPolicy(cfg.RiskPolicy), outbox),
+		Vehicles:     vehicle.NewVehicleStorage(cfg.DataFile("vehicles.json"), vehicle.Catalogue(cfg.VehicleCatalogue), outbox),
+		Employees:    employee.NewEmployeeStorage(cfg.DataFile("employees.json"), outbox),
+		Rentals:      rental.NewRentalStorage(cfg.DataFile("rentals.json"), rental.Pricing(cfg.RentalPricing), outbox),
+		Damages:      damage.NewDamageStorage(cfg.DataFile("damages.json"), cfg.DamagePhotoDir),
+		Documents:    document.NewDocumentStorage(cfg.DataFile("documents.json"), blob.NewStore(cfg.Documents.Dir, cfg.Documents.MaxSize, cfg.Documents.ContentTypes), journal),
+		Reservations: reservation.NewReservationStorage(cfg.DataFile("reservations.json"), cfg.ReservationGracePeriod.Duration, outbox),
+		Branches:     branch.NewBranchStorage(cfg.DataFile("branches.json")),
+		Shifts:       shift.NewShiftStorage(cfg.DataFile("shifts.json")),
+		Payments:     payment.NewPaymentStorage(cfg.DataFile("payments.json"), payment.NewLocalProvider(), cfg.DepositAmount, journal),
+		Journal:      journal,
+		Events:       outbox,
+		Webhooks: webhook.NewManager(cfg.DataFile("webhooks.json"), cfg.DataFile("webhook_deliveries.json"), webhook.Policy{
+			MaxAttempts:         cfg.Webhooks.MaxAttempts,
+			InitialBackoff:      cfg.Webhooks.InitialBackoff.Duration,
+			MaxBackoff:          cfg.Webhooks.MaxBackoff.Duration,
+			Timeout:             cfg.Webhooks.Timeout.Duration,
+			PollInterval:        cfg.Webhooks.PollInterval.Duration,
+			DeliveryRetention:   cfg.Webhooks.DeliveryRetention,
+			AllowPrivateTargets: cfg.Webhooks.AllowPrivateTargets,
+		}, journal, outbox),
+	}
+
+	templates, err := notify.LoadTemplates(cfg.Email.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	st.Notifier = notify.NewNotifier(cfg.DataFile("emails.json"), cfg.DataFile("notification_preferences.json"), templates, newSender(cfg.Email), notify.Policy{
+		MaxAttempts:    cfg.Email.MaxAttempts,
+		InitialBackoff: cfg.Email.InitialBackoff.Duration,
+		MaxBackoff:     cfg.Email.MaxBackoff.Duration,
+		PollInterval:   cfg.Email.PollInterval.Duration,
+		Retention:      cfg.Email.Retention,
+	}, journal, outbox, st.Customers, st.Vehicles, st.Rentals)
+
+	st.Overdue = rental.NewOverdueMonitor(st.Rentals, journal, cfg.Overdue.Interval.Duration, cfg.Overdue.ReminderLead.Duration, st.Clock)
+
+	st.Backups = backup.NewManager(cfg.Backup.Dir, st.Files(), []backup.Dir{
+		{Name: "damage_photos", Path: cfg.DamagePhotoDir},
+		{Name: "documents", Path: cfg.Documents.Dir},
+	}, cfg.Backup.Retain, st.Journal)
+
+	if err := st.Journal.Recover(); err != nil {
+		return nil, err
+	}
+
+	errs := []error{}
+	for _, file := range st.Files() {
+		errs = append(errs, file.Ensure())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+// Files lists every storage file with its empty contents, in the order
+// backups store them and readiness checks report them.
+func (st *Storages) Files() []storage.File {
+	return []storage.File{
+		storage.NewFile(st.Customers.GetStorage(), customer.Customers{}),
+		storage.NewFile(st.Vehicles.GetStorage(), vehicle.Vehicles{}),
+		storage.NewFile(st.Employees.GetStorage(), employee.Employees{}),
+		storage.NewFile(st.Rentals.GetStorage(), rental.Rentals{}),
+		storage.NewFile(st.Damages.GetStorage(), damage.Damages{}),
+		storage.NewFile(st.Documents.GetStorage(), document.Documents{}),
+		storage.NewFile(st.Reservations.GetStorage(), reservation.Reservations{}),
+		storage.NewFile(st.Branches.GetStorage(), branch.Branches{}),
+		storage.NewFile(st.Shifts.GetStorage(), shift.Shifts{}),
+		storage.NewFile(st.Payments.GetStorage(), payment.Entries{}),
+		storage.NewFile(st.Events.GetStorage(), events.Events{}),
+		storage.NewFile(st.Webhooks.GetStorage(), webhook.Subscriptions{}),
+		storage.NewFile(st.Webhooks.GetQueueStorage(), webhook.Queue{Deliveries: webhook.Deliveries{}}),
+		storage.NewFile(st.Notifier.GetStorage(), notify.Queue{Messages: notify.Messages{}}),
+		storage.NewFile(st.Notifier.GetPreferencesStorage(), notify.Preferences{}),
+	}
+}
+
+func newSender(cfg config.Email) notify.Sender {
+	switch cfg.Sender {
+	case notify.SenderSMTP:
+		return &notify.SMTPSender{Addr: cfg.SMTPAddr, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.From}
+	case notify.SenderFile:
+		return &notify.FileSender{Dir: cfg.Dir, From: cfg.From}
+	default:
+		return notify.LogSender{}
+	}
+}
+
+func (st *Storages) NewAPIServer(cfg config.Config) *api.APIServer {
+	return api.NewAPIServer(cfg.ListenAddr, cfg.Server, st.Customers, st.Vehicles, st.Employees, st.Rentals, st.Damages, st.Documents, st.Reservations, st.Branches, st.Shifts, st.Payments, st.Journal, st.Files(), st.Events, st.Webhooks, st.Notifier, st.Backups, st.Clock)
+}
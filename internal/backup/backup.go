@@ -0,0 +1,632 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const (
+	archivePrefix = "backup-"
+	archiveSuffix = ".tar.gz"
+	manifestName  = "manifest.json"
+	timeLayout    = "20060102T150405.000000000Z"
+)
+
+type File struct {
+	Name   string
+	Size   int64
+	SHA256 string
+}
+
+// Manifest is the first entry of every archive. Files lists the storage
+// files and the files of Dirs, which are stored as <dir name>/<path>.
+type Manifest struct {
+	CreatedAt time.Time
+	Files     []File
+	Dirs      []string `json:",omitempty"`
+}
+
+type Snapshot struct {
+	Name      string
+	CreatedAt time.Time
+	Size      int64
+	Files     []File
+}
+
+// Dir is a directory archived together with the storage files, such as the
+// damage photos or the document blobs. Name identifies it in the archive, so
+// a backup can be restored into a differently configured path.
+type Dir struct {
+	Name string
+	Path string
+}
+
+// Manager writes compressed snapshots of the storage files and directories.
+// Every snapshot is taken inside one storage transaction, so the files in an
+// archive are consistent with each other and with the directory contents
+// they refer to.
+type Manager struct {
+	dir     string
+	files   []storage.File
+	dirs    []Dir
+	retain  int
+	journal *storage.Journal
+	mu      sync.Mutex
+}
+
+func NewManager(dir string, files []storage.File, dirs []Dir, retain int, journal *storage.Journal) *Manager {
+	return &Manager{dir: dir, files: files, dirs: dirs, retain: retain, journal: journal}
+}
+
+// dirFile is a file of a Dir, with the path it is read from.
+type dirFile struct {
+	File
+	path string
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Manager) Snapshot() (Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return Snapshot{}, err
+	}
+
+	tx, err := m.journal.Begin()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer tx.Rollback()
+
+	manifest := Manifest{CreatedAt: time.Now().UTC()}
+
+	contents := make([][]byte, len(m.files))
+	for idx, file := range m.files {
+		data, err := tx.ReadFile(file.Name)
+		if err != nil {
+			return Snapshot{}, err
+		}
+
+		contents[idx] = data
+		manifest.Files = append(manifest.Files, File{Name: filepath.Base(file.Name), Size: int64(len(data)), SHA256: checksum(data)})
+	}
+
+	dirFiles := []dirFile{}
+	for _, dir := range m.dirs {
+		found, err := listDir(dir)
+		if err != nil {
+			return Snapshot{}, err
+		}
+
+		manifest.Dirs = append(manifest.Dirs, dir.Name)
+		dirFiles = append(dirFiles, found...)
+	}
+	for _, file := range dirFiles {
+		manifest.Files = append(manifest.Files, file.File)
+	}
+
+	name := archivePrefix + manifest.CreatedAt.Format(timeLayout) + archiveSuffix
+	size, err := m.writeArchive(name, manifest, contents, dirFiles)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	tx.Rollback()
+
+	if err := m.prune(); err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{Name: name, CreatedAt: manifest.CreatedAt, Size: size, Files: manifest.Files}, nil
+}
+
+// listDir checksums every file below dir. A directory that does not exist
+// yet has no files.
+func listDir(dir Dir) ([]dirFile, error) {
+	found := []dirFile{}
+
+	err := filepath.WalkDir(dir.Path, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir.Path {
+			return fs.SkipAll
+		}
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir.Path, path)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		hash := sha256.New()
+		size, err := io.Copy(hash, file)
+		if err != nil {
+			return err
+		}
+
+		found = append(found, dirFile{
+			File: File{Name: dir.Name + "/" + filepath.ToSlash(rel), Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))},
+			path: path,
+		})
+		return nil
+	})
+
+	return found, err
+}
+
+// writeArchive writes the archive to a temporary file first, so an archive
+// with the final name is always complete. The manifest comes first, so it
+// can be read without unpacking the rest.
+func (m *Manager) writeArchive(name string, manifest Manifest, contents [][]byte, dirFiles []dirFile) (int64, error) {
+	tmp, err := os.CreateTemp(m.dir, name+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	compressed := gzip.NewWriter(tmp)
+	writer := tar.NewWriter(compressed)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return 0, err
+	}
+
+	write := func(name string, size int64, content io.Reader) error {
+		header := &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: manifest.CreatedAt}
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+
+		_, err := io.Copy(writer, content)
+		return err
+	}
+
+	if err := write(manifestName, int64(len(manifestData)), bytes.NewReader(manifestData)); err != nil {
+		return 0, err
+	}
+
+	for idx, data := range contents {
+		if err := write(manifest.Files[idx].Name, int64(len(data)), bytes.NewReader(data)); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, file := range dirFiles {
+		content, err := os.Open(file.path)
+		if err != nil {
+			return 0, err
+		}
+
+		err = write(file.Name, file.Size, content)
+		content.Close()
+		if err != nil {
+			return 0, fmt.Errorf("archiving %s: %w", file.Name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := compressed.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return 0, err
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	return info.Size(), os.Rename(tmp.Name(), filepath.Join(m.dir, name))
+}
+
+func (m *Manager) archives() ([]string, error) {
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), archivePrefix) && strings.HasSuffix(entry.Name(), archiveSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	// The timestamp layout sorts lexically, newest last.
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *Manager) prune() error {
+	names, err := m.archives()
+	if err != nil {
+		return err
+	}
+
+	for len(names) > m.retain {
+		if err := os.Remove(filepath.Join(m.dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+
+	return nil
+}
+
+// List returns the snapshots newest first. Only the manifest at the start of
+// each archive is read.
+func (m *Manager) List() ([]Snapshot, error) {
+	names, err := m.archives()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for idx := len(names) - 1; idx >= 0; idx-- {
+		snapshot, err := m.readManifest(names[idx])
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// archiveReader reads the entries of an archive in order.
+type archiveReader struct {
+	file       *os.File
+	compressed *gzip.Reader
+	*tar.Reader
+}
+
+func (m *Manager) openArchive(name string) (*archiveReader, error) {
+	file, err := os.Open(filepath.Join(m.dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("backup %s: %w", name, err)
+	}
+
+	return &archiveReader{file: file, compressed: compressed, Reader: tar.NewReader(compressed)}, nil
+}
+
+func (archive *archiveReader) Close() error {
+	return errors.Join(archive.compressed.Close(), archive.file.Close())
+}
+
+// manifest reads the first entry of the archive, which must be the manifest.
+func (archive *archiveReader) manifest(name string) (Manifest, error) {
+	header, err := archive.Next()
+	if err != nil {
+		return Manifest{}, fmt.Errorf("backup %s: %w", name, err)
+	}
+
+	var manifest Manifest
+	if header.Name != manifestName {
+		return Manifest{}, fmt.Errorf("backup %s has no valid manifest: first entry is %s", name, header.Name)
+	}
+
+	if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+		return Manifest{}, fmt.Errorf("backup %s has no valid manifest: %w", name, err)
+	}
+
+	return manifest, nil
+}
+
+func (m *Manager) readManifest(name string) (Snapshot, error) {
+	archive, err := m.openArchive(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer archive.Close()
+
+	manifest, err := archive.manifest(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	info, err := archive.file.Stat()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{Name: name, CreatedAt: manifest.CreatedAt, Size: info.Size(), Files: manifest.Files}, nil
+}
+
+// verify reads the whole archive and checks every file against the manifest.
+func (m *Manager) verify(name string) (Snapshot, error) {
+	archive, err := m.openArchive(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer archive.Close()
+
+	manifest, err := archive.manifest(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	sums := map[string]string{}
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("backup %s: %w", name, err)
+		}
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, archive); err != nil {
+			return Snapshot{}, fmt.Errorf("backup %s: %w", name, err)
+		}
+		sums[header.Name] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	for _, file := range manifest.Files {
+		sum, ok := sums[file.Name]
+		if !ok {
+			return Snapshot{}, fmt.Errorf("backup %s is missing %s", name, file.Name)
+		}
+
+		if sum != file.SHA256 {
+			return Snapshot{}, fmt.Errorf("backup %s: checksum mismatch for %s", name, file.Name)
+		}
+	}
+
+	info, err := archive.file.Stat()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{Name: name, CreatedAt: manifest.CreatedAt, Size: info.Size(), Files: manifest.Files}, nil
+}
+
+// ParseTime accepts an RFC 3339 time, a backup timestamp or a whole backup
+// file name.
+func ParseTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return at, nil
+	}
+
+	value = strings.TrimSuffix(strings.TrimPrefix(value, archivePrefix), archiveSuffix)
+	at, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or a backup timestamp", value)
+	}
+
+	return at, nil
+}
+
+// Restore replaces the storage files with the newest snapshot taken at or
+// before at. The current files are snapshotted first, and the restored files
+// are written in one transaction.
+func (m *Manager) Restore(at time.Time) (Snapshot, error) {
+	names, err := m.archives()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	name := ""
+	for idx := len(names) - 1; idx >= 0; idx-- {
+		createdAt, err := ParseTime(names[idx])
+		if err != nil {
+			continue
+		}
+
+		if !createdAt.After(at) {
+			name = names[idx]
+			break
+		}
+	}
+
+	if name == "" {
+		return Snapshot{}, fmt.Errorf("no backup taken at or before %s", at.Format(time.RFC3339))
+	}
+
+	target, err := m.verify(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if _, err := m.Snapshot(); err != nil {
+		return Snapshot{}, fmt.Errorf("snapshotting current data before restore: %w", err)
+	}
+
+	contents, err := m.extract(name)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	tx, err := m.journal.Begin()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer tx.Rollback()
+
+	// A storage file added after the backup was taken starts out empty.
+	for _, file := range m.files {
+		data, ok := contents[filepath.Base(file.Name)]
+		if !ok {
+			if data, err = file.Empty(); err != nil {
+				return Snapshot{}, err
+			}
+		}
+
+		if err := tx.WriteFile(file.Name, data); err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	return target, tx.Commit()
+}
+
+// extract writes the directory files of an archive back into their
+// directories and returns the storage files, which are restored in one
+// transaction afterwards. Files in the directories that the backup does not
+// know are left alone.
+func (m *Manager) extract(name string) (map[string][]byte, error) {
+	archive, err := m.openArchive(name)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	manifest, err := archive.manifest(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := map[string]string{}
+	for _, dir := range m.dirs {
+		if slices.Contains(manifest.Dirs, dir.Name) {
+			dirs[dir.Name] = dir.Path
+		}
+	}
+
+	contents := map[string][]byte{}
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return contents, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("backup %s: %w", name, err)
+		}
+
+		dirName, rel, inDir := strings.Cut(header.Name, "/")
+		if !inDir {
+			data, err := io.ReadAll(archive)
+			if err != nil {
+				return nil, fmt.Errorf("backup %s: %w", name, err)
+			}
+			contents[header.Name] = data
+			continue
+		}
+
+		path, ok := dirs[dirName]
+		if !ok {
+			continue
+		}
+
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, fmt.Errorf("backup %s: invalid path %s", name, header.Name)
+		}
+
+		if err := restoreFile(filepath.Join(path, filepath.FromSlash(rel)), archive); err != nil {
+			return nil, fmt.Errorf("restoring %s: %w", header.Name, err)
+		}
+	}
+}
+
+func restoreFile(path string, content io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// StartScheduler takes a backup every interval until ctx is done. An interval
+// of zero disables scheduled backups.
+func (m *Manager) StartScheduler(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	if interval <= 0 {
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		logger := logging.FromContext(ctx)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				snapshot, err := m.Snapshot()
+				if err != nil {
+					logger.Error("scheduled backup failed", "error", err)
+					continue
+				}
+
+				logger.Info("backup written", "backup", snapshot.Name, "size", snapshot.Size)
+			}
+		}
+	}()
+
+	return done
+}
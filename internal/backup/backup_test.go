@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+type record struct {
+	Name string
+}
+
+type fixture struct {
+	dir     string
+	records *storage.Storage[[]record]
+	notes   *storage.Storage[map[string]string]
+	photos  Dir
+	journal *storage.Journal
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+
+	dir := t.TempDir()
+	f := fixture{
+		dir:     dir,
+		records: storage.NewStorage[[]record](filepath.Join(dir, "records.json")),
+		notes:   storage.NewStorage[map[string]string](filepath.Join(dir, "notes.json")),
+		photos:  Dir{Name: "photos", Path: filepath.Join(dir, "photos")},
+		journal: storage.NewJournal(filepath.Join(dir, "journal.json")),
+	}
+
+	if err := f.records.Save([]record{{Name: "first"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func (f fixture) manager(files ...storage.File) *Manager {
+	return NewManager(filepath.Join(f.dir, "backups"), files, []Dir{f.photos}, 5, f.journal)
+}
+
+func writePhoto(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotIncludesDirectories(t *testing.T) {
+	f := newFixture(t)
+	photo := filepath.Join(f.photos.Path, "12", "front.jpg")
+	writePhoto(t, photo, "before")
+
+	m := f.manager(storage.NewFile(f.records, []record{}))
+	snapshot, err := m.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	for _, file := range snapshot.Files {
+		names[file.Name] = true
+	}
+	if !names["records.json"] || !names["photos/12/front.jpg"] {
+		t.Fatalf("snapshot files = %v, want records.json and photos/12/front.jpg", snapshot.Files)
+	}
+
+	writePhoto(t, photo, "after")
+	if err := f.records.Save([]record{{Name: "second"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Restore(snapshot.CreatedAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(photo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "before" {
+		t.Fatalf("restored photo = %q, want %q", content, "before")
+	}
+
+	records := []record{}
+	if err := f.records.Load(&records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Name != "first" {
+		t.Fatalf("restored records = %v, want [first]", records)
+	}
+}
+
+func TestRestoreOlderManifestStartsNewFilesEmpty(t *testing.T) {
+	f := newFixture(t)
+
+	old := f.manager(storage.NewFile(f.records, []record{}))
+	snapshot, err := old.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.notes.Save(map[string]string{"key": "value"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := f.manager(storage.NewFile(f.records, []record{}), storage.NewFile(f.notes, map[string]string{}))
+	if _, err := m.Restore(snapshot.CreatedAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	notes := map[string]string{}
+	if err := f.notes.Load(&notes); err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 0 {
+		t.Fatalf("notes = %v, want empty", notes)
+	}
+}
+
+func TestListReadsOnlyTheManifest(t *testing.T) {
+	f := newFixture(t)
+	m := f.manager(storage.NewFile(f.records, []record{}))
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := archivePrefix + createdAt.Format(timeLayout) + archiveSuffix
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The archive is cut off after the manifest, so only List can succeed.
+	file, err := os.Create(filepath.Join(m.dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := gzip.NewWriter(file)
+	writer := tar.NewWriter(compressed)
+
+	manifest, err := json.Marshal(Manifest{CreatedAt: createdAt, Files: []File{{Name: "records.json", Size: 1 << 20}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write(manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteHeader(&tar.Header{Name: "records.json", Mode: 0644, Size: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || !snapshots[0].CreatedAt.Equal(createdAt) || len(snapshots[0].Files) != 1 {
+		t.Fatalf("List() = %v, want the one manifest", snapshots)
+	}
+
+	if _, err := m.verify(name); err == nil {
+		t.Fatal("verify succeeded on a truncated archive")
+	}
+}
@@ -0,0 +1,144 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrTooLarge    = errors.New("invalid input: file is too large")
+	ErrContentType = errors.New("invalid input: file type is not allowed")
+)
+
+// Blob describes stored content. Digest is the hex SHA-256 of the content and
+// doubles as its address, so identical uploads are stored once.
+type Blob struct {
+	Digest      string
+	Size        int64
+	ContentType string
+}
+
+// Store keeps blobs as files under dir, sharded by the first two characters
+// of their digest. Content types are sniffed from the content itself, the
+// type claimed by the uploader is never trusted.
+type Store struct {
+	dir          string
+	maxSize      int64
+	contentTypes []string
+}
+
+func NewStore(dir string, maxSize int64, contentTypes []string) *Store {
+	return &Store{dir: dir, maxSize: maxSize, contentTypes: contentTypes}
+}
+
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *Store) Put(content io.Reader) (Blob, error) {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(content, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return Blob{}, err
+	}
+	header = header[:n]
+
+	if n == 0 {
+		return Blob{}, errors.New("invalid input: file may not be empty")
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(header))
+	if err != nil {
+		return Blob{}, err
+	}
+
+	if !slices.Contains(s.contentTypes, contentType) {
+		return Blob{}, fmt.Errorf("%w: got %s, expected %s", ErrContentType, contentType, strings.Join(s.contentTypes, ", "))
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return Blob{}, err
+	}
+
+	file, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(io.MultiReader(bytes.NewReader(header), content), s.maxSize+1))
+	if err != nil {
+		return Blob{}, err
+	}
+
+	if size > s.maxSize {
+		return Blob{}, fmt.Errorf("%w: the limit is %d bytes", ErrTooLarge, s.maxSize)
+	}
+
+	if err := file.Sync(); err != nil {
+		return Blob{}, err
+	}
+
+	if err := file.Close(); err != nil {
+		return Blob{}, err
+	}
+
+	blob := Blob{Digest: hex.EncodeToString(hash.Sum(nil)), Size: size, ContentType: contentType}
+	path := s.path(blob.Digest)
+
+	if _, err := os.Stat(path); err == nil {
+		return blob, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Blob{}, err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return Blob{}, err
+	}
+
+	return blob, nil
+}
+
+func (s *Store) Open(digest string) (*os.File, error) {
+	if !validDigest(digest) {
+		return nil, fmt.Errorf("invalid blob digest %q", digest)
+	}
+
+	return os.Open(s.path(digest))
+}
+
+// Delete removes a blob. Deleting a blob that does not exist is not an error.
+func (s *Store) Delete(digest string) error {
+	if !validDigest(digest) {
+		return fmt.Errorf("invalid blob digest %q", digest)
+	}
+
+	if err := os.Remove(s.path(digest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) path(digest string) string {
+	return filepath.Join(s.dir, digest[:2], digest)
+}
+
+func validDigest(digest string) bool {
+	decoded, err := hex.DecodeString(digest)
+	return err == nil && len(decoded) == sha256.Size && digest == strings.ToLower(digest)
+}
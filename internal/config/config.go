@@ -0,0 +1,782 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+	"github.com/ZulfiPy/RWAPIGo/internal/notify"
+	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+)
+
+const envPrefix = "RWAPI_"
+
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"90s\" or \"2h\": %w", err)
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = duration
+	return nil
+}
+
+// Clients are rate limited by IP unless a group keys on api_key or user. An
+// X-API-Key only counts when it is listed in Server.APIKeys, and an
+// X-Employee-ID only when it names an employee, combined with the client IP
+// so nobody can use up another employee's budget. Other requests fall back
+// to their IP.
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByAPIKey = "api_key"
+	RateLimitByUser   = "user"
+)
+
+const DefaultRateLimitGroup = "default"
+
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+	Key               string
+}
+
+type CORS struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           Duration
+}
+
+// Server configures the HTTP server. Multipart uploads and CSV imports are
+// limited by MaxUploadBytes instead of MaxBodyBytes, and document uploads
+// further by Documents.MaxSize.
+type Server struct {
+	ReadTimeout       Duration
+	ReadHeaderTimeout Duration
+	WriteTimeout      Duration
+	IdleTimeout       Duration
+	ShutdownTimeout   Duration
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64
+	MaxUploadBytes    int64
+	TLSCertFile       string
+	TLSKeyFile        string
+	RateLimits        map[string]RateLimit
+	APIKeys           []string
+	CORS              CORS
+}
+
+type Backup struct {
+	Dir      string
+	Interval Duration
+	Retain   int
+}
+
+type Webhooks struct {
+	MaxAttempts         int
+	InitialBackoff      Duration
+	MaxBackoff          Duration
+	Timeout             Duration
+	PollInterval        Duration
+	DeliveryRetention   int
+	AllowPrivateTargets bool
+}
+
+type RentalPricing struct {
+	IncludedKmPerDay    int
+	OverageFeePerKm     float64
+	RefuelFeePerPercent float64
+	OneWayFee           float64
+	LateFeePerDay       float64
+}
+
+// RiskPolicy lists the employees allowed to override risk flags.
+type RiskPolicy struct {
+	OverrideManagers []int64
+}
+
+// VehicleCatalogue lists the values vehicles may take. Values are matched
+// in title case.
+type VehicleCatalogue struct {
+	FuelTypes []string
+	Gearboxes []string
+	Colors    []string
+	Bodies    []string
+	MinYear   int
+}
+
+type Overdue struct {
+	Interval     Duration
+	ReminderLead Duration
+}
+
+// Documents configures the blob store for customer document scans. Content
+// types are matched against the sniffed type of each upload.
+type Documents struct {
+	Dir          string
+	MaxSize      int64
+	ContentTypes []string
+}
+
+type Email struct {
+	Sender         string
+	From           string
+	SMTPAddr       string
+	SMTPUsername   string
+	SMTPPassword   string
+	Dir            string
+	TemplateDir    string
+	MaxAttempts    int
+	InitialBackoff Duration
+	MaxBackoff     Duration
+	PollInterval   Duration
+	Retention      int
+}
+
+type Config struct {
+	ListenAddr                string
+	LogLevel                  string
+	Server                    Server
+	DataDir                   string
+	DamagePhotoDir            string
+	Documents                 Documents
+	ReservationGracePeriod    Duration
+	ReservationExpiryInterval Duration
+	DepositAmount             float64
+	RentalPricing             RentalPricing
+	RiskPolicy                RiskPolicy
+	Overdue                   Overdue
+	VehicleCatalogue          VehicleCatalogue
+	EventRetention            int
+	Webhooks                  Webhooks
+	Email                     Email
+	Backup                    Backup
+}
+
+func Default() Config {
+	return Config{
+		ListenAddr: ":8080",
+		LogLevel:   "info",
+		Server: Server{
+			ReadTimeout:       Duration{15 * time.Second},
+			ReadHeaderTimeout: Duration{5 * time.Second},
+			WriteTimeout:      Duration{30 * time.Second},
+			IdleTimeout:       Duration{60 * time.Second},
+			ShutdownTimeout:   Duration{20 * time.Second},
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      1 << 20,
+			MaxUploadBytes:    32 << 20,
+			RateLimits: map[string]RateLimit{
+				DefaultRateLimitGroup: {RequestsPerSecond: 10, Burst: 20, Key: RateLimitByIP},
+			},
+			CORS: CORS{
+				AllowedOrigins: []string{},
+				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+				AllowedHeaders: []string{"Content-Type", "X-Employee-ID", "X-Manager-Override", "X-Request-ID", "X-API-Key"},
+				ExposedHeaders: []string{"X-Request-ID", "Retry-After"},
+				MaxAge:         Duration{10 * time.Minute},
+			},
+		},
+		DataDir:        ".",
+		DamagePhotoDir: "damage_photos",
+		Documents: Documents{
+			Dir:          "documents",
+			MaxSize:      10 << 20,
+			ContentTypes: []string{"image/jpeg", "image/png", "application/pdf"},
+		},
+		ReservationGracePeriod:    Duration{2 * time.Hour},
+		ReservationExpiryInterval: Duration{time.Minute},
+		DepositAmount:             300,
+		RentalPricing: RentalPricing{
+			IncludedKmPerDay:    200,
+			OverageFeePerKm:     0.25,
+			RefuelFeePerPercent: 1.5,
+			OneWayFee:           50,
+			LateFeePerDay:       40,
+		},
+		Overdue: Overdue{
+			Interval:     Duration{5 * time.Minute},
+			ReminderLead: Duration{24 * time.Hour},
+		},
+		RiskPolicy: RiskPolicy{OverrideManagers: []int64{}},
+		VehicleCatalogue: VehicleCatalogue{
+			FuelTypes: []string{"Petrol", "Diesel", "Hybrid", "Electric", "Lpg", "Cng"},
+			Gearboxes: []string{"Automatic", "Manual"},
+			Colors:    []string{"White", "Black", "Red", "Blue", "Green", "Yellow", "Gray", "Silver", "Brown"},
+			Bodies:    []string{"Sedan", "Touring", "Hatchback", "Minivan", "Coupe", "Cabriolet", "Pickup", "Limousine"},
+			MinYear:   2010,
+		},
+		EventRetention: 10000,
+		Webhooks: Webhooks{
+			MaxAttempts:       8,
+			InitialBackoff:    Duration{10 * time.Second},
+			MaxBackoff:        Duration{time.Hour},
+			Timeout:           Duration{10 * time.Second},
+			PollInterval:      Duration{5 * time.Second},
+			DeliveryRetention: 10000,
+		},
+		Email: Email{
+			Sender:         notify.SenderLog,
+			From:           "RWAPIGo <no-reply@localhost>",
+			Dir:            "emails",
+			MaxAttempts:    5,
+			InitialBackoff: Duration{30 * time.Second},
+			MaxBackoff:     Duration{time.Hour},
+			PollInterval:   Duration{10 * time.Second},
+			Retention:      10000,
+		},
+		Backup: Backup{
+			Dir:      "backups",
+			Interval: Duration{24 * time.Hour},
+			Retain:   7,
+		},
+	}
+}
+
+func Load(args []string) (Config, error) {
+	return LoadFlags(flag.NewFlagSet("rwapigolang", flag.ContinueOnError), args)
+}
+
+// LoadFlags is Load with a caller supplied flag set, so subcommands can
+// register their own flags next to the shared ones.
+func LoadFlags(flags *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+
+	configFile := flags.String("config", os.Getenv(envPrefix+"CONFIG"), "path to a JSON configuration file")
+	listenAddr := flags.String("listen", "", "address the HTTP server listens on")
+	logLevel := flags.String("log-level", "", "minimum log level: debug, info, warn or error")
+	dataDir := flags.String("data-dir", "", "directory holding the JSON storage files")
+	photoDir := flags.String("damage-photo-dir", "", "directory damage photos are stored in")
+	documentDir := flags.String("document-dir", "", "directory customer documents are stored in")
+	tlsCert := flags.String("tls-cert", "", "path to the TLS certificate, enables HTTPS together with -tls-key")
+	tlsKey := flags.String("tls-key", "", "path to the TLS private key")
+	backupDir := flags.String("backup-dir", "", "directory backups are written to")
+	emailSender := flags.String("email-sender", "", "how emails are sent: smtp, file or log")
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if *listenAddr != "" {
+		cfg.ListenAddr = *listenAddr
+	}
+
+	if *logLevel != "" {
+		cfg.LogLevel = *logLevel
+	}
+
+	if *dataDir != "" {
+		cfg.DataDir = *dataDir
+	}
+
+	if *photoDir != "" {
+		cfg.DamagePhotoDir = *photoDir
+	}
+
+	if *documentDir != "" {
+		cfg.Documents.Dir = *documentDir
+	}
+
+	if *tlsCert != "" {
+		cfg.Server.TLSCertFile = *tlsCert
+	}
+
+	if *tlsKey != "" {
+		cfg.Server.TLSKeyFile = *tlsKey
+	}
+
+	if *backupDir != "" {
+		cfg.Backup.Dir = *backupDir
+	}
+
+	if *emailSender != "" {
+		cfg.Email.Sender = *emailSender
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	if value, ok := os.LookupEnv(envPrefix + "LISTEN_ADDR"); ok {
+		cfg.ListenAddr = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "LOG_LEVEL"); ok {
+		cfg.LogLevel = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "DATA_DIR"); ok {
+		cfg.DataDir = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "DAMAGE_PHOTO_DIR"); ok {
+		cfg.DamagePhotoDir = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "DOCUMENT_DIR"); ok {
+		cfg.Documents.Dir = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "DOCUMENT_MAX_SIZE"); ok {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%sDOCUMENT_MAX_SIZE: %w", envPrefix, err)
+		}
+		cfg.Documents.MaxSize = size
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "TLS_CERT_FILE"); ok {
+		cfg.Server.TLSCertFile = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "TLS_KEY_FILE"); ok {
+		cfg.Server.TLSKeyFile = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "MAX_BODY_BYTES"); ok {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%sMAX_BODY_BYTES: %w", envPrefix, err)
+		}
+		cfg.Server.MaxBodyBytes = size
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "MAX_UPLOAD_BYTES"); ok {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%sMAX_UPLOAD_BYTES: %w", envPrefix, err)
+		}
+		cfg.Server.MaxUploadBytes = size
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "API_KEYS"); ok {
+		cfg.Server.APIKeys = splitList(value)
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "CORS_ALLOWED_ORIGINS"); ok {
+		cfg.Server.CORS.AllowedOrigins = splitList(value)
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "RESERVATION_GRACE_PERIOD"); ok {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%sRESERVATION_GRACE_PERIOD: %w", envPrefix, err)
+		}
+		cfg.ReservationGracePeriod = Duration{duration}
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "RISK_OVERRIDE_MANAGERS"); ok {
+		managers := []int64{}
+		for _, item := range splitList(value) {
+			managerID, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%sRISK_OVERRIDE_MANAGERS: %w", envPrefix, err)
+			}
+			managers = append(managers, managerID)
+		}
+		cfg.RiskPolicy.OverrideManagers = managers
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "OVERDUE_INTERVAL"); ok {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%sOVERDUE_INTERVAL: %w", envPrefix, err)
+		}
+		cfg.Overdue.Interval = Duration{duration}
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "DEPOSIT_AMOUNT"); ok {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%sDEPOSIT_AMOUNT: %w", envPrefix, err)
+		}
+		cfg.DepositAmount = amount
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "VEHICLE_MIN_YEAR"); ok {
+		year, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%sVEHICLE_MIN_YEAR: %w", envPrefix, err)
+		}
+		cfg.VehicleCatalogue.MinYear = year
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "EVENT_RETENTION"); ok {
+		retain, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%sEVENT_RETENTION: %w", envPrefix, err)
+		}
+		cfg.EventRetention = retain
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "BACKUP_DIR"); ok {
+		cfg.Backup.Dir = value
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "BACKUP_INTERVAL"); ok {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%sBACKUP_INTERVAL: %w", envPrefix, err)
+		}
+		cfg.Backup.Interval = Duration{duration}
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "BACKUP_RETAIN"); ok {
+		retain, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%sBACKUP_RETAIN: %w", envPrefix, err)
+		}
+		cfg.Backup.Retain = retain
+	}
+
+	values := map[string]*string{
+		"EMAIL_SENDER":       &cfg.Email.Sender,
+		"EMAIL_FROM":         &cfg.Email.From,
+		"EMAIL_DIR":          &cfg.Email.Dir,
+		"EMAIL_TEMPLATE_DIR": &cfg.Email.TemplateDir,
+		"SMTP_ADDR":          &cfg.Email.SMTPAddr,
+		"SMTP_USERNAME":      &cfg.Email.SMTPUsername,
+		"SMTP_PASSWORD":      &cfg.Email.SMTPPassword,
+	}
+
+	for name, value := range values {
+		if env, ok := os.LookupEnv(envPrefix + name); ok {
+			*value = env
+		}
+	}
+
+	lists := map[string]*[]string{
+		"VEHICLE_FUEL_TYPES":     &cfg.VehicleCatalogue.FuelTypes,
+		"VEHICLE_GEARBOXES":      &cfg.VehicleCatalogue.Gearboxes,
+		"VEHICLE_COLORS":         &cfg.VehicleCatalogue.Colors,
+		"VEHICLE_BODIES":         &cfg.VehicleCatalogue.Bodies,
+		"DOCUMENT_CONTENT_TYPES": &cfg.Documents.ContentTypes,
+	}
+
+	for name, list := range lists {
+		if value, ok := os.LookupEnv(envPrefix + name); ok {
+			*list = splitList(value)
+		}
+	}
+
+	return nil
+}
+
+func splitList(value string) []string {
+	values := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.ListenAddr == "" {
+		return errors.New("config: listen address may not be empty")
+	}
+
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if cfg.DataDir == "" {
+		return errors.New("config: data directory may not be empty")
+	}
+
+	server := cfg.Server
+	if server.ReadTimeout.Duration <= 0 || server.ReadHeaderTimeout.Duration <= 0 || server.WriteTimeout.Duration <= 0 || server.IdleTimeout.Duration <= 0 || server.ShutdownTimeout.Duration <= 0 {
+		return errors.New("config: server timeouts must be positive")
+	}
+
+	if server.MaxHeaderBytes <= 0 {
+		return errors.New("config: server max header bytes must be positive")
+	}
+
+	if server.MaxBodyBytes <= 0 {
+		return errors.New("config: server max body bytes must be positive")
+	}
+
+	if server.MaxUploadBytes <= 0 {
+		return errors.New("config: server max upload bytes must be positive")
+	}
+
+	for group, limit := range server.RateLimits {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("config: rate limit %q: %w", group, err)
+		}
+
+		if limit.Key == RateLimitByAPIKey && len(server.APIKeys) == 0 {
+			return fmt.Errorf("config: rate limit %q keys on %s but no API keys are configured", group, RateLimitByAPIKey)
+		}
+	}
+
+	if err := server.CORS.validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if (server.TLSCertFile == "") != (server.TLSKeyFile == "") {
+		return errors.New("config: TLS requires both a certificate and a key file")
+	}
+
+	if cfg.DamagePhotoDir == "" {
+		return errors.New("config: damage photo directory may not be empty")
+	}
+
+	if err := cfg.Documents.validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if cfg.Documents.MaxSize >= server.MaxUploadBytes {
+		return errors.New("config: document max size must be below the server max upload bytes")
+	}
+
+	if cfg.ReservationGracePeriod.Duration < 0 {
+		return errors.New("config: reservation grace period may not be negative")
+	}
+
+	if cfg.ReservationExpiryInterval.Duration <= 0 {
+		return errors.New("config: reservation expiry interval must be positive")
+	}
+
+	if cfg.DepositAmount < 0 {
+		return errors.New("config: deposit amount may not be negative")
+	}
+
+	pricing := cfg.RentalPricing
+	if pricing.IncludedKmPerDay < 0 || pricing.OverageFeePerKm < 0 || pricing.RefuelFeePerPercent < 0 || pricing.OneWayFee < 0 || pricing.LateFeePerDay < 0 {
+		return errors.New("config: rental pricing values may not be negative")
+	}
+
+	for _, managerID := range cfg.RiskPolicy.OverrideManagers {
+		if utils.IntLength(managerID) != 11 {
+			return fmt.Errorf("config: risk override manager %d must be an 11 digit personal ID", managerID)
+		}
+	}
+
+	if cfg.Overdue.Interval.Duration <= 0 {
+		return errors.New("config: overdue check interval must be positive")
+	}
+
+	if cfg.Overdue.ReminderLead.Duration < 0 {
+		return errors.New("config: due-back reminder lead time may not be negative, use 0 to disable reminders")
+	}
+
+	if err := cfg.VehicleCatalogue.validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if cfg.EventRetention < 1 {
+		return errors.New("config: event retention must keep at least 1 event")
+	}
+
+	webhooks := cfg.Webhooks
+	if webhooks.MaxAttempts < 1 {
+		return errors.New("config: webhooks need at least 1 delivery attempt")
+	}
+
+	if webhooks.InitialBackoff.Duration <= 0 || webhooks.MaxBackoff.Duration < webhooks.InitialBackoff.Duration {
+		return errors.New("config: webhook backoff must be positive, with the maximum no lower than the initial backoff")
+	}
+
+	if webhooks.Timeout.Duration <= 0 || webhooks.PollInterval.Duration <= 0 {
+		return errors.New("config: webhook timeout and poll interval must be positive")
+	}
+
+	if webhooks.DeliveryRetention < 1 {
+		return errors.New("config: webhook delivery retention must keep at least 1 delivery")
+	}
+
+	if err := cfg.Email.validate(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	if cfg.Backup.Dir == "" {
+		return errors.New("config: backup directory may not be empty")
+	}
+
+	if cfg.Backup.Interval.Duration < 0 {
+		return errors.New("config: backup interval may not be negative, use 0 to disable scheduled backups")
+	}
+
+	if cfg.Backup.Retain < 1 {
+		return errors.New("config: backup retention must keep at least 1 backup")
+	}
+
+	return nil
+}
+
+func (rl RateLimit) validate() error {
+	if rl.RequestsPerSecond < 0 {
+		return errors.New("requests per second may not be negative")
+	}
+
+	if rl.RequestsPerSecond > 0 && rl.Burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+
+	switch rl.Key {
+	case "", RateLimitByIP, RateLimitByAPIKey, RateLimitByUser:
+		return nil
+	default:
+		return fmt.Errorf("unknown client key %q, expected %s, %s or %s", rl.Key, RateLimitByIP, RateLimitByAPIKey, RateLimitByUser)
+	}
+}
+
+func (c VehicleCatalogue) validate() error {
+	lists := map[string][]string{
+		"fuel types": c.FuelTypes,
+		"gearboxes":  c.Gearboxes,
+		"colors":     c.Colors,
+		"bodies":     c.Bodies,
+	}
+
+	for name, values := range lists {
+		if len(values) == 0 {
+			return fmt.Errorf("vehicle catalogue %s may not be empty", name)
+		}
+
+		if slices.Contains(values, "") {
+			return fmt.Errorf("vehicle catalogue %s may not contain empty values", name)
+		}
+	}
+
+	if c.MinYear < 1900 || c.MinYear > time.Now().Year() {
+		return fmt.Errorf("vehicle catalogue minimum year %d must be between 1900 and the current year", c.MinYear)
+	}
+
+	return nil
+}
+
+func (c CORS) validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" && c.AllowCredentials {
+			return errors.New("CORS credentials cannot be allowed for the wildcard origin")
+		}
+	}
+
+	if c.MaxAge.Duration < 0 {
+		return errors.New("CORS max age may not be negative")
+	}
+
+	return nil
+}
+
+func (d Documents) validate() error {
+	if d.Dir == "" {
+		return errors.New("document directory may not be empty")
+	}
+
+	if d.MaxSize <= 0 {
+		return errors.New("document max size must be positive")
+	}
+
+	if len(d.ContentTypes) == 0 {
+		return errors.New("documents need at least 1 allowed content type")
+	}
+
+	return nil
+}
+
+func (e Email) validate() error {
+	switch e.Sender {
+	case notify.SenderSMTP:
+		if e.SMTPAddr == "" {
+			return errors.New("email sender smtp requires an SMTP address")
+		}
+	case notify.SenderFile:
+		if e.Dir == "" {
+			return errors.New("email sender file requires an email directory")
+		}
+	case notify.SenderLog:
+	default:
+		return fmt.Errorf("unknown email sender %q, expected %s, %s or %s", e.Sender, notify.SenderSMTP, notify.SenderFile, notify.SenderLog)
+	}
+
+	if _, err := mail.ParseAddress(e.From); err != nil {
+		return fmt.Errorf("email from address: %w", err)
+	}
+
+	if e.MaxAttempts < 1 {
+		return errors.New("emails need at least 1 send attempt")
+	}
+
+	if e.InitialBackoff.Duration <= 0 || e.MaxBackoff.Duration < e.InitialBackoff.Duration {
+		return errors.New("email backoff must be positive, with the maximum no lower than the initial backoff")
+	}
+
+	if e.PollInterval.Duration <= 0 {
+		return errors.New("email poll interval must be positive")
+	}
+
+	if e.Retention < 1 {
+		return errors.New("email retention must keep at least 1 email")
+	}
+
+	return nil
+}
+
+func (s Server) TLSEnabled() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
+func (cfg *Config) DataFile(name string) string {
+	return filepath.Join(cfg.DataDir, name)
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadFlagsPrecedence(t *testing.T) {
+	file := writeConfigFile(t, `{"ListenAddr": ":9000", "LogLevel": "warn", "DataDir": "from-file", "DepositAmount": 150}`)
+
+	tests := []struct {
+		name           string
+		env            map[string]string
+		args           []string
+		wantListenAddr string
+		wantLogLevel   string
+		wantDataDir    string
+		wantDeposit    float64
+	}{
+		{
+			name:           "file over defaults",
+			wantListenAddr: ":9000",
+			wantLogLevel:   "warn",
+			wantDataDir:    "from-file",
+			wantDeposit:    150,
+		},
+		{
+			name:           "env over file",
+			env:            map[string]string{"RWAPI_LISTEN_ADDR": ":9100", "RWAPI_DATA_DIR": "from-env", "RWAPI_DEPOSIT_AMOUNT": "200"},
+			wantListenAddr: ":9100",
+			wantLogLevel:   "warn",
+			wantDataDir:    "from-env",
+			wantDeposit:    200,
+		},
+		{
+			name:           "flags over env",
+			env:            map[string]string{"RWAPI_LISTEN_ADDR": ":9100", "RWAPI_DATA_DIR": "from-env"},
+			args:           []string{"-listen", ":9200", "-log-level", "debug"},
+			wantListenAddr: ":9200",
+			wantLogLevel:   "debug",
+			wantDataDir:    "from-env",
+			wantDeposit:    150,
+		},
+		{
+			name:           "config file from env",
+			env:            map[string]string{"RWAPI_CONFIG": file},
+			args:           []string{},
+			wantListenAddr: ":9000",
+			wantLogLevel:   "warn",
+			wantDataDir:    "from-file",
+			wantDeposit:    150,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			args := test.args
+			if _, ok := test.env["RWAPI_CONFIG"]; !ok {
+				args = append([]string{"-config", file}, args...)
+			}
+
+			cfg, err := LoadFlags(flag.NewFlagSet("test", flag.ContinueOnError), args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.ListenAddr != test.wantListenAddr || cfg.LogLevel != test.wantLogLevel || cfg.DataDir != test.wantDataDir || cfg.DepositAmount != test.wantDeposit {
+				t.Errorf("config = listen %q, log level %q, data dir %q, deposit %.2f, want %q, %q, %q, %.2f",
+					cfg.ListenAddr, cfg.LogLevel, cfg.DataDir, cfg.DepositAmount,
+					test.wantListenAddr, test.wantLogLevel, test.wantDataDir, test.wantDeposit)
+			}
+		})
+	}
+}
+
+func TestLoadFlagsRejectsBadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", `{"ListenAddress": ":9000"}`},
+		{"bad duration", `{"ReservationGracePeriod": 7200}`},
+		{"invalid values", `{"ListenAddr": ""}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := []string{"-config", writeConfigFile(t, test.content)}
+			if _, err := LoadFlags(flag.NewFlagSet("test", flag.ContinueOnError), args); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(cfg Config) bool
+		wantErr string
+	}{
+		{
+			name:  "lists are split and trimmed",
+			env:   map[string]string{"RWAPI_API_KEYS": " one, two ,,three"},
+			check: func(cfg Config) bool { return strings.Join(cfg.Server.APIKeys, "|") == "one|two|three" },
+		},
+		{
+			name: "durations and numbers",
+			env:  map[string]string{"RWAPI_RESERVATION_GRACE_PERIOD": "90m", "RWAPI_VEHICLE_MIN_YEAR": "2015", "RWAPI_RISK_OVERRIDE_MANAGERS": "38001010001,38001010002"},
+			check: func(cfg Config) bool {
+				return cfg.ReservationGracePeriod.Duration == 90*time.Minute && cfg.VehicleCatalogue.MinYear == 2015 && len(cfg.RiskPolicy.OverrideManagers) == 2
+			},
+		},
+		{
+			name:  "catalogue lists",
+			env:   map[string]string{"RWAPI_VEHICLE_COLORS": "white,black"},
+			check: func(cfg Config) bool { return strings.Join(cfg.VehicleCatalogue.Colors, "|") == "white|black" },
+		},
+		{name: "bad duration", env: map[string]string{"RWAPI_OVERDUE_INTERVAL": "soon"}, wantErr: "RWAPI_OVERDUE_INTERVAL"},
+		{name: "bad integer", env: map[string]string{"RWAPI_EVENT_RETENTION": "many"}, wantErr: "RWAPI_EVENT_RETENTION"},
+		{name: "bad float", env: map[string]string{"RWAPI_DEPOSIT_AMOUNT": "free"}, wantErr: "RWAPI_DEPOSIT_AMOUNT"},
+		{name: "bad manager", env: map[string]string{"RWAPI_RISK_OVERRIDE_MANAGERS": "38001010001,boss"}, wantErr: "RWAPI_RISK_OVERRIDE_MANAGERS"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			cfg := Default()
+			err := loadEnv(&cfg)
+
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("err = %v, want one naming %s", err, test.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !test.check(cfg) {
+				t.Errorf("config = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"defaults", func(cfg *Config) {}, ""},
+		{"empty listen address", func(cfg *Config) { cfg.ListenAddr = "" }, "listen address"},
+		{"unknown log level", func(cfg *Config) { cfg.LogLevel = "loud" }, "level"},
+		{"non-positive timeout", func(cfg *Config) { cfg.Server.WriteTimeout = Duration{} }, "timeouts"},
+		{"api key rate limit without keys", func(cfg *Config) {
+			cfg.Server.RateLimits["keys"] = RateLimit{RequestsPerSecond: 1, Burst: 1, Key: RateLimitByAPIKey}
+		}, "no API keys"},
+		{"unknown rate limit key", func(cfg *Config) {
+			cfg.Server.RateLimits["odd"] = RateLimit{RequestsPerSecond: 1, Burst: 1, Key: "cookie"}
+		}, "unknown client key"},
+		{"credentials for any origin", func(cfg *Config) {
+			cfg.Server.CORS.AllowedOrigins = []string{"*"}
+			cfg.Server.CORS.AllowCredentials = true
+		}, "wildcard origin"},
+		{"TLS certificate without key", func(cfg *Config) { cfg.Server.TLSCertFile = "cert.pem" }, "TLS"},
+		{"document size above upload limit", func(cfg *Config) { cfg.Documents.MaxSize = cfg.Server.MaxUploadBytes }, "document max size"},
+		{"negative pricing", func(cfg *Config) { cfg.RentalPricing.OneWayFee = -1 }, "rental pricing"},
+		{"short manager ID", func(cfg *Config) { cfg.RiskPolicy.OverrideManagers = []int64{12345} }, "11 digit"},
+		{"empty catalogue list", func(cfg *Config) { cfg.VehicleCatalogue.Bodies = nil }, "bodies"},
+		{"empty catalogue value", func(cfg *Config) { cfg.VehicleCatalogue.Colors = []string{"White", ""} }, "empty values"},
+		{"catalogue year in the future", func(cfg *Config) { cfg.VehicleCatalogue.MinYear = time.Now().Year() + 1 }, "minimum year"},
+		{"smtp without address", func(cfg *Config) { cfg.Email.Sender = "smtp" }, "SMTP address"},
+		{"webhook backoff below initial", func(cfg *Config) { cfg.Webhooks.MaxBackoff = Duration{time.Second} }, "webhook backoff"},
+		{"no backups retained", func(cfg *Config) { cfg.Backup.Retain = 0 }, "backup retention"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Default()
+			test.modify(&cfg)
+
+			err := cfg.Validate()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("err = %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const (
+	CustomerCreated = "CustomerCreated"
+	CustomerUpdated = "CustomerUpdated"
+	CustomerDeleted = "CustomerDeleted"
+	VehicleCreated  = "VehicleCreated"
+	VehicleUpdated  = "VehicleUpdated"
+	VehicleDeleted  = "VehicleDeleted"
+	VehicleAssigned = "VehicleAssigned"
+	VehicleReturned = "VehicleReturned"
+	EmployeeCreated = "EmployeeCreated"
+	EmployeeUpdated = "EmployeeUpdated"
+	EmployeeDeleted = "EmployeeDeleted"
+	RentalDueSoon   = "RentalDueSoon"
+	RentalOverdue   = "RentalOverdue"
+
+	ReservationExpired = "ReservationExpired"
+
+	RiskFlagSet        = "RiskFlagSet"
+	RiskFlagCleared    = "RiskFlagCleared"
+	RiskFlagOverridden = "RiskFlagOverridden"
+)
+
+var Types = []string{
+	CustomerCreated, CustomerUpdated, CustomerDeleted,
+	VehicleCreated, VehicleUpdated, VehicleDeleted, VehicleAssigned, VehicleReturned,
+	EmployeeCreated, EmployeeUpdated, EmployeeDeleted,
+	RentalDueSoon, RentalOverdue,
+	ReservationExpired,
+	RiskFlagSet, RiskFlagCleared, RiskFlagOverridden,
+}
+
+// Event is one entry in the outbox. IDs increase by one per event and double
+// as the cursor clients resume from.
+type Event struct {
+	ID         int64
+	Type       string
+	Subject    string
+	OccurredAt time.Time
+	Data       json.RawMessage
+}
+
+type Events []Event
+
+// Assignment is the payload of VehicleAssigned and VehicleReturned.
+type Assignment struct {
+	PersonalID  int64
+	PlateNumber string
+}
+
+func New(eventType, subject string, data any) Event {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload = []byte("null")
+	}
+
+	return Event{Type: eventType, Subject: subject, OccurredAt: time.Now(), Data: payload}
+}
+
+// notifier wakes everyone waiting on the outbox by closing the current
+// channel and handing out a fresh one.
+type notifier struct {
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+func (n *notifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.changed
+}
+
+func (n *notifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// Outbox is the persistent log of domain events. Model storages append to it
+// in the same transaction as the change that caused the event, so an event is
+// only visible once its change has been committed.
+type Outbox struct {
+	storage  *storage.Storage[Events]
+	journal  *storage.Journal
+	tx       *storage.Tx
+	retain   int
+	notifier *notifier
+}
+
+func NewOutbox(fileName string, retain int, journal *storage.Journal) *Outbox {
+	return &Outbox{
+		storage:  storage.NewStorage[Events](fileName),
+		journal:  journal,
+		retain:   retain,
+		notifier: &notifier{changed: make(chan struct{})},
+	}
+}
+
+func (o *Outbox) GetStorage() *storage.Storage[Events] {
+	return o.storage
+}
+
+func (o *Outbox) WithContext(ctx context.Context) *Outbox {
+	scoped := *o
+	scoped.storage = o.storage.WithContext(ctx)
+	return &scoped
+}
+
+func (o *Outbox) WithTx(tx *storage.Tx) *Outbox {
+	scoped := *o
+	scoped.storage = o.storage.WithTx(tx)
+	scoped.tx = tx
+	return &scoped
+}
+
+// Within runs fn in the outbox's transaction, or in a new one committed when
+// fn returns nil if none is bound, so a change and the events it appends are
+// saved together.
+func (o *Outbox) Within(fn func(tx *storage.Tx) error) error {
+	if o.tx != nil {
+		return fn(o.tx)
+	}
+
+	tx, err := o.journal.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Append assigns IDs to pending and stores them, dropping the oldest events
+// beyond the retention limit. Outside a transaction it opens its own, so IDs
+// stay unique when several writers append at once.
+func (o *Outbox) Append(pending ...Event) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if o.tx == nil {
+		tx, err := o.journal.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := o.WithTx(tx).Append(pending...); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	events := Events{}
+	if err := o.storage.Load(&events); err != nil {
+		return err
+	}
+
+	nextID := int64(1)
+	if len(events) > 0 {
+		nextID = events[len(events)-1].ID + 1
+	}
+
+	for _, event := range pending {
+		event.ID = nextID
+		nextID++
+		events = append(events, event)
+	}
+
+	if len(events) > o.retain {
+		events = events[len(events)-o.retain:]
+	}
+
+	if err := o.storage.Save(events); err != nil {
+		return err
+	}
+
+	o.tx.OnCommit(o.notifier.notify)
+	return nil
+}
+
+// Since returns up to limit events with an ID greater than cursor.
+func (o *Outbox) Since(cursor int64, limit int) (Events, error) {
+	events := Events{}
+	if err := o.storage.Load(&events); err != nil {
+		return nil, err
+	}
+
+	found := Events{}
+	for _, event := range events {
+		if event.ID <= cursor {
+			continue
+		}
+
+		found = append(found, event)
+		if len(found) == limit {
+			break
+		}
+	}
+
+	return found, nil
+}
+
+// Changed returns a channel that is closed the next time events are
+// committed. Take it before calling Since so no event can slip in between.
+func (o *Outbox) Changed() <-chan struct{} {
+	return o.notifier.wait()
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector{}, r.collectors...)
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func formatLabels(names, values []string, extra ...string) string {
+	pairs := []string{}
+	for idx, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, values[idx]))
+	}
+
+	for idx := 0; idx+1 < len(extra); idx += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[idx], extra[idx+1]))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func writeHeader(w io.Writer, name, help, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	return err
+}
+
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+	r.register(counter)
+	return counter
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := labelKey(labelValues)
+	series, ok := c.series[key]
+	if !ok {
+		series = &counterSeries{labels: labelValues}
+		c.series[key] = series
+	}
+
+	series.value += value
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := writeHeader(w, c.name, c.help, "counter"); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(c.series) {
+		series := c.series[key]
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, series.labels), formatFloat(series.value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	histogram := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*histogramSeries{}}
+	r.register(histogram)
+	return histogram
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := labelKey(labelValues)
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{labels: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+
+	for idx, bound := range h.buckets {
+		if value <= bound {
+			series.counts[idx]++
+		}
+	}
+
+	series.sum += value
+	series.count++
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := writeHeader(w, h.name, h.help, "histogram"); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(h.series) {
+		series := h.series[key]
+
+		for idx, bound := range h.buckets {
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, series.labels, "le", formatFloat(bound)), series.counts[idx]); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, series.labels, "le", "+Inf"), series.count); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.name, formatLabels(h.labels, series.labels), formatFloat(series.sum), h.name, formatLabels(h.labels, series.labels), series.count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type GaugeFunc struct {
+	name    string
+	help    string
+	label   string
+	collect func() map[string]float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help, label string, collect func() map[string]float64) *GaugeFunc {
+	gauge := &GaugeFunc{name: name, help: help, label: label, collect: collect}
+	r.register(gauge)
+	return gauge
+}
+
+func (g *GaugeFunc) write(w io.Writer) error {
+	values := g.collect()
+
+	if err := writeHeader(w, g.name, g.help, "gauge"); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels([]string{g.label}, []string{key}), formatFloat(values[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
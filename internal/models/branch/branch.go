@@ -0,0 +1,171 @@
+package branch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+type Branch struct {
+	ID           int
+	Name         string
+	Address      string
+	OpeningHours string
+}
+
+type Branches []Branch
+
+type BranchStorage struct {
+	storage *storage.Storage[Branches]
+}
+
+func NewBranchStorage(fileName string) *BranchStorage {
+	return &BranchStorage{
+		storage: storage.NewStorage[Branches](fileName),
+	}
+}
+
+func (bs *BranchStorage) GetStorage() *storage.Storage[Branches] {
+	return bs.storage
+}
+
+func (bs *BranchStorage) WithContext(ctx context.Context) *BranchStorage {
+	scoped := *bs
+	scoped.storage = bs.storage.WithContext(ctx)
+	return &scoped
+}
+
+func (bs *BranchStorage) WithTx(tx *storage.Tx) *BranchStorage {
+	scoped := *bs
+	scoped.storage = bs.storage.WithTx(tx)
+	return &scoped
+}
+
+func (bs *BranchStorage) validateInput(input Branch) error {
+	if input.Name == "" || len(input.Name) < 3 {
+		return errors.New("invalid input: branch name cannot be empty or shorter than 3 characters")
+	}
+
+	if input.Address == "" || len(input.Address) < 7 {
+		return errors.New("invalid input: branch address cannot be empty or shorter than 7 symbols")
+	}
+
+	if input.OpeningHours == "" {
+		return errors.New("invalid input: branch opening hours cannot be empty")
+	}
+
+	return nil
+}
+
+func findBranch(branches Branches, id int) int {
+	for idx, branch := range branches {
+		if branch.ID == id {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func (bs *BranchStorage) GetBranches() (Branches, error) {
+	branches := Branches{}
+
+	if err := bs.storage.Load(&branches); err != nil {
+		return nil, err
+	}
+
+	return branches, nil
+}
+
+func (bs *BranchStorage) GetBranch(id int) (Branch, error) {
+	branches, err := bs.GetBranches()
+	if err != nil {
+		return Branch{}, err
+	}
+
+	idx := findBranch(branches, id)
+	if idx == -1 {
+		return Branch{}, fmt.Errorf("branch with ID %d not found", id)
+	}
+
+	return branches[idx], nil
+}
+
+func (bs *BranchStorage) AddBranch(input Branch) (Branch, error) {
+	branches := Branches{}
+	if err := bs.storage.Load(&branches); err != nil {
+		return Branch{}, err
+	}
+
+	if err := bs.validateInput(input); err != nil {
+		return Branch{}, err
+	}
+
+	for _, branch := range branches {
+		if branch.Name == input.Name {
+			return Branch{}, fmt.Errorf("branch with name %v already exists", input.Name)
+		}
+	}
+
+	nextID := 1
+	for _, branch := range branches {
+		if branch.ID >= nextID {
+			nextID = branch.ID + 1
+		}
+	}
+
+	input.ID = nextID
+	branches = append(branches, input)
+
+	if err := bs.storage.Save(branches); err != nil {
+		return Branch{}, err
+	}
+
+	return input, nil
+}
+
+func (bs *BranchStorage) EditBranch(input Branch) (Branch, error) {
+	branches := Branches{}
+	if err := bs.storage.Load(&branches); err != nil {
+		return Branch{}, err
+	}
+
+	idx := findBranch(branches, input.ID)
+	if idx == -1 {
+		return Branch{}, fmt.Errorf("branch with ID %d not found", input.ID)
+	}
+
+	if err := bs.validateInput(input); err != nil {
+		return Branch{}, err
+	}
+
+	branches[idx] = input
+
+	if err := bs.storage.Save(branches); err != nil {
+		return Branch{}, err
+	}
+
+	return input, nil
+}
+
+func (bs *BranchStorage) DeleteBranch(id int) error {
+	branches := Branches{}
+	if err := bs.storage.Load(&branches); err != nil {
+		return err
+	}
+
+	idx := findBranch(branches, id)
+	if idx == -1 {
+		return fmt.Errorf("branch with ID %d not found", id)
+	}
+
+	branches = append(branches[:idx], branches[idx+1:]...)
+
+	if err := bs.storage.Save(branches); err != nil {
+		return err
+	}
+
+	return nil
+}
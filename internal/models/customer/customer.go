@@ -1,12 +1,14 @@
 package customer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/mail"
 	"time"
 	"unicode"
 
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
 	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
 	"github.com/ZulfiPy/RWAPIGo/internal/storage"
 	"github.com/ZulfiPy/RWAPIGo/internal/utils"
@@ -19,6 +21,7 @@ type Customer struct {
 	PhoneNumber    string
 	Email          string
 	RentedVehicles []vehicle.Vehicle
+	RiskFlags      []RiskFlag
 	CreatedAt      time.Time
 	LastEditedAt   *time.Time
 }
@@ -27,11 +30,15 @@ type Customers []Customer
 
 type CustomerStorage struct {
 	storage *storage.Storage[Customers]
+	risk    RiskPolicy
+	events  *events.Outbox
 }
 
-func NewCustomerStorage(fileName string) *CustomerStorage {
+func NewCustomerStorage(fileName string, risk RiskPolicy, outbox *events.Outbox) *CustomerStorage {
 	return &CustomerStorage{
 		storage: storage.NewStorage[Customers](fileName),
+		risk:    risk,
+		events:  outbox,
 	}
 }
 
@@ -39,12 +46,18 @@ func (cs *CustomerStorage) GetStorage() *storage.Storage[Customers] {
 	return cs.storage
 }
 
-func validateIndex(idx, customersLength int) error {
-	if idx < 0 || idx >= customersLength {
-		return fmt.Errorf("error:index %d is out of range", idx)
-	}
+func (cs *CustomerStorage) WithContext(ctx context.Context) *CustomerStorage {
+	scoped := *cs
+	scoped.storage = cs.storage.WithContext(ctx)
+	scoped.events = cs.events.WithContext(ctx)
+	return &scoped
+}
 
-	return nil
+func (cs *CustomerStorage) WithTx(tx *storage.Tx) *CustomerStorage {
+	scoped := *cs
+	scoped.storage = cs.storage.WithTx(tx)
+	scoped.events = cs.events.WithTx(tx)
+	return &scoped
 }
 
 func (cs *CustomerStorage) validateInput(input Customer) error {
@@ -87,6 +100,10 @@ func (cs *CustomerStorage) validateInput(input Customer) error {
 	return nil
 }
 
+func subject(personalID int64) string {
+	return fmt.Sprintf("customer/%d", personalID)
+}
+
 func (cs *CustomerStorage) findCustomerByPersonalID(personalID int64) int {
 	customers := Customers{}
 	cs.storage.Load(&customers)
@@ -101,96 +118,97 @@ func (cs *CustomerStorage) findCustomerByPersonalID(personalID int64) int {
 }
 
 func (cs *CustomerStorage) AddCustomer(input Customer) error {
-	if err := cs.validateInput(input); err != nil {
+	batch, err := cs.Begin()
+	if err != nil {
 		return err
 	}
 
-	if idx := cs.findCustomerByPersonalID(input.PersonalID); idx != -1 {
-		return fmt.Errorf("customer with personalID %d is found in the storage, duplicated customers not allowed", input.PersonalID)
+	if _, err := batch.AddCustomer(input); err != nil {
+		return err
 	}
 
-	customers := &Customers{}
-	cs.storage.Load(customers)
+	return batch.Commit()
+}
 
-	newCustomer := Customer{
-		FirstName:      input.FirstName,
-		LastName:       input.LastName,
-		PersonalID:     input.PersonalID,
-		PhoneNumber:    input.PhoneNumber,
-		Email:          input.Email,
-		RentedVehicles: []vehicle.Vehicle{},
-		CreatedAt:      time.Now(),
+// ImportCustomers adds the valid inputs and reports an error for every other
+// one. commit decides from the number of failed inputs whether the valid ones
+// are saved.
+func (cs *CustomerStorage) ImportCustomers(inputs []Customer, commit func(failed int) bool) ([]error, error) {
+	customers := Customers{}
+	if err := cs.storage.Load(&customers); err != nil {
+		return nil, err
 	}
 
-	*customers = append(*customers, newCustomer)
-
-	if err := cs.storage.Save(*customers); err != nil {
-		return err
+	known := map[int64]bool{}
+	for _, customer := range customers {
+		known[customer.PersonalID] = true
 	}
 
-	return nil
-}
-
-func (cs *CustomerStorage) DeleteCustomer(personalID int64) error {
-	customers := Customers{}
-	cs.storage.Load(&customers)
+	rowErrors := make([]error, len(inputs))
+	failed := 0
+	created := events.Events{}
+	for idx, input := range inputs {
+		err := cs.validateInput(input)
+		if err == nil && known[input.PersonalID] {
+			err = fmt.Errorf("customer with personalID %d is found in the storage, duplicated customers not allowed", input.PersonalID)
+		}
 
-	idx := cs.findCustomerByPersonalID(personalID)
+		if err != nil {
+			rowErrors[idx] = err
+			failed++
+			continue
+		}
 
-	if idx == -1 {
-		return fmt.Errorf("customer with personalID %d not found", personalID)
+		known[input.PersonalID] = true
+		newCustomer := Customer{
+			FirstName:      input.FirstName,
+			LastName:       input.LastName,
+			PersonalID:     input.PersonalID,
+			PhoneNumber:    input.PhoneNumber,
+			Email:          input.Email,
+			RentedVehicles: []vehicle.Vehicle{},
+			RiskFlags:      []RiskFlag{},
+			CreatedAt:      time.Now(),
+		}
+		customers = append(customers, newCustomer)
+		created = append(created, events.New(events.CustomerCreated, subject(newCustomer.PersonalID), newCustomer))
 	}
 
-	if err := validateIndex(idx, len(customers)); err != nil {
-		return err
+	if !commit(failed) {
+		return rowErrors, nil
 	}
 
-	customers = append(customers[:idx], customers[idx+1:]...)
-
 	if err := cs.storage.Save(customers); err != nil {
-		return err
+		return rowErrors, err
 	}
 
-	return nil
+	return rowErrors, cs.events.Append(created...)
 }
 
-func (cs *CustomerStorage) EditCustomer(firstName, lastName, email, phoneNumber string, personalID int64) error {
-	idx := cs.findCustomerByPersonalID(personalID)
-
-	if idx == -1 {
-		return fmt.Errorf("customer with personalID %d not found in the storage", personalID)
-	}
-
-	customers := Customers{}
-	cs.storage.Load(&customers)
-
-	customerToEdit := &customers[idx]
-
-	if len(firstName) != 0 {
-		customerToEdit.FirstName = firstName
+func (cs *CustomerStorage) DeleteCustomer(personalID int64) error {
+	batch, err := cs.Begin()
+	if err != nil {
+		return err
 	}
 
-	if len(lastName) != 0 {
-		customerToEdit.LastName = lastName
+	if err := batch.DeleteCustomer(personalID); err != nil {
+		return err
 	}
 
-	if len(email) != 0 {
-		customerToEdit.Email = email
-	}
+	return batch.Commit()
+}
 
-	if len(phoneNumber) != 0 {
-		customerToEdit.PhoneNumber = phoneNumber
+func (cs *CustomerStorage) EditCustomer(firstName, lastName, email, phoneNumber string, personalID int64) error {
+	batch, err := cs.Begin()
+	if err != nil {
+		return err
 	}
 
-	lastEdited := time.Now()
-
-	customerToEdit.LastEditedAt = &lastEdited
-
-	if err := cs.storage.Save(customers); err != nil {
+	if _, err := batch.EditCustomer(firstName, lastName, email, phoneNumber, personalID); err != nil {
 		return err
 	}
 
-	return nil
+	return batch.Commit()
 }
 
 func (cs *CustomerStorage) GetCustomers() (Customers, error) {
@@ -203,6 +221,21 @@ func (cs *CustomerStorage) GetCustomers() (Customers, error) {
 	return customers, nil
 }
 
+func (cs *CustomerStorage) GetCustomer(personalID int64) (Customer, error) {
+	customers := Customers{}
+	if err := cs.storage.Load(&customers); err != nil {
+		return Customer{}, err
+	}
+
+	for _, customer := range customers {
+		if customer.PersonalID == personalID {
+			return customer, nil
+		}
+	}
+
+	return Customer{}, fmt.Errorf("customer with personalID %d not found", personalID)
+}
+
 func (cs *CustomerStorage) AddVehicle(vehicle vehicle.Vehicle, personalID int64) (Customer, error) {
 	customers := Customers{}
 	if err := cs.storage.Load(&customers); err != nil {
@@ -219,6 +252,12 @@ func (cs *CustomerStorage) AddVehicle(vehicle vehicle.Vehicle, personalID int64)
 	if err := cs.storage.Save(customers); err != nil {
 		return Customer{}, err
 	}
+
+	assigned := events.Assignment{PersonalID: personalID, PlateNumber: vehicle.PlateNumber}
+	if err := cs.events.Append(events.New(events.VehicleAssigned, subject(personalID), assigned)); err != nil {
+		return Customer{}, err
+	}
+
 	return customers[idx], nil
 }
 
@@ -233,9 +272,11 @@ func (cs *CustomerStorage) DeleteVehicle(plateNumber string, personalID int64) e
 		return fmt.Errorf("customer with personalID %d not found", personalID)
 	}
 
+	returned := false
 	for idx, vehicle := range customers[customerIdx].RentedVehicles {
 		if vehicle.PlateNumber == plateNumber {
 			customers[customerIdx].RentedVehicles = append(customers[customerIdx].RentedVehicles[:idx], customers[customerIdx].RentedVehicles[idx+1:]...)
+			returned = true
 			break
 		}
 		// return fmt.Errorf("vehicle with plateNumber %v not found in customers rented vehicles", plateNumber)
@@ -245,5 +286,125 @@ func (cs *CustomerStorage) DeleteVehicle(plateNumber string, personalID int64) e
 		return err
 	}
 
+	if !returned {
+		return nil
+	}
+
+	return cs.events.Append(events.New(events.VehicleReturned, subject(personalID), events.Assignment{PersonalID: personalID, PlateNumber: plateNumber}))
+}
+
+// Batch applies several changes to one loaded copy of the customers file and
+// saves them with a single Commit.
+type Batch struct {
+	storage   *CustomerStorage
+	customers Customers
+	changed   bool
+	pending   events.Events
+}
+
+func (cs *CustomerStorage) Begin() (*Batch, error) {
+	customers := Customers{}
+	if err := cs.storage.Load(&customers); err != nil {
+		return nil, err
+	}
+
+	return &Batch{storage: cs, customers: customers}, nil
+}
+
+func (b *Batch) find(personalID int64) int {
+	for idx, customer := range b.customers {
+		if customer.PersonalID == personalID {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func (b *Batch) AddCustomer(input Customer) (Customer, error) {
+	if err := b.storage.validateInput(input); err != nil {
+		return Customer{}, err
+	}
+
+	if idx := b.find(input.PersonalID); idx != -1 {
+		return Customer{}, fmt.Errorf("customer with personalID %d is found in the storage, duplicated customers not allowed", input.PersonalID)
+	}
+
+	newCustomer := Customer{
+		FirstName:      input.FirstName,
+		LastName:       input.LastName,
+		PersonalID:     input.PersonalID,
+		PhoneNumber:    input.PhoneNumber,
+		Email:          input.Email,
+		RentedVehicles: []vehicle.Vehicle{},
+		RiskFlags:      []RiskFlag{},
+		CreatedAt:      time.Now(),
+	}
+
+	b.customers = append(b.customers, newCustomer)
+	b.changed = true
+	b.pending = append(b.pending, events.New(events.CustomerCreated, subject(newCustomer.PersonalID), newCustomer))
+
+	return newCustomer, nil
+}
+
+func (b *Batch) EditCustomer(firstName, lastName, email, phoneNumber string, personalID int64) (Customer, error) {
+	idx := b.find(personalID)
+	if idx == -1 {
+		return Customer{}, fmt.Errorf("customer with personalID %d not found in the storage", personalID)
+	}
+
+	customerToEdit := &b.customers[idx]
+
+	if len(firstName) != 0 {
+		customerToEdit.FirstName = firstName
+	}
+
+	if len(lastName) != 0 {
+		customerToEdit.LastName = lastName
+	}
+
+	if len(email) != 0 {
+		customerToEdit.Email = email
+	}
+
+	if len(phoneNumber) != 0 {
+		customerToEdit.PhoneNumber = phoneNumber
+	}
+
+	lastEdited := time.Now()
+	customerToEdit.LastEditedAt = &lastEdited
+	b.changed = true
+	b.pending = append(b.pending, events.New(events.CustomerUpdated, subject(personalID), *customerToEdit))
+
+	return *customerToEdit, nil
+}
+
+func (b *Batch) DeleteCustomer(personalID int64) error {
+	idx := b.find(personalID)
+	if idx == -1 {
+		return fmt.Errorf("customer with personalID %d not found", personalID)
+	}
+
+	b.pending = append(b.pending, events.New(events.CustomerDeleted, subject(personalID), b.customers[idx]))
+	b.customers = append(b.customers[:idx], b.customers[idx+1:]...)
+	b.changed = true
+
 	return nil
 }
+
+// Commit saves the batch and its events in one transaction.
+func (b *Batch) Commit() error {
+	if !b.changed {
+		return nil
+	}
+
+	return b.storage.events.Within(func(tx *storage.Tx) error {
+		scoped := b.storage.WithTx(tx)
+		if err := scoped.storage.Save(b.customers); err != nil {
+			return err
+		}
+
+		return scoped.events.Append(b.pending...)
+	})
+}
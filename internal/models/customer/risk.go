@@ -0,0 +1,203 @@
+package customer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+)
+
+// RiskFlag marks a customer who should not be served, for example because of
+// unpaid damages or fraud. A flag without ExpiresAt stays until removed.
+type RiskFlag struct {
+	ID        int
+	Reason    string
+	SetBy     int64
+	SetAt     time.Time
+	ExpiresAt *time.Time
+}
+
+// RiskPolicy lists the employees allowed to override risk flags.
+type RiskPolicy struct {
+	OverrideManagers []int64
+}
+
+// RiskFlagChange is the payload of RiskFlagSet and RiskFlagCleared. By is the
+// employee who made the change.
+type RiskFlagChange struct {
+	PersonalID int64
+	Flag       RiskFlag
+	By         int64
+}
+
+// RiskOverride is the payload of RiskFlagOverridden, recorded whenever a
+// manager lets a flagged customer rent a vehicle.
+type RiskOverride struct {
+	PersonalID  int64
+	PlateNumber string
+	ManagerID   int64
+	EmployeeID  int64
+	Flags       []RiskFlag
+}
+
+// FlaggedError is returned for customers with active risk flags.
+type FlaggedError struct {
+	PersonalID int64
+	Flags      []RiskFlag
+}
+
+func (e *FlaggedError) Error() string {
+	reasons := []string{}
+	for _, flag := range e.Flags {
+		reason := fmt.Sprintf("%s (set by employee %d on %s", flag.Reason, flag.SetBy, flag.SetAt.Format("02.01.2006"))
+		if flag.ExpiresAt != nil {
+			reason += ", expires " + flag.ExpiresAt.Format("02.01.2006")
+		}
+		reasons = append(reasons, reason+")")
+	}
+
+	return fmt.Sprintf("customer with personalID %d is flagged: %s", e.PersonalID, strings.Join(reasons, "; "))
+}
+
+func (f RiskFlag) Active(at time.Time) bool {
+	return f.ExpiresAt == nil || at.Before(*f.ExpiresAt)
+}
+
+func (c Customer) ActiveRiskFlags(at time.Time) []RiskFlag {
+	active := []RiskFlag{}
+	for _, flag := range c.RiskFlags {
+		if flag.Active(at) {
+			active = append(active, flag)
+		}
+	}
+
+	return active
+}
+
+// CheckRisk returns a FlaggedError when the customer has active risk flags.
+func (c Customer) CheckRisk(at time.Time) error {
+	if flags := c.ActiveRiskFlags(at); len(flags) > 0 {
+		return &FlaggedError{PersonalID: c.PersonalID, Flags: flags}
+	}
+
+	return nil
+}
+
+func (cs *CustomerStorage) GetRiskFlags(personalID int64) ([]RiskFlag, error) {
+	customer, err := cs.GetCustomer(personalID)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer.RiskFlags == nil {
+		return []RiskFlag{}, nil
+	}
+
+	return customer.RiskFlags, nil
+}
+
+func (cs *CustomerStorage) AddRiskFlag(personalID int64, input RiskFlag) (RiskFlag, error) {
+	now := time.Now()
+
+	if len(strings.TrimSpace(input.Reason)) < 5 {
+		return RiskFlag{}, errors.New("invalid input: risk flag reason cannot be empty or shorter than 5 characters")
+	}
+
+	if utils.IntLength(input.SetBy) != 11 {
+		return RiskFlag{}, errors.New("invalid input: personal id of the flagging employee must be exactly 11 digits")
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return RiskFlag{}, errors.New("invalid input: risk flag expiry must be in the future")
+	}
+
+	var flag RiskFlag
+	err := cs.events.Within(func(tx *storage.Tx) error {
+		scoped := cs.WithTx(tx)
+
+		customers := Customers{}
+		if err := scoped.storage.Load(&customers); err != nil {
+			return err
+		}
+
+		idx := slices.IndexFunc(customers, func(customer Customer) bool { return customer.PersonalID == personalID })
+		if idx == -1 {
+			return fmt.Errorf("customer with personalID %d not found", personalID)
+		}
+
+		nextID := 1
+		for _, flag := range customers[idx].RiskFlags {
+			if flag.ID >= nextID {
+				nextID = flag.ID + 1
+			}
+		}
+
+		flag = RiskFlag{
+			ID:        nextID,
+			Reason:    strings.TrimSpace(input.Reason),
+			SetBy:     input.SetBy,
+			SetAt:     now,
+			ExpiresAt: input.ExpiresAt,
+		}
+		customers[idx].RiskFlags = append(customers[idx].RiskFlags, flag)
+
+		if err := scoped.storage.Save(customers); err != nil {
+			return err
+		}
+
+		change := RiskFlagChange{PersonalID: personalID, Flag: flag, By: input.SetBy}
+		return scoped.events.Append(events.New(events.RiskFlagSet, subject(personalID), change))
+	})
+	if err != nil {
+		return RiskFlag{}, err
+	}
+
+	return flag, nil
+}
+
+func (cs *CustomerStorage) RemoveRiskFlag(personalID int64, flagID int, employeeID int64) error {
+	return cs.events.Within(func(tx *storage.Tx) error {
+		scoped := cs.WithTx(tx)
+
+		customers := Customers{}
+		if err := scoped.storage.Load(&customers); err != nil {
+			return err
+		}
+
+		idx := slices.IndexFunc(customers, func(customer Customer) bool { return customer.PersonalID == personalID })
+		if idx == -1 {
+			return fmt.Errorf("customer with personalID %d not found", personalID)
+		}
+
+		flags := customers[idx].RiskFlags
+		flagIdx := slices.IndexFunc(flags, func(flag RiskFlag) bool { return flag.ID == flagID })
+		if flagIdx == -1 {
+			return fmt.Errorf("risk flag with ID %d not found for customer with personalID %d", flagID, personalID)
+		}
+
+		removed := flags[flagIdx]
+		customers[idx].RiskFlags = append(flags[:flagIdx], flags[flagIdx+1:]...)
+
+		if err := scoped.storage.Save(customers); err != nil {
+			return err
+		}
+
+		change := RiskFlagChange{PersonalID: personalID, Flag: removed, By: employeeID}
+		return scoped.events.Append(events.New(events.RiskFlagCleared, subject(personalID), change))
+	})
+}
+
+// OverrideRisk records a manager's approval to serve a flagged customer. It
+// fails unless the manager is listed in the risk policy.
+func (cs *CustomerStorage) OverrideRisk(override RiskOverride) error {
+	if !slices.Contains(cs.risk.OverrideManagers, override.ManagerID) {
+		return fmt.Errorf("employee with personalID %d is not allowed to override risk flags", override.ManagerID)
+	}
+
+	return cs.events.Append(events.New(events.RiskFlagOverridden, subject(override.PersonalID), override))
+}
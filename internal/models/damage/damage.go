@@ -0,0 +1,271 @@
+package damage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const (
+	StatusOpen     = "open"
+	StatusRepaired = "repaired"
+)
+
+type Damage struct {
+	ID                 int
+	PlateNumber        string
+	RentalID           int
+	Location           string
+	Severity           string
+	Description        string
+	RepairCostEstimate float64
+	Photos             []string
+	Status             string
+	ReportedAt         time.Time
+	RepairedAt         *time.Time
+}
+
+type Damages []Damage
+
+type DamageStorage struct {
+	storage  *storage.Storage[Damages]
+	photoDir string
+}
+
+var severities = []string{"minor", "moderate", "severe"}
+
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+func NewDamageStorage(fileName, photoDir string) *DamageStorage {
+	return &DamageStorage{
+		storage:  storage.NewStorage[Damages](fileName),
+		photoDir: photoDir,
+	}
+}
+
+func (ds *DamageStorage) GetStorage() *storage.Storage[Damages] {
+	return ds.storage
+}
+
+func (ds *DamageStorage) WithContext(ctx context.Context) *DamageStorage {
+	scoped := *ds
+	scoped.storage = ds.storage.WithContext(ctx)
+	return &scoped
+}
+
+func (ds *DamageStorage) WithTx(tx *storage.Tx) *DamageStorage {
+	scoped := *ds
+	scoped.storage = ds.storage.WithTx(tx)
+	return &scoped
+}
+
+// ValidateDamage checks a damage report before its photos are saved, so an
+// invalid report does not leave photos behind.
+func (ds *DamageStorage) ValidateDamage(input Damage) error {
+	if input.PlateNumber == "" {
+		return errors.New("invalid input: damage plate number may not be empty")
+	}
+
+	if input.Location == "" {
+		return errors.New("invalid input: damage location may not be empty")
+	}
+
+	if !slices.Contains(severities, strings.ToLower(input.Severity)) {
+		return errors.New("invalid input: damage severity may only be (minor / moderate / severe)")
+	}
+
+	if input.Description == "" {
+		return errors.New("invalid input: damage description may not be empty")
+	}
+
+	if input.RepairCostEstimate < 0 {
+		return errors.New("invalid input: repair cost estimate may not be negative")
+	}
+
+	return nil
+}
+
+func (ds *DamageStorage) GetDamages(plateNumber string) (Damages, error) {
+	damages := Damages{}
+	if err := ds.storage.Load(&damages); err != nil {
+		return nil, err
+	}
+
+	vehicleDamages := Damages{}
+	for _, damage := range damages {
+		if damage.PlateNumber == plateNumber {
+			vehicleDamages = append(vehicleDamages, damage)
+		}
+	}
+
+	return vehicleDamages, nil
+}
+
+func (ds *DamageStorage) HasOpenDamages(plateNumber string) (bool, error) {
+	damages, err := ds.GetDamages(plateNumber)
+	if err != nil {
+		return false, err
+	}
+
+	for _, damage := range damages {
+		if damage.Status == StatusOpen {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (ds *DamageStorage) AddDamage(input Damage) (Damage, error) {
+	if err := ds.ValidateDamage(input); err != nil {
+		return Damage{}, err
+	}
+
+	damages := Damages{}
+	if err := ds.storage.Load(&damages); err != nil {
+		return Damage{}, err
+	}
+
+	nextID := 1
+	for _, damage := range damages {
+		if damage.ID >= nextID {
+			nextID = damage.ID + 1
+		}
+	}
+
+	newDamage := Damage{
+		ID:                 nextID,
+		PlateNumber:        input.PlateNumber,
+		RentalID:           input.RentalID,
+		Location:           input.Location,
+		Severity:           strings.ToLower(input.Severity),
+		Description:        input.Description,
+		RepairCostEstimate: input.RepairCostEstimate,
+		Photos:             input.Photos,
+		Status:             StatusOpen,
+		ReportedAt:         time.Now(),
+	}
+
+	if newDamage.Photos == nil {
+		newDamage.Photos = []string{}
+	}
+
+	damages = append(damages, newDamage)
+
+	if err := ds.storage.Save(damages); err != nil {
+		return Damage{}, err
+	}
+
+	return newDamage, nil
+}
+
+func (ds *DamageStorage) RepairDamage(plateNumber string, damageID int) (Damage, error) {
+	damages := Damages{}
+	if err := ds.storage.Load(&damages); err != nil {
+		return Damage{}, err
+	}
+
+	for idx, damage := range damages {
+		if damage.ID != damageID || damage.PlateNumber != plateNumber {
+			continue
+		}
+
+		if damage.Status == StatusRepaired {
+			return Damage{}, fmt.Errorf("damage %d of vehicle %v is already repaired", damageID, plateNumber)
+		}
+
+		repairedAt := time.Now()
+		damages[idx].Status = StatusRepaired
+		damages[idx].RepairedAt = &repairedAt
+
+		if err := ds.storage.Save(damages); err != nil {
+			return Damage{}, err
+		}
+
+		return damages[idx], nil
+	}
+
+	return Damage{}, fmt.Errorf("damage %d of vehicle %v not found", damageID, plateNumber)
+}
+
+func (ds *DamageStorage) SavePhoto(plateNumber string, photo io.Reader) (string, error) {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(photo, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	header = header[:n]
+
+	extension, ok := photoExtensions[http.DetectContentType(header)]
+	if !ok {
+		return "", errors.New("invalid input: damage photos may only be JPEG, PNG or WebP images")
+	}
+
+	dir := filepath.Join(ds.photoDir, plateNumber)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	fileName := fmt.Sprintf("%d-%s%s", time.Now().Unix(), hex.EncodeToString(suffix), extension)
+
+	path := filepath.Join(dir, fileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.Write(header)
+	if err == nil {
+		_, err = io.Copy(file, photo)
+	}
+	if err = errors.Join(err, file.Close()); err != nil {
+		return "", errors.Join(err, os.Remove(path))
+	}
+
+	return fileName, nil
+}
+
+// RemovePhotos deletes photos saved for a damage report that was not added.
+func (ds *DamageStorage) RemovePhotos(plateNumber string, fileNames []string) error {
+	errs := []error{}
+	for _, fileName := range fileNames {
+		err := os.Remove(filepath.Join(ds.photoDir, plateNumber, fileName))
+		if !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (ds *DamageStorage) PhotoPath(plateNumber, fileName string) (string, error) {
+	if fileName != filepath.Base(fileName) || plateNumber != filepath.Base(plateNumber) {
+		return "", errors.New("invalid photo path")
+	}
+
+	path := filepath.Join(ds.photoDir, plateNumber, fileName)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("photo %v of vehicle %v not found", fileName, plateNumber)
+	}
+
+	return path, nil
+}
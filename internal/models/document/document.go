@@ -0,0 +1,322 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/blob"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+)
+
+const (
+	TypeIDCard   = "id_card"
+	TypeLicence  = "licence"
+	TypeContract = "contract"
+)
+
+var Types = []string{TypeIDCard, TypeLicence, TypeContract}
+
+// Document is a scan kept for a customer. The content lives in the blob
+// store under SHA256, documents with the same content share one blob.
+type Document struct {
+	ID          int
+	PersonalID  int64
+	Type        string
+	FileName    string
+	ContentType string
+	Size        int64
+	SHA256      string
+	ExpiresAt   *time.Time
+	UploadedBy  int64
+	UploadedAt  time.Time
+}
+
+type Documents []Document
+
+type DocumentStorage struct {
+	storage *storage.Storage[Documents]
+	blobs   *blob.Store
+	journal *storage.Journal
+	tx      *storage.Tx
+	// blobLock keeps a blob from being deleted as unreferenced while an
+	// upload of the same content is being saved. It is taken before the
+	// journal, never while a transaction is open.
+	blobLock *sync.Mutex
+}
+
+func NewDocumentStorage(fileName string, blobs *blob.Store, journal *storage.Journal) *DocumentStorage {
+	return &DocumentStorage{
+		storage:  storage.NewStorage[Documents](fileName),
+		blobs:    blobs,
+		journal:  journal,
+		blobLock: &sync.Mutex{},
+	}
+}
+
+func (ds *DocumentStorage) GetStorage() *storage.Storage[Documents] {
+	return ds.storage
+}
+
+func (ds *DocumentStorage) WithContext(ctx context.Context) *DocumentStorage {
+	scoped := *ds
+	scoped.storage = ds.storage.WithContext(ctx)
+	return &scoped
+}
+
+func (ds *DocumentStorage) WithTx(tx *storage.Tx) *DocumentStorage {
+	scoped := *ds
+	scoped.storage = ds.storage.WithTx(tx)
+	scoped.tx = tx
+	return &scoped
+}
+
+func (ds *DocumentStorage) MaxSize() int64 {
+	return ds.blobs.MaxSize()
+}
+
+func validateDocument(input Document) error {
+	if !slices.Contains(Types, input.Type) {
+		return fmt.Errorf("invalid input: document type may only be (%s)", strings.Join(Types, " / "))
+	}
+
+	if utils.IntLength(input.UploadedBy) != 11 {
+		return errors.New("invalid input: personal id of the uploading employee must be exactly 11 digits")
+	}
+
+	return nil
+}
+
+func (ds *DocumentStorage) GetDocuments(personalID int64) (Documents, error) {
+	documents := Documents{}
+	if err := ds.storage.Load(&documents); err != nil {
+		return nil, err
+	}
+
+	customerDocuments := Documents{}
+	for _, document := range documents {
+		if document.PersonalID == personalID {
+			customerDocuments = append(customerDocuments, document)
+		}
+	}
+
+	return customerDocuments, nil
+}
+
+func (ds *DocumentStorage) GetDocument(personalID int64, documentID int) (Document, error) {
+	documents, err := ds.GetDocuments(personalID)
+	if err != nil {
+		return Document{}, err
+	}
+
+	idx := slices.IndexFunc(documents, func(document Document) bool { return document.ID == documentID })
+	if idx == -1 {
+		return Document{}, fmt.Errorf("document %d of customer with personalID %d not found", documentID, personalID)
+	}
+
+	return documents[idx], nil
+}
+
+// OpenDocument returns the document together with its content.
+func (ds *DocumentStorage) OpenDocument(personalID int64, documentID int) (Document, *os.File, error) {
+	document, err := ds.GetDocument(personalID, documentID)
+	if err != nil {
+		return Document{}, nil, err
+	}
+
+	content, err := ds.blobs.Open(document.SHA256)
+	if err != nil {
+		return Document{}, nil, fmt.Errorf("content of document %d is missing: %w", documentID, err)
+	}
+
+	return document, content, nil
+}
+
+// Upload validates input, stores content in the blob store and passes the
+// stored blob to save, which records the document with AddDocument in a
+// transaction it commits. When save fails the blob is deleted again, unless
+// another document refers to the same content.
+func (ds *DocumentStorage) Upload(input Document, content io.Reader, save func(stored blob.Blob) error) error {
+	if err := validateDocument(input); err != nil {
+		return err
+	}
+
+	ds.blobLock.Lock()
+	defer ds.blobLock.Unlock()
+
+	stored, err := ds.blobs.Put(content)
+	if err != nil {
+		return err
+	}
+
+	if err := save(stored); err != nil {
+		return errors.Join(err, ds.discard(stored.Digest))
+	}
+
+	return nil
+}
+
+// discard deletes a blob whose document was not saved. It reads the
+// documents in a transaction, so a commit that failed halfway is replayed
+// first and a document it did save keeps its content.
+func (ds *DocumentStorage) discard(digest string) error {
+	tx, err := ds.journal.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	documents := Documents{}
+	if err := ds.storage.WithTx(tx).Load(&documents); err != nil {
+		return err
+	}
+
+	return ds.deleteUnreferenced(documents, digest)
+}
+
+// AddDocument records a document for content stored by Upload. The size and
+// content type are taken from the stored content.
+func (ds *DocumentStorage) AddDocument(input Document, stored blob.Blob) (Document, error) {
+	if err := validateDocument(input); err != nil {
+		return Document{}, err
+	}
+
+	documents := Documents{}
+	if err := ds.storage.Load(&documents); err != nil {
+		return Document{}, err
+	}
+
+	nextID := 1
+	for _, document := range documents {
+		if document.ID >= nextID {
+			nextID = document.ID + 1
+		}
+	}
+
+	newDocument := Document{
+		ID:          nextID,
+		PersonalID:  input.PersonalID,
+		Type:        input.Type,
+		FileName:    fileName(input.FileName),
+		ContentType: stored.ContentType,
+		Size:        stored.Size,
+		SHA256:      stored.Digest,
+		ExpiresAt:   input.ExpiresAt,
+		UploadedBy:  input.UploadedBy,
+		UploadedAt:  time.Now(),
+	}
+
+	if err := ds.storage.Save(append(documents, newDocument)); err != nil {
+		return Document{}, err
+	}
+
+	return newDocument, nil
+}
+
+func fileName(name string) string {
+	name = filepath.Base(name)
+	if name == "." || name == string(filepath.Separator) {
+		return ""
+	}
+
+	return name
+}
+
+func (ds *DocumentStorage) DeleteDocument(personalID int64, documentID int) error {
+	removed, err := ds.deleteDocuments(func(document Document) bool {
+		return document.PersonalID == personalID && document.ID == documentID
+	})
+	if err != nil {
+		return err
+	}
+
+	if removed == 0 {
+		return fmt.Errorf("document %d of customer with personalID %d not found", documentID, personalID)
+	}
+
+	return nil
+}
+
+// DeleteCustomerDocuments removes every document of a customer, used when the
+// customer is deleted.
+func (ds *DocumentStorage) DeleteCustomerDocuments(personalID int64) error {
+	_, err := ds.deleteDocuments(func(document Document) bool { return document.PersonalID == personalID })
+	return err
+}
+
+// deleteDocuments removes the matching documents and then the blobs no other
+// document refers to. Within a transaction the blobs are only deleted once it
+// commits, so a rollback leaves every document readable.
+func (ds *DocumentStorage) deleteDocuments(match func(Document) bool) (int, error) {
+	documents := Documents{}
+	if err := ds.storage.Load(&documents); err != nil {
+		return 0, err
+	}
+
+	digests := []string{}
+	kept := Documents{}
+	for _, document := range documents {
+		if match(document) {
+			digests = append(digests, document.SHA256)
+			continue
+		}
+		kept = append(kept, document)
+	}
+
+	if len(digests) == 0 {
+		return 0, nil
+	}
+
+	if err := ds.storage.Save(kept); err != nil {
+		return 0, err
+	}
+
+	if ds.tx == nil {
+		return len(digests), ds.collect(digests)
+	}
+
+	committed := *ds
+	committed.storage = ds.storage.WithTx(nil)
+	committed.tx = nil
+	ds.tx.OnCommit(func() {
+		if err := committed.collect(digests); err != nil {
+			slog.Error("deleting document content failed", "error", err)
+		}
+	})
+
+	return len(digests), nil
+}
+
+func (ds *DocumentStorage) collect(digests []string) error {
+	ds.blobLock.Lock()
+	defer ds.blobLock.Unlock()
+
+	documents := Documents{}
+	if err := ds.storage.Load(&documents); err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, digest := range digests {
+		errs = append(errs, ds.deleteUnreferenced(documents, digest))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (ds *DocumentStorage) deleteUnreferenced(documents Documents, digest string) error {
+	if slices.ContainsFunc(documents, func(document Document) bool { return document.SHA256 == digest }) {
+		return nil
+	}
+
+	return ds.blobs.Delete(digest)
+}
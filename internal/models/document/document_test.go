@@ -0,0 +1,108 @@
+package document
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/blob"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const testEmployeeID = 39001010000
+
+func newTestStorage(t *testing.T) (*DocumentStorage, *storage.Journal, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	blobDir := filepath.Join(dir, "blobs")
+	journal := storage.NewJournal(filepath.Join(dir, "journal.json"))
+	ds := NewDocumentStorage(filepath.Join(dir, "documents.json"), blob.NewStore(blobDir, 1<<20, []string{"text/plain"}), journal)
+
+	if err := storage.EnsureStorageFile(ds.GetStorage(), Documents{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return ds, journal, blobDir
+}
+
+func upload(ds *DocumentStorage, journal *storage.Journal, content string, fail error) error {
+	input := Document{PersonalID: 49001010000, Type: TypeContract, UploadedBy: testEmployeeID}
+
+	return ds.Upload(input, strings.NewReader(content), func(stored blob.Blob) error {
+		tx, err := journal.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := ds.WithTx(tx).AddDocument(input, stored); err != nil {
+			return err
+		}
+
+		if fail != nil {
+			return fail
+		}
+
+		return tx.Commit()
+	})
+}
+
+func countBlobs(t *testing.T, dir string) int {
+	t.Helper()
+
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			count++
+		}
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func TestUploadDiscardsBlobWhenNotSaved(t *testing.T) {
+	ds, journal, blobDir := newTestStorage(t)
+
+	failed := errors.New("customer not found")
+	if err := upload(ds, journal, "signed contract", failed); !errors.Is(err, failed) {
+		t.Fatalf("Upload() error = %v, want %v", err, failed)
+	}
+
+	if count := countBlobs(t, blobDir); count != 0 {
+		t.Fatalf("%d blobs left after a failed upload, want 0", count)
+	}
+
+	documents := Documents{}
+	if err := ds.GetStorage().Load(&documents); err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 0 {
+		t.Fatalf("documents = %v, want none", documents)
+	}
+}
+
+func TestUploadKeepsSharedBlob(t *testing.T) {
+	ds, journal, blobDir := newTestStorage(t)
+
+	if err := upload(ds, journal, "signed contract", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := upload(ds, journal, "signed contract", errors.New("rolled back")); err == nil {
+		t.Fatal("Upload() succeeded, want the error from save")
+	}
+
+	if count := countBlobs(t, blobDir); count != 1 {
+		t.Fatalf("%d blobs left, want the one shared with the saved document", count)
+	}
+}
@@ -1,9 +1,11 @@
 package employee
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
 	"github.com/ZulfiPy/RWAPIGo/internal/storage"
 	"github.com/ZulfiPy/RWAPIGo/internal/utils"
 )
@@ -16,12 +18,14 @@ type Employee struct {
 	Email       string
 	PhoneNumber string
 	Address     string
+	BranchID    int
 }
 
 type Employees []Employee
 
 type EmployeeStorage struct {
 	storage *storage.Storage[Employees]
+	events  *events.Outbox
 }
 
 func (es *EmployeeStorage) validateInput(input Employee) error {
@@ -88,9 +92,10 @@ func (es *EmployeeStorage) employeePersists(employees Employees, personalID int6
 	return -1, fmt.Errorf("employee with personalID %d not found", personalID)
 }
 
-func NewEmployeeStorage(fileName string) *EmployeeStorage {
+func NewEmployeeStorage(fileName string, outbox *events.Outbox) *EmployeeStorage {
 	return &EmployeeStorage{
 		storage: storage.NewStorage[Employees](fileName),
+		events:  outbox,
 	}
 }
 
@@ -98,6 +103,24 @@ func (es *EmployeeStorage) GetStorage() *storage.Storage[Employees] {
 	return es.storage
 }
 
+func (es *EmployeeStorage) WithContext(ctx context.Context) *EmployeeStorage {
+	scoped := *es
+	scoped.storage = es.storage.WithContext(ctx)
+	scoped.events = es.events.WithContext(ctx)
+	return &scoped
+}
+
+func (es *EmployeeStorage) WithTx(tx *storage.Tx) *EmployeeStorage {
+	scoped := *es
+	scoped.storage = es.storage.WithTx(tx)
+	scoped.events = es.events.WithTx(tx)
+	return &scoped
+}
+
+func subject(personalID int64) string {
+	return fmt.Sprintf("employee/%d", personalID)
+}
+
 func (es *EmployeeStorage) GetEmployees() (Employees, error) {
 	employees := Employees{}
 
@@ -108,53 +131,104 @@ func (es *EmployeeStorage) GetEmployees() (Employees, error) {
 	return employees, nil
 }
 
-func (es *EmployeeStorage) AddEmployee(input Employee) (Employee, error) {
-	employees := Employees{}
-	if err := es.storage.Load(&employees); err != nil {
+func (es *EmployeeStorage) GetEmployee(personalID int64) (Employee, error) {
+	employees, err := es.GetEmployees()
+	if err != nil {
 		return Employee{}, err
 	}
 
-	if err := es.validateInput(input); err != nil {
+	idx, err := es.employeePersists(employees, personalID)
+	if err != nil {
 		return Employee{}, err
 	}
 
-	if _, err := es.employeePersists(employees, input.PersonalID); err != nil {
-		return Employee{}, fmt.Errorf("employee with personal ID %d already exists", input.PersonalID)
+	return employees[idx], nil
+}
+
+func (es *EmployeeStorage) AddEmployee(input Employee) (Employee, error) {
+	batch, err := es.Begin()
+	if err != nil {
+		return Employee{}, err
 	}
 
-	employees = append(employees, input)
-	if err := es.storage.Save(employees); err != nil {
+	employee, err := batch.AddEmployee(input)
+	if err != nil {
 		return Employee{}, err
 	}
 
-	return input, nil
+	return employee, batch.Commit()
 }
 
-func (es *EmployeeStorage) DeleteEmployee(personalID int64) error {
+// ImportEmployees adds the valid inputs and reports an error for every other
+// one. commit decides from the number of failed inputs whether the valid ones
+// are saved.
+func (es *EmployeeStorage) ImportEmployees(inputs []Employee, commit func(failed int) bool, check func(Employee) error) ([]error, error) {
 	employees := Employees{}
 	if err := es.storage.Load(&employees); err != nil {
-		return err
+		return nil, err
 	}
 
-	if _, err := es.employeePersists(employees, personalID); err != nil {
-		return err
-	}
+	rowErrors := make([]error, len(inputs))
+	failed := 0
+	created := events.Events{}
+	for idx, input := range inputs {
+		err := es.validateInput(input)
+		if err == nil && check != nil {
+			err = check(input)
+		}
+		if _, persists := es.employeePersists(employees, input.PersonalID); err == nil && persists == nil {
+			err = fmt.Errorf("employee with personal ID %d already exists", input.PersonalID)
+		}
 
-	for idx, employee := range employees {
-		if employee.PersonalID == personalID {
-			employees = append(employees[:idx], employees[idx+1:]...)
-			break
+		if err != nil {
+			rowErrors[idx] = err
+			failed++
+			continue
 		}
+
+		employees = append(employees, input)
+		created = append(created, events.New(events.EmployeeCreated, subject(input.PersonalID), input))
+	}
+
+	if !commit(failed) {
+		return rowErrors, nil
 	}
 
 	if err := es.storage.Save(employees); err != nil {
+		return rowErrors, err
+	}
+
+	return rowErrors, es.events.Append(created...)
+}
+
+func (es *EmployeeStorage) DeleteEmployee(personalID int64) error {
+	batch, err := es.Begin()
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if err := batch.DeleteEmployee(personalID); err != nil {
+		return err
+	}
+
+	return batch.Commit()
 }
 
 func (es *EmployeeStorage) EditEmployeeContacts(email, phoneNumber, address string, personalID int64) (Employee, error) {
+	batch, err := es.Begin()
+	if err != nil {
+		return Employee{}, err
+	}
+
+	employee, err := batch.EditEmployeeContacts(email, phoneNumber, address, personalID)
+	if err != nil {
+		return Employee{}, err
+	}
+
+	return employee, batch.Commit()
+}
+
+func (es *EmployeeStorage) SetBranch(personalID int64, branchID int) (Employee, error) {
 	employees := Employees{}
 
 	if err := es.storage.Load(&employees); err != nil {
@@ -166,19 +240,99 @@ func (es *EmployeeStorage) EditEmployeeContacts(email, phoneNumber, address stri
 		return Employee{}, err
 	}
 
-	if err := es.validateEditData(email, phoneNumber, address); err != nil {
+	employees[idx].BranchID = branchID
+
+	if err := es.storage.Save(employees); err != nil {
+		return Employee{}, err
+	}
+
+	if err := es.events.Append(events.New(events.EmployeeUpdated, subject(personalID), employees[idx])); err != nil {
+		return Employee{}, err
+	}
+
+	return employees[idx], nil
+}
+
+// Batch applies several changes to one loaded copy of the employees file and
+// saves them with a single Commit.
+type Batch struct {
+	storage   *EmployeeStorage
+	employees Employees
+	changed   bool
+	pending   events.Events
+}
+
+func (es *EmployeeStorage) Begin() (*Batch, error) {
+	employees := Employees{}
+	if err := es.storage.Load(&employees); err != nil {
+		return nil, err
+	}
+
+	return &Batch{storage: es, employees: employees}, nil
+}
+
+func (b *Batch) AddEmployee(input Employee) (Employee, error) {
+	if err := b.storage.validateInput(input); err != nil {
+		return Employee{}, err
+	}
+
+	if _, err := b.storage.employeePersists(b.employees, input.PersonalID); err == nil {
+		return Employee{}, fmt.Errorf("employee with personal ID %d already exists", input.PersonalID)
+	}
+
+	b.employees = append(b.employees, input)
+	b.changed = true
+	b.pending = append(b.pending, events.New(events.EmployeeCreated, subject(input.PersonalID), input))
+
+	return input, nil
+}
+
+func (b *Batch) EditEmployeeContacts(email, phoneNumber, address string, personalID int64) (Employee, error) {
+	idx, err := b.storage.employeePersists(b.employees, personalID)
+	if err != nil {
+		return Employee{}, err
+	}
+
+	if err := b.storage.validateEditData(email, phoneNumber, address); err != nil {
 		return Employee{}, err
 	}
 
-	employee := employees[idx]
+	employee := b.employees[idx]
 	employee.Email = email
 	employee.PhoneNumber = phoneNumber
 	employee.Address = address
-	employees[idx] = employee
+	b.employees[idx] = employee
+	b.changed = true
+	b.pending = append(b.pending, events.New(events.EmployeeUpdated, subject(personalID), employee))
 
-	if err := es.storage.Save(employees); err != nil {
-		return Employee{}, err
+	return employee, nil
+}
+
+func (b *Batch) DeleteEmployee(personalID int64) error {
+	idx, err := b.storage.employeePersists(b.employees, personalID)
+	if err != nil {
+		return err
 	}
 
-	return employee, nil
+	b.pending = append(b.pending, events.New(events.EmployeeDeleted, subject(personalID), b.employees[idx]))
+	b.employees = append(b.employees[:idx], b.employees[idx+1:]...)
+	b.changed = true
+
+	return nil
+}
+
+// Commit saves the batch and its events in one transaction.
+func (b *Batch) Commit() error {
+	if !b.changed {
+		return nil
+	}
+
+	return b.storage.events.Within(func(tx *storage.Tx) error {
+		scoped := b.storage.WithTx(tx)
+		if err := scoped.storage.Save(b.employees); err != nil {
+			return err
+		}
+
+		return scoped.events.Append(b.pending...)
+	})
 }
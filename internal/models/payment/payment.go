@@ -0,0 +1,577 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const (
+	TypeDeposit        = "deposit"
+	TypeDepositRelease = "deposit_release"
+	TypeCharge         = "charge"
+	TypePayment        = "payment"
+	TypeRefund         = "refund"
+)
+
+// Entries that move money are recorded as pending and only sent to the
+// provider once the transaction recording them has committed, so a rollback
+// never leaves a charge without a ledger entry. The outcome of the provider
+// call is appended as a completed or failed entry settling the pending one;
+// entries are never changed once written.
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+var errDepositPending = errors.New("the deposit to release is still pending")
+
+type Entry struct {
+	ID          int
+	PersonalID  int64
+	Type        string
+	Amount      float64
+	Balance     float64
+	RentalID    int
+	Reference   string
+	RefundOf    string `json:",omitempty"`
+	Description string
+	Status      string
+	Failure     string `json:",omitempty"`
+	Settles     int    `json:",omitempty"`
+	CreatedAt   time.Time
+}
+
+// counts tells whether the entry is part of the balance. Pending entries only
+// count through the entry settling them. Entries recorded before entries had
+// a status count as completed.
+func (e Entry) counts() bool {
+	return e.Status != StatusFailed && e.Status != StatusPending
+}
+
+type Entries []Entry
+
+// outcomes maps every settled pending entry to the entry settling it.
+func (entries Entries) outcomes() map[int]Entry {
+	outcomes := map[int]Entry{}
+	for _, entry := range entries {
+		if entry.Settles != 0 {
+			outcomes[entry.Settles] = entry
+		}
+	}
+
+	return outcomes
+}
+
+// resolve returns the entry settling entry, or entry itself while it is
+// pending or when it never was.
+func resolve(outcomes map[int]Entry, entry Entry) Entry {
+	if outcome, ok := outcomes[entry.ID]; ok {
+		return outcome
+	}
+
+	return entry
+}
+
+type Balance struct {
+	PersonalID   int64
+	Balance      float64
+	DepositsHeld float64
+	Charges      float64
+	Payments     float64
+	Refunds      float64
+}
+
+type PaymentStorage struct {
+	storage       *storage.Storage[Entries]
+	provider      Provider
+	depositAmount float64
+	journal       *storage.Journal
+	tx            *storage.Tx
+	// settling keeps two settlements from calling the provider for the same
+	// pending entry.
+	settling *sync.Mutex
+}
+
+func NewPaymentStorage(fileName string, provider Provider, depositAmount float64, journal *storage.Journal) *PaymentStorage {
+	return &PaymentStorage{
+		storage:       storage.NewStorage[Entries](fileName),
+		provider:      provider,
+		depositAmount: depositAmount,
+		journal:       journal,
+		settling:      &sync.Mutex{},
+	}
+}
+
+func (ps *PaymentStorage) GetStorage() *storage.Storage[Entries] {
+	return ps.storage
+}
+
+func (ps *PaymentStorage) WithContext(ctx context.Context) *PaymentStorage {
+	scoped := *ps
+	scoped.storage = ps.storage.WithContext(ctx)
+	return &scoped
+}
+
+func (ps *PaymentStorage) WithTx(tx *storage.Tx) *PaymentStorage {
+	scoped := *ps
+	scoped.storage = ps.storage.WithTx(tx)
+	scoped.tx = tx
+	return &scoped
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func signedAmount(entryType string, amount float64) float64 {
+	switch entryType {
+	case TypeDeposit, TypePayment:
+		return amount
+	default:
+		return -amount
+	}
+}
+
+func customerBalance(entries Entries, personalID int64) float64 {
+	balance := 0.0
+	for _, entry := range entries {
+		if entry.PersonalID == personalID && entry.counts() {
+			balance += signedAmount(entry.Type, entry.Amount)
+		}
+	}
+
+	return roundMoney(balance)
+}
+
+func (ps *PaymentStorage) appendEntry(input Entry) (Entry, error) {
+	entries := Entries{}
+	if err := ps.storage.Load(&entries); err != nil {
+		return Entry{}, err
+	}
+
+	if input.Amount <= 0 {
+		return Entry{}, errors.New("invalid input: payment amount must be greater than zero")
+	}
+
+	nextID := 1
+	for _, entry := range entries {
+		if entry.ID >= nextID {
+			nextID = entry.ID + 1
+		}
+	}
+
+	if input.Status == "" {
+		input.Status = StatusCompleted
+	}
+
+	input.ID = nextID
+	input.Amount = roundMoney(input.Amount)
+	input.Balance = customerBalance(entries, input.PersonalID)
+	if input.counts() {
+		input.Balance = roundMoney(input.Balance + signedAmount(input.Type, input.Amount))
+	}
+	input.CreatedAt = time.Now()
+
+	entries = append(entries, input)
+
+	if err := ps.storage.Save(entries); err != nil {
+		return Entry{}, err
+	}
+
+	return input, nil
+}
+
+func (ps *PaymentStorage) GetEntries(personalID int64) (Entries, error) {
+	entries := Entries{}
+	if err := ps.storage.Load(&entries); err != nil {
+		return nil, err
+	}
+
+	customerEntries := Entries{}
+	for _, entry := range entries {
+		if entry.PersonalID == personalID {
+			customerEntries = append(customerEntries, entry)
+		}
+	}
+
+	return customerEntries, nil
+}
+
+func (ps *PaymentStorage) GetEntry(id int) (Entry, error) {
+	entries := Entries{}
+	if err := ps.storage.Load(&entries); err != nil {
+		return Entry{}, err
+	}
+
+	idx := slices.IndexFunc(entries, func(entry Entry) bool { return entry.ID == id })
+	if idx == -1 {
+		return Entry{}, fmt.Errorf("payment entry %d not found", id)
+	}
+
+	return entries[idx], nil
+}
+
+// Outcome returns the entry settling the pending entry id, or the entry
+// itself while it is still pending or when it was never pending.
+func (ps *PaymentStorage) Outcome(id int) (Entry, error) {
+	entries := Entries{}
+	if err := ps.storage.Load(&entries); err != nil {
+		return Entry{}, err
+	}
+
+	idx := slices.IndexFunc(entries, func(entry Entry) bool { return entry.ID == id })
+	if idx == -1 {
+		return Entry{}, fmt.Errorf("payment entry %d not found", id)
+	}
+
+	return resolve(entries.outcomes(), entries[idx]), nil
+}
+
+func (ps *PaymentStorage) GetBalance(personalID int64) (Balance, error) {
+	entries, err := ps.GetEntries(personalID)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	balance := Balance{PersonalID: personalID}
+	settled := map[int]bool{}
+	for _, entry := range entries {
+		if entry.RentalID != 0 && entry.Type != TypeDeposit && entry.counts() {
+			settled[entry.RentalID] = true
+		}
+	}
+
+	for _, entry := range entries {
+		if !entry.counts() {
+			continue
+		}
+
+		switch entry.Type {
+		case TypeDeposit:
+			if !settled[entry.RentalID] {
+				balance.DepositsHeld += entry.Amount
+			}
+		case TypeCharge:
+			balance.Charges += entry.Amount
+		case TypePayment:
+			balance.Payments += entry.Amount
+		case TypeRefund:
+			balance.Refunds += entry.Amount
+		}
+	}
+
+	balance.Balance = customerBalance(entries, personalID)
+	balance.DepositsHeld = roundMoney(balance.DepositsHeld)
+	balance.Charges = roundMoney(balance.Charges)
+	balance.Payments = roundMoney(balance.Payments)
+	balance.Refunds = roundMoney(balance.Refunds)
+
+	return balance, nil
+}
+
+func (ps *PaymentStorage) CaptureDeposit(personalID int64, rentalID int) (Entry, error) {
+	if ps.depositAmount <= 0 {
+		return Entry{}, nil
+	}
+
+	return ps.appendPending(Entry{
+		PersonalID:  personalID,
+		Type:        TypeDeposit,
+		Amount:      ps.depositAmount,
+		RentalID:    rentalID,
+		Description: fmt.Sprintf("security deposit for rental %d", rentalID),
+	})
+}
+
+func (ps *PaymentStorage) SettleRental(personalID int64, rentalID int, charges float64) (Entries, error) {
+	entries, err := ps.GetEntries(personalID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pending entries are taken into account unless their outcome failed, a
+	// deposit still being captured is released once it is.
+	outcomes := entries.outcomes()
+
+	var deposit *Entry
+	released := 0.0
+	for idx, entry := range entries {
+		if entry.RentalID != rentalID || entry.Settles != 0 || resolve(outcomes, entry).Status == StatusFailed {
+			continue
+		}
+
+		if entry.Type == TypeDeposit {
+			deposit = &entries[idx]
+		}
+
+		if entry.Type == TypeDepositRelease {
+			released += entry.Amount
+		}
+	}
+
+	posted := Entries{}
+
+	if charges > 0 {
+		charge, err := ps.appendEntry(Entry{
+			PersonalID:  personalID,
+			Type:        TypeCharge,
+			Amount:      charges,
+			RentalID:    rentalID,
+			Description: fmt.Sprintf("charges for rental %d", rentalID),
+		})
+		if err != nil {
+			return posted, err
+		}
+		posted = append(posted, charge)
+	}
+
+	if deposit == nil {
+		return posted, nil
+	}
+
+	release := roundMoney(deposit.Amount - released - charges)
+	if release <= 0 {
+		return posted, nil
+	}
+
+	entry, err := ps.appendPending(Entry{
+		PersonalID:  personalID,
+		Type:        TypeDepositRelease,
+		Amount:      release,
+		RentalID:    rentalID,
+		Description: fmt.Sprintf("deposit release for rental %d", rentalID),
+	})
+	if err != nil {
+		return posted, err
+	}
+
+	return append(posted, entry), nil
+}
+
+func (ps *PaymentStorage) PostCharge(personalID int64, amount float64, description string) (Entry, error) {
+	return ps.appendEntry(Entry{
+		PersonalID:  personalID,
+		Type:        TypeCharge,
+		Amount:      amount,
+		Description: description,
+	})
+}
+
+func (ps *PaymentStorage) TakePayment(personalID int64, amount float64, description string) (Entry, error) {
+	if amount <= 0 {
+		return Entry{}, errors.New("invalid input: payment amount must be greater than zero")
+	}
+
+	return ps.appendPending(Entry{
+		PersonalID:  personalID,
+		Type:        TypePayment,
+		Amount:      amount,
+		Description: description,
+	})
+}
+
+func (ps *PaymentStorage) Refund(personalID int64, amount float64, reference, description string) (Entry, error) {
+	if amount <= 0 {
+		return Entry{}, errors.New("invalid input: refund amount must be greater than zero")
+	}
+
+	balance, err := ps.GetBalance(personalID)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entries, err := ps.GetEntries(personalID)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	// Money still on its way out is no longer refundable.
+	outcomes := entries.outcomes()
+	outgoing := 0.0
+	for _, entry := range entries {
+		if resolve(outcomes, entry).Status == StatusPending && signedAmount(entry.Type, entry.Amount) < 0 {
+			outgoing += entry.Amount
+		}
+	}
+
+	refundable := roundMoney(balance.Balance - balance.DepositsHeld - outgoing)
+	if amount > refundable {
+		return Entry{}, fmt.Errorf("refund of %.2f exceeds the refundable balance of %.2f", amount, refundable)
+	}
+
+	return ps.appendPending(Entry{
+		PersonalID:  personalID,
+		Type:        TypeRefund,
+		Amount:      amount,
+		RefundOf:    reference,
+		Description: description,
+	})
+}
+
+// appendPending records an entry whose money still has to be moved by the
+// provider. Within a transaction the provider is called once it commits, the
+// returned entry is then still pending; without one it is settled right away.
+func (ps *PaymentStorage) appendPending(input Entry) (Entry, error) {
+	input.Status = StatusPending
+
+	entry, err := ps.appendEntry(input)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if ps.tx == nil {
+		if err := ps.settle(entry.ID); err != nil {
+			return entry, err
+		}
+		return ps.Outcome(entry.ID)
+	}
+
+	committed := *ps
+	committed.storage = ps.storage.WithTx(nil)
+	committed.tx = nil
+	ps.tx.OnCommit(func() {
+		if err := committed.settle(entry.ID); err != nil {
+			slog.Error("settling payment failed, it stays pending", "entry", entry.ID, "error", err)
+		}
+	})
+
+	return entry, nil
+}
+
+// SettlePending retries the provider calls of entries left pending, by a
+// restart between commit and settlement or by a deposit release that waited
+// for its deposit. It returns how many entries were settled.
+func (ps *PaymentStorage) SettlePending() (int, error) {
+	entries := Entries{}
+	if err := ps.storage.Load(&entries); err != nil {
+		return 0, err
+	}
+
+	outcomes := entries.outcomes()
+
+	settled := 0
+	errs := []error{}
+	for _, entry := range entries {
+		if _, ok := outcomes[entry.ID]; ok || entry.Status != StatusPending {
+			continue
+		}
+
+		if err := ps.settle(entry.ID); err != nil {
+			errs = append(errs, fmt.Errorf("payment entry %d: %w", entry.ID, err))
+			continue
+		}
+		settled++
+	}
+
+	return settled, errors.Join(errs...)
+}
+
+// settle calls the provider for a pending entry and records the outcome. A
+// declined call fails the entry and is not returned as an error.
+func (ps *PaymentStorage) settle(id int) error {
+	ps.settling.Lock()
+	defer ps.settling.Unlock()
+
+	entries := Entries{}
+	if err := ps.storage.Load(&entries); err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(entries, func(entry Entry) bool { return entry.ID == id })
+	if idx == -1 {
+		return fmt.Errorf("payment entry %d not found", id)
+	}
+
+	entry := entries[idx]
+	if resolve(entries.outcomes(), entry).Status != StatusPending {
+		return nil
+	}
+
+	reference, err := ps.call(entries, entry)
+	if errors.Is(err, errDepositPending) {
+		return err
+	}
+	if err != nil {
+		slog.Warn("payment declined", "entry", entry.ID, "type", entry.Type, "personal_id", entry.PersonalID, "error", err)
+	}
+
+	return ps.record(id, reference, err)
+}
+
+func (ps *PaymentStorage) call(entries Entries, entry Entry) (string, error) {
+	key := fmt.Sprintf("entry-%d", entry.ID)
+
+	switch entry.Type {
+	case TypeDeposit, TypePayment:
+		return ps.provider.Charge(key, entry.PersonalID, entry.Amount, entry.Description)
+	case TypeDepositRelease:
+		idx := slices.IndexFunc(entries, func(deposit Entry) bool {
+			return deposit.Type == TypeDeposit && deposit.RentalID == entry.RentalID && deposit.Settles == 0
+		})
+		if idx == -1 {
+			return "", fmt.Errorf("no deposit was taken for rental %d", entry.RentalID)
+		}
+
+		deposit := resolve(entries.outcomes(), entries[idx])
+		if deposit.Status == StatusPending {
+			return "", errDepositPending
+		}
+		if deposit.Status == StatusFailed {
+			return "", fmt.Errorf("the deposit for rental %d was not taken", entry.RentalID)
+		}
+
+		return ps.provider.Refund(key, entry.PersonalID, deposit.Reference, entry.Amount)
+	case TypeRefund:
+		return ps.provider.Refund(key, entry.PersonalID, entry.RefundOf, entry.Amount)
+	}
+
+	return "", fmt.Errorf("payment entry of type %s is not sent to the provider", entry.Type)
+}
+
+// record appends the outcome of the provider call for the pending entry id.
+func (ps *PaymentStorage) record(id int, reference string, callErr error) error {
+	tx, err := ps.journal.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scoped := *ps
+	scoped.storage = ps.storage.WithTx(tx)
+
+	pending, err := scoped.GetEntry(id)
+	if err != nil {
+		return err
+	}
+
+	outcome := Entry{
+		PersonalID:  pending.PersonalID,
+		Type:        pending.Type,
+		Amount:      pending.Amount,
+		RentalID:    pending.RentalID,
+		RefundOf:    pending.RefundOf,
+		Description: pending.Description,
+		Status:      StatusCompleted,
+		Reference:   reference,
+		Settles:     id,
+	}
+	if callErr != nil {
+		outcome.Status = StatusFailed
+		outcome.Failure = callErr.Error()
+	}
+
+	if _, err := scoped.appendEntry(outcome); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,179 @@
+package payment
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+func newTestStorage(t *testing.T, depositAmount float64) (*PaymentStorage, *LocalProvider, *storage.Journal) {
+	t.Helper()
+
+	dir := t.TempDir()
+	journal := storage.NewJournal(filepath.Join(dir, "journal.json"))
+	provider := NewLocalProvider()
+	ps := NewPaymentStorage(filepath.Join(dir, "payments.json"), provider, depositAmount, journal)
+	if err := storage.EnsureStorageFile(ps.GetStorage(), Entries{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return ps, provider, journal
+}
+
+func TestRolledBackDepositIsNotCharged(t *testing.T) {
+	ps, provider, journal := newTestStorage(t, 200)
+
+	tx, err := journal.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := ps.WithTx(tx).CaptureDeposit(39001010000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != StatusPending {
+		t.Errorf("status in transaction = %q, want %q", entry.Status, StatusPending)
+	}
+
+	tx.Rollback()
+
+	if transactions := provider.Transactions(); len(transactions) != 0 {
+		t.Errorf("provider charged %v for a rolled back deposit", transactions)
+	}
+}
+
+func TestCommittedDepositIsSettled(t *testing.T) {
+	ps, provider, journal := newTestStorage(t, 200)
+
+	tx, err := journal.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := ps.WithTx(tx).CaptureDeposit(39001010000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	settled, err := ps.Outcome(entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settled.Status != StatusCompleted || settled.Reference == "" || settled.Settles != entry.ID {
+		t.Errorf("outcome = %+v, want completed with a reference", settled)
+	}
+
+	pending, err := ps.GetEntry(entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Status != StatusPending || pending.Reference != "" {
+		t.Errorf("pending entry changed to %+v", pending)
+	}
+	if transactions := provider.Transactions(); len(transactions) != 1 {
+		t.Errorf("provider has %d transactions, want 1", len(transactions))
+	}
+}
+
+func TestDeclinedPaymentFailsEntry(t *testing.T) {
+	ps, provider, _ := newTestStorage(t, 0)
+	provider.Fail = true
+
+	entry, err := ps.TakePayment(39001010000, 50, "card payment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != StatusFailed || entry.Failure == "" {
+		t.Errorf("entry = %+v, want failed with a reason", entry)
+	}
+
+	balance, err := ps.GetBalance(39001010000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Balance != 0 || balance.Payments != 0 {
+		t.Errorf("balance = %+v, a failed payment must not count", balance)
+	}
+}
+
+func TestFailedEntryKeepsRunningBalance(t *testing.T) {
+	ps, provider, _ := newTestStorage(t, 0)
+	personalID := int64(39001010000)
+
+	if _, err := ps.TakePayment(personalID, 100, "card payment"); err != nil {
+		t.Fatal(err)
+	}
+
+	provider.Fail = true
+	if _, err := ps.TakePayment(personalID, 50, "declined card payment"); err != nil {
+		t.Fatal(err)
+	}
+	provider.Fail = false
+
+	if _, err := ps.PostCharge(personalID, 30, "cleaning"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ps.GetEntries(personalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		status  string
+		balance float64
+	}{
+		{StatusPending, 0},
+		{StatusCompleted, 100},
+		{StatusPending, 100},
+		{StatusFailed, 100},
+		{StatusCompleted, 70},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, entry := range entries {
+		if entry.Status != want[i].status || entry.Balance != want[i].balance {
+			t.Errorf("entry %d = %s with balance %.2f, want %s with balance %.2f", entry.ID, entry.Status, entry.Balance, want[i].status, want[i].balance)
+		}
+	}
+
+	balance, err := ps.GetBalance(personalID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Balance != 70 || balance.Payments != 100 {
+		t.Errorf("balance = %+v, want 70 with payments of 100", balance)
+	}
+}
+
+func TestSettlePendingDoesNotChargeTwice(t *testing.T) {
+	ps, provider, _ := newTestStorage(t, 0)
+
+	entry, err := ps.appendEntry(Entry{PersonalID: 39001010000, Type: TypePayment, Amount: 50, Status: StatusPending})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider took the payment but the outcome was never recorded.
+	if _, err := provider.Charge("entry-1", entry.PersonalID, entry.Amount, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	settled, err := ps.SettlePending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settled != 1 {
+		t.Errorf("settled %d entries, want 1", settled)
+	}
+	if transactions := provider.Transactions(); len(transactions) != 1 {
+		t.Errorf("provider has %d transactions, want 1", len(transactions))
+	}
+}
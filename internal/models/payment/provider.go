@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Provider moves money for ledger entries. The key identifies the entry a call
+// is made for; a provider must treat a repeated key as the same request and
+// return the original reference, so an interrupted settlement can be retried.
+type Provider interface {
+	Charge(key string, personalID int64, amount float64, description string) (string, error)
+	Refund(key string, personalID int64, reference string, amount float64) (string, error)
+}
+
+type Transaction struct {
+	Reference  string
+	PersonalID int64
+	Amount     float64
+	Refunded   float64
+}
+
+type LocalProvider struct {
+	mu           sync.Mutex
+	transactions map[string]*Transaction
+	keys         map[string]string
+	nextID       int
+	Fail         bool
+}
+
+func NewLocalProvider() *LocalProvider {
+	return &LocalProvider{
+		transactions: map[string]*Transaction{},
+		keys:         map[string]string{},
+	}
+}
+
+func (lp *LocalProvider) Charge(key string, personalID int64, amount float64, _ string) (string, error) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	if reference, ok := lp.keys[key]; ok {
+		return reference, nil
+	}
+
+	if lp.Fail {
+		return "", errors.New("payment declined by local provider")
+	}
+
+	lp.nextID++
+	reference := fmt.Sprintf("local-ch-%d", lp.nextID)
+	lp.transactions[reference] = &Transaction{
+		Reference:  reference,
+		PersonalID: personalID,
+		Amount:     amount,
+	}
+	lp.keys[key] = reference
+
+	return reference, nil
+}
+
+func (lp *LocalProvider) Refund(key string, personalID int64, reference string, amount float64) (string, error) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	if refundReference, ok := lp.keys[key]; ok {
+		return refundReference, nil
+	}
+
+	if lp.Fail {
+		return "", errors.New("refund declined by local provider")
+	}
+
+	if transaction, ok := lp.transactions[reference]; ok {
+		if transaction.PersonalID != personalID {
+			return "", fmt.Errorf("transaction %v does not belong to customer with personalID %d", reference, personalID)
+		}
+
+		if transaction.Refunded+amount > transaction.Amount {
+			return "", fmt.Errorf("refund of %.2f exceeds the remaining amount of transaction %v", amount, reference)
+		}
+
+		transaction.Refunded += amount
+	}
+
+	lp.nextID++
+	refundReference := fmt.Sprintf("local-rf-%d", lp.nextID)
+	lp.keys[key] = refundReference
+
+	return refundReference, nil
+}
+
+func (lp *LocalProvider) Transactions() []Transaction {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	transactions := []Transaction{}
+	for _, transaction := range lp.transactions {
+		transactions = append(transactions, *transaction)
+	}
+
+	return transactions
+}
@@ -0,0 +1,182 @@
+package rental
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+// Clock returns the current time. The overdue monitor takes one so tests can
+// move time forward without waiting.
+type Clock func() time.Time
+
+type OverdueRental struct {
+	Rental
+	HoursLate float64
+}
+
+type DueBackScan struct {
+	Reminded int
+	Overdue  int
+}
+
+func subject(id int) string {
+	return fmt.Sprintf("rental/%d", id)
+}
+
+// GetOverdueRentals returns the active rentals past their due-back time at
+// the given time, most overdue first, with the late fee accrued so far.
+func (rs *RentalStorage) GetOverdueRentals(at time.Time) ([]OverdueRental, error) {
+	rentals, err := rs.GetRentals()
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := []OverdueRental{}
+	for _, rental := range rentals {
+		late := rental.Late(at)
+		if rental.ReturnedAt != nil || late <= 0 {
+			continue
+		}
+
+		rental.LateFee = rs.lateFee(rental, at)
+		overdue = append(overdue, OverdueRental{Rental: rental, HoursLate: math.Round(late.Hours()*10) / 10})
+	}
+
+	sort.SliceStable(overdue, func(i, j int) bool {
+		return overdue[i].DueBackAt.Before(*overdue[j].DueBackAt)
+	})
+
+	return overdue, nil
+}
+
+// ScanDueBack checks the active rentals against their due-back time. Rentals
+// due within reminderLead get a RentalDueSoon event once, rentals past it are
+// marked overdue with a RentalOverdue event once, and the late fee of every
+// overdue rental is brought up to date.
+func (rs *RentalStorage) ScanDueBack(now time.Time, reminderLead time.Duration) (DueBackScan, error) {
+	rentals := Rentals{}
+	if err := rs.storage.Load(&rentals); err != nil {
+		return DueBackScan{}, err
+	}
+
+	scan := DueBackScan{}
+	changed := false
+	emitted := events.Events{}
+
+	for idx := range rentals {
+		rental := &rentals[idx]
+		if rental.ReturnedAt != nil || rental.DueBackAt == nil {
+			continue
+		}
+
+		if rental.Late(now) > 0 {
+			if fee := rs.lateFee(*rental, now); fee != rental.LateFee {
+				rental.LateFee = fee
+				changed = true
+			}
+
+			if rental.OverdueAt == nil {
+				rental.OverdueAt = &now
+				scan.Overdue++
+				changed = true
+				emitted = append(emitted, events.New(events.RentalOverdue, subject(rental.ID), *rental))
+			}
+
+			continue
+		}
+
+		if reminderLead > 0 && rental.RemindedAt == nil && !now.Before(rental.DueBackAt.Add(-reminderLead)) {
+			rental.RemindedAt = &now
+			scan.Reminded++
+			changed = true
+			emitted = append(emitted, events.New(events.RentalDueSoon, subject(rental.ID), *rental))
+		}
+	}
+
+	if !changed {
+		return scan, nil
+	}
+
+	if err := rs.storage.Save(rentals); err != nil {
+		return DueBackScan{}, err
+	}
+
+	return scan, rs.events.Append(emitted...)
+}
+
+// OverdueMonitor runs ScanDueBack periodically, each scan in its own
+// journaled transaction so the rentals and their events are saved together.
+type OverdueMonitor struct {
+	rentals      *RentalStorage
+	journal      *storage.Journal
+	interval     time.Duration
+	reminderLead time.Duration
+	clock        Clock
+}
+
+func NewOverdueMonitor(rentals *RentalStorage, journal *storage.Journal, interval, reminderLead time.Duration, clock Clock) *OverdueMonitor {
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return &OverdueMonitor{
+		rentals:      rentals,
+		journal:      journal,
+		interval:     interval,
+		reminderLead: reminderLead,
+		clock:        clock,
+	}
+}
+
+// Check runs one scan at the monitor's current time.
+func (m *OverdueMonitor) Check(ctx context.Context) (DueBackScan, error) {
+	tx, err := m.journal.Begin()
+	if err != nil {
+		return DueBackScan{}, err
+	}
+	defer tx.Rollback()
+
+	scan, err := m.rentals.WithContext(ctx).WithTx(tx).ScanDueBack(m.clock(), m.reminderLead)
+	if err != nil {
+		return DueBackScan{}, err
+	}
+
+	return scan, tx.Commit()
+}
+
+func (m *OverdueMonitor) Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		logger := logging.FromContext(ctx)
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			scan, err := m.Check(ctx)
+			if err != nil {
+				logger.Error("overdue rental check failed", "error", err)
+			} else if scan.Reminded > 0 || scan.Overdue > 0 {
+				logger.Info("checked rentals due back", "reminded", scan.Reminded, "overdue", scan.Overdue)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return done
+}
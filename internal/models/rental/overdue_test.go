@@ -0,0 +1,139 @@
+package rental
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestMonitor(t *testing.T, clock *fakeClock, rentals Rentals) (*OverdueMonitor, *RentalStorage, *events.Outbox) {
+	t.Helper()
+
+	dir := t.TempDir()
+	journal := storage.NewJournal(filepath.Join(dir, "journal.json"))
+	outbox := events.NewOutbox(filepath.Join(dir, "events.json"), 100, journal)
+	rs := NewRentalStorage(filepath.Join(dir, "rentals.json"), Pricing{LateFeePerDay: 25}, outbox)
+
+	if err := storage.EnsureStorageFile(outbox.GetStorage(), events.Events{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.EnsureStorageFile(rs.GetStorage(), rentals); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewOverdueMonitor(rs, journal, time.Minute, 2*time.Hour, clock.Now), rs, outbox
+}
+
+func eventTypes(t *testing.T, outbox *events.Outbox) []string {
+	t.Helper()
+
+	found, err := outbox.Since(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := []string{}
+	for _, event := range found {
+		types = append(types, event.Type)
+	}
+
+	return types
+}
+
+func TestScanDueBack(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	dueBackAt := start.Add(3 * time.Hour)
+	clock := &fakeClock{now: start}
+
+	monitor, rs, outbox := newTestMonitor(t, clock, Rentals{
+		{ID: 1, PersonalID: 39001010000, PlateNumber: "111AAA", CheckoutAt: start.Add(-24 * time.Hour), DueBackAt: &dueBackAt},
+		{ID: 2, PersonalID: 39001010001, PlateNumber: "222BBB", CheckoutAt: start.Add(-24 * time.Hour)},
+	})
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		want    DueBackScan
+		lateFee float64
+		events  []string
+	}{
+		{name: "not due yet", want: DueBackScan{}, events: []string{}},
+		{name: "due soon", advance: 90 * time.Minute, want: DueBackScan{Reminded: 1}, events: []string{events.RentalDueSoon}},
+		{name: "no second reminder", advance: 30 * time.Minute, want: DueBackScan{}, events: []string{events.RentalDueSoon}},
+		{name: "overdue", advance: 2 * time.Hour, want: DueBackScan{Overdue: 1}, lateFee: 25, events: []string{events.RentalDueSoon, events.RentalOverdue}},
+		{name: "late fee accrues per started day", advance: 24 * time.Hour, want: DueBackScan{}, lateFee: 50, events: []string{events.RentalDueSoon, events.RentalOverdue}},
+	}
+
+	for _, step := range steps {
+		clock.Advance(step.advance)
+
+		scan, err := monitor.Check(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		if scan != step.want {
+			t.Errorf("%s: scan = %+v, want %+v", step.name, scan, step.want)
+		}
+
+		rental, err := rs.GetRental(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rental.LateFee != step.lateFee {
+			t.Errorf("%s: late fee = %.2f, want %.2f", step.name, rental.LateFee, step.lateFee)
+		}
+
+		if got := eventTypes(t, outbox); !slices.Equal(got, step.events) {
+			t.Errorf("%s: events = %v, want %v", step.name, got, step.events)
+		}
+	}
+
+	overdue, err := rs.GetOverdueRentals(clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overdue) != 1 || overdue[0].ID != 1 || overdue[0].HoursLate != 25 {
+		t.Errorf("overdue rentals = %+v, want rental 1 25 hours late", overdue)
+	}
+}
+
+func TestScanDueBackSkipsReturnedRentals(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	dueBackAt := start.Add(-time.Hour)
+	returnedAt := start.Add(-2 * time.Hour)
+	clock := &fakeClock{now: start}
+
+	monitor, _, outbox := newTestMonitor(t, clock, Rentals{
+		{ID: 1, PersonalID: 39001010000, PlateNumber: "111AAA", DueBackAt: &dueBackAt, ReturnedAt: &returnedAt},
+	})
+
+	scan, err := monitor.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scan != (DueBackScan{}) {
+		t.Errorf("scan = %+v, want nothing", scan)
+	}
+	if got := eventTypes(t, outbox); len(got) != 0 {
+		t.Errorf("events = %v, want none", got)
+	}
+}
@@ -0,0 +1,300 @@
+package rental
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+)
+
+type Rental struct {
+	ID                int
+	PersonalID        int64
+	PlateNumber       string
+	CheckoutAt        time.Time
+	CheckoutOdometer  int
+	CheckoutFuelLevel int
+	DueBackAt         *time.Time
+	RemindedAt        *time.Time
+	OverdueAt         *time.Time
+	ReturnedAt        *time.Time
+	ReturnOdometer    int
+	ReturnFuelLevel   int
+	DistanceDriven    int
+	OverageCharge     float64
+	RefuelCharge      float64
+	PickupBranchID    int
+	ReturnBranchID    int
+	OneWayFee         float64
+	LateFee           float64
+	CheckoutBy        int64
+	ReturnedBy        int64
+	RiskOverrideBy    int64
+}
+
+type Rentals []Rental
+
+type Handover struct {
+	Odometer   int
+	FuelLevel  int
+	BranchID   int
+	EmployeeID int64
+	DueBackAt  *time.Time
+	// OverrideBy is the manager who approved renting to a flagged customer.
+	OverrideBy int64
+}
+
+type Pricing struct {
+	IncludedKmPerDay    int
+	OverageFeePerKm     float64
+	RefuelFeePerPercent float64
+	OneWayFee           float64
+	LateFeePerDay       float64
+}
+
+type RentalStorage struct {
+	storage *storage.Storage[Rentals]
+	pricing Pricing
+	events  *events.Outbox
+}
+
+func NewRentalStorage(fileName string, pricing Pricing, outbox *events.Outbox) *RentalStorage {
+	return &RentalStorage{
+		storage: storage.NewStorage[Rentals](fileName),
+		pricing: pricing,
+		events:  outbox,
+	}
+}
+
+func (rs *RentalStorage) GetStorage() *storage.Storage[Rentals] {
+	return rs.storage
+}
+
+func (rs *RentalStorage) WithContext(ctx context.Context) *RentalStorage {
+	scoped := *rs
+	scoped.storage = rs.storage.WithContext(ctx)
+	scoped.events = rs.events.WithContext(ctx)
+	return &scoped
+}
+
+func (rs *RentalStorage) WithTx(tx *storage.Tx) *RentalStorage {
+	scoped := *rs
+	scoped.storage = rs.storage.WithTx(tx)
+	scoped.events = rs.events.WithTx(tx)
+	return &scoped
+}
+
+func validateHandover(handover Handover) error {
+	if handover.Odometer < 0 {
+		return errors.New("invalid input: odometer reading may not be negative")
+	}
+
+	if handover.FuelLevel < 0 || handover.FuelLevel > 100 {
+		return errors.New("invalid input: fuel or battery level must be between 0 and 100 percent")
+	}
+
+	if utils.IntLength(handover.EmployeeID) != 11 {
+		return errors.New("invalid input: personal id of the handling employee must be exactly 11 digits")
+	}
+
+	return nil
+}
+
+func findActiveRental(rentals Rentals, personalID int64, plateNumber string) int {
+	for idx, rental := range rentals {
+		if rental.PersonalID == personalID && rental.PlateNumber == plateNumber && rental.ReturnedAt == nil {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func (r Rental) TotalCharges() float64 {
+	return roundMoney(r.OverageCharge + r.RefuelCharge + r.OneWayFee + r.LateFee)
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func (rs *RentalStorage) calculateCharges(rental *Rental) {
+	days := int(math.Ceil(rental.ReturnedAt.Sub(rental.CheckoutAt).Hours() / 24))
+	if days < 1 {
+		days = 1
+	}
+
+	rental.DistanceDriven = rental.ReturnOdometer - rental.CheckoutOdometer
+
+	overageKm := rental.DistanceDriven - days*rs.pricing.IncludedKmPerDay
+	if overageKm > 0 {
+		rental.OverageCharge = roundMoney(float64(overageKm) * rs.pricing.OverageFeePerKm)
+	}
+
+	missingFuel := rental.CheckoutFuelLevel - rental.ReturnFuelLevel
+	if missingFuel > 0 {
+		rental.RefuelCharge = roundMoney(float64(missingFuel) * rs.pricing.RefuelFeePerPercent)
+	}
+
+	if rental.PickupBranchID != 0 && rental.ReturnBranchID != rental.PickupBranchID {
+		rental.OneWayFee = rs.pricing.OneWayFee
+	}
+
+	rental.LateFee = rs.lateFee(*rental, *rental.ReturnedAt)
+}
+
+// lateFee charges LateFeePerDay for every started day past the due-back
+// time.
+func (rs *RentalStorage) lateFee(rental Rental, at time.Time) float64 {
+	late := rental.Late(at)
+	if late <= 0 {
+		return 0
+	}
+
+	days := math.Ceil(late.Hours() / 24)
+	return roundMoney(days * rs.pricing.LateFeePerDay)
+}
+
+// Late is how far past its due-back time the rental is at the given time,
+// zero when it is not late or has no due-back time.
+func (r Rental) Late(at time.Time) time.Duration {
+	if r.DueBackAt == nil || !at.After(*r.DueBackAt) {
+		return 0
+	}
+
+	return at.Sub(*r.DueBackAt)
+}
+
+func (rs *RentalStorage) GetRentals() (Rentals, error) {
+	rentals := Rentals{}
+
+	if err := rs.storage.Load(&rentals); err != nil {
+		return nil, err
+	}
+
+	return rentals, nil
+}
+
+func (rs *RentalStorage) GetRental(id int) (Rental, error) {
+	rentals, err := rs.GetRentals()
+	if err != nil {
+		return Rental{}, err
+	}
+
+	for _, rental := range rentals {
+		if rental.ID == id {
+			return rental, nil
+		}
+	}
+
+	return Rental{}, fmt.Errorf("rental with ID %d not found", id)
+}
+
+// RentedPlates returns the plate numbers of the vehicles currently rented out.
+func (rs *RentalStorage) RentedPlates() (map[string]bool, error) {
+	rentals, err := rs.GetRentals()
+	if err != nil {
+		return nil, err
+	}
+
+	rented := map[string]bool{}
+	for _, rental := range rentals {
+		if rental.ReturnedAt == nil {
+			rented[rental.PlateNumber] = true
+		}
+	}
+
+	return rented, nil
+}
+
+func (rs *RentalStorage) Checkout(personalID int64, plateNumber string, handover Handover, checkoutAt time.Time) (Rental, error) {
+	rentals := Rentals{}
+	if err := rs.storage.Load(&rentals); err != nil {
+		return Rental{}, err
+	}
+
+	if err := validateHandover(handover); err != nil {
+		return Rental{}, err
+	}
+
+	if handover.DueBackAt != nil && !handover.DueBackAt.After(checkoutAt) {
+		return Rental{}, errors.New("invalid input: due-back time must be in the future")
+	}
+
+	for _, rental := range rentals {
+		if rental.PlateNumber == plateNumber && rental.ReturnedAt == nil {
+			return Rental{}, fmt.Errorf("vehicle with plateNumber %v is already rented out", plateNumber)
+		}
+	}
+
+	nextID := 1
+	for _, rental := range rentals {
+		if rental.ID >= nextID {
+			nextID = rental.ID + 1
+		}
+	}
+
+	newRental := Rental{
+		ID:                nextID,
+		PersonalID:        personalID,
+		PlateNumber:       plateNumber,
+		CheckoutAt:        checkoutAt,
+		CheckoutOdometer:  handover.Odometer,
+		CheckoutFuelLevel: handover.FuelLevel,
+		DueBackAt:         handover.DueBackAt,
+		RiskOverrideBy:    handover.OverrideBy,
+		PickupBranchID:    handover.BranchID,
+		CheckoutBy:        handover.EmployeeID,
+	}
+
+	rentals = append(rentals, newRental)
+
+	if err := rs.storage.Save(rentals); err != nil {
+		return Rental{}, err
+	}
+
+	return newRental, nil
+}
+
+func (rs *RentalStorage) CheckIn(personalID int64, plateNumber string, handover Handover, returnedAt time.Time) (Rental, error) {
+	rentals := Rentals{}
+	if err := rs.storage.Load(&rentals); err != nil {
+		return Rental{}, err
+	}
+
+	if err := validateHandover(handover); err != nil {
+		return Rental{}, err
+	}
+
+	idx := findActiveRental(rentals, personalID, plateNumber)
+	if idx == -1 {
+		return Rental{}, fmt.Errorf("no active rental of vehicle %v found for customer with personalID %d", plateNumber, personalID)
+	}
+
+	rental := &rentals[idx]
+
+	if handover.Odometer < rental.CheckoutOdometer {
+		return Rental{}, fmt.Errorf("invalid input: return odometer %d may not be lower than checkout odometer %d", handover.Odometer, rental.CheckoutOdometer)
+	}
+
+	rental.ReturnedAt = &returnedAt
+	rental.ReturnOdometer = handover.Odometer
+	rental.ReturnFuelLevel = handover.FuelLevel
+	rental.ReturnedBy = handover.EmployeeID
+	rental.ReturnBranchID = handover.BranchID
+	if handover.BranchID == 0 {
+		rental.ReturnBranchID = rental.PickupBranchID
+	}
+	rs.calculateCharges(rental)
+
+	if err := rs.storage.Save(rentals); err != nil {
+		return Rental{}, err
+	}
+
+	return *rental, nil
+}
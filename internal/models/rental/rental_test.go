@@ -0,0 +1,125 @@
+package rental
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const (
+	testCustomerID = 39001010000
+	testEmployeeID = 48001010000
+)
+
+func newTestStorage(t *testing.T, pricing Pricing) *RentalStorage {
+	t.Helper()
+
+	rs := NewRentalStorage(filepath.Join(t.TempDir(), "rentals.json"), pricing, nil)
+	if err := storage.EnsureStorageFile(rs.GetStorage(), Rentals{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return rs
+}
+
+func TestCheckInChargesLateFeeAtGivenTime(t *testing.T) {
+	rs := newTestStorage(t, Pricing{LateFeePerDay: 25})
+
+	checkoutAt := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+	dueBackAt := checkoutAt.Add(48 * time.Hour)
+	if _, err := rs.Checkout(testCustomerID, "123ABC", Handover{FuelLevel: 100, EmployeeID: testEmployeeID, DueBackAt: &dueBackAt}, checkoutAt); err != nil {
+		t.Fatal(err)
+	}
+
+	returnedAt := dueBackAt.Add(30 * time.Hour)
+	returned, err := rs.CheckIn(testCustomerID, "123ABC", Handover{FuelLevel: 100, EmployeeID: testEmployeeID}, returnedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !returned.CheckoutAt.Equal(checkoutAt) || !returned.ReturnedAt.Equal(returnedAt) {
+		t.Errorf("rental ran from %s to %s, want %s to %s", returned.CheckoutAt, returned.ReturnedAt, checkoutAt, returnedAt)
+	}
+	if returned.LateFee != 50 {
+		t.Errorf("late fee = %.2f, want 50 for two started days", returned.LateFee)
+	}
+}
+
+func TestCheckoutRejectsDueBackBeforeCheckout(t *testing.T) {
+	rs := newTestStorage(t, Pricing{})
+
+	checkoutAt := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+	dueBackAt := checkoutAt.Add(-time.Hour)
+	if _, err := rs.Checkout(testCustomerID, "123ABC", Handover{EmployeeID: testEmployeeID, DueBackAt: &dueBackAt}, checkoutAt); err == nil {
+		t.Fatal("expected an error for a due-back time before checkout")
+	}
+}
+
+func TestCheckInCharges(t *testing.T) {
+	pricing := Pricing{IncludedKmPerDay: 100, OverageFeePerKm: 0.5, RefuelFeePerPercent: 2}
+	checkoutAt := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		rentedFor    time.Duration
+		odometer     int
+		fuelLevel    int
+		wantDistance int
+		wantOverage  float64
+		wantRefuel   float64
+	}{
+		{"within the allowance", 48 * time.Hour, 1150, 80, 150, 0, 0},
+		{"overage above the allowance", 48 * time.Hour, 1300, 80, 300, 50, 0},
+		{"started day adds to the allowance", 49 * time.Hour, 1300, 80, 300, 0, 0},
+		{"short rental gets a full day", time.Hour, 1120, 80, 120, 10, 0},
+		{"refuel below checkout fuel", 48 * time.Hour, 1100, 60, 100, 0, 40},
+		{"fuller tank is not credited", 48 * time.Hour, 1100, 100, 100, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rs := newTestStorage(t, pricing)
+
+			if _, err := rs.Checkout(testCustomerID, "123ABC", Handover{Odometer: 1000, FuelLevel: 80, EmployeeID: testEmployeeID}, checkoutAt); err != nil {
+				t.Fatal(err)
+			}
+
+			returned, err := rs.CheckIn(testCustomerID, "123ABC", Handover{Odometer: test.odometer, FuelLevel: test.fuelLevel, EmployeeID: testEmployeeID}, checkoutAt.Add(test.rentedFor))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if returned.DistanceDriven != test.wantDistance || returned.OverageCharge != test.wantOverage || returned.RefuelCharge != test.wantRefuel {
+				t.Errorf("distance %d, overage %.2f, refuel %.2f, want %d, %.2f, %.2f",
+					returned.DistanceDriven, returned.OverageCharge, returned.RefuelCharge,
+					test.wantDistance, test.wantOverage, test.wantRefuel)
+			}
+			if total := test.wantOverage + test.wantRefuel; returned.TotalCharges() != total {
+				t.Errorf("total = %.2f, want %.2f", returned.TotalCharges(), total)
+			}
+		})
+	}
+}
+
+func TestCheckInRejectsOdometerBelowCheckout(t *testing.T) {
+	rs := newTestStorage(t, Pricing{})
+
+	checkoutAt := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+	if _, err := rs.Checkout(testCustomerID, "123ABC", Handover{Odometer: 1000, FuelLevel: 80, EmployeeID: testEmployeeID}, checkoutAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rs.CheckIn(testCustomerID, "123ABC", Handover{Odometer: 999, FuelLevel: 80, EmployeeID: testEmployeeID}, checkoutAt.Add(time.Hour)); err == nil {
+		t.Fatal("expected an error for a return odometer below the checkout reading")
+	}
+
+	rented, err := rs.RentedPlates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rented["123ABC"] {
+		t.Error("rejected check-in returned the vehicle")
+	}
+}
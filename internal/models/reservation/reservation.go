@@ -0,0 +1,416 @@
+package reservation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+)
+
+const (
+	StatusActive    = "active"
+	StatusExpired   = "expired"
+	StatusConverted = "converted"
+	StatusCancelled = "cancelled"
+)
+
+type Reservation struct {
+	ID             int
+	PersonalID     int64
+	VehicleClass   string
+	PlateNumber    string
+	PickupAt       time.Time
+	ReturnAt       time.Time
+	PickupLocation string
+	ReturnLocation string
+	PickupBranchID int
+	ReturnBranchID int
+	Status         string
+	RentalID       int
+	CreatedAt      time.Time
+	ClosedAt       *time.Time
+}
+
+type Reservations []Reservation
+
+type ReservationStorage struct {
+	storage     *storage.Storage[Reservations]
+	gracePeriod time.Duration
+	events      *events.Outbox
+}
+
+func NewReservationStorage(fileName string, gracePeriod time.Duration, outbox *events.Outbox) *ReservationStorage {
+	return &ReservationStorage{
+		storage:     storage.NewStorage[Reservations](fileName),
+		gracePeriod: gracePeriod,
+		events:      outbox,
+	}
+}
+
+func (rs *ReservationStorage) GetStorage() *storage.Storage[Reservations] {
+	return rs.storage
+}
+
+func (rs *ReservationStorage) WithContext(ctx context.Context) *ReservationStorage {
+	scoped := *rs
+	scoped.storage = rs.storage.WithContext(ctx)
+	scoped.events = rs.events.WithContext(ctx)
+	return &scoped
+}
+
+func (rs *ReservationStorage) WithTx(tx *storage.Tx) *ReservationStorage {
+	scoped := *rs
+	scoped.storage = rs.storage.WithTx(tx)
+	scoped.events = rs.events.WithTx(tx)
+	return &scoped
+}
+
+func (r Reservation) overlaps(pickupAt, returnAt time.Time) bool {
+	return r.PickupAt.Before(returnAt) && pickupAt.Before(r.ReturnAt)
+}
+
+func (r Reservation) Matches(v vehicle.Vehicle) bool {
+	if r.PlateNumber != "" {
+		return r.PlateNumber == v.PlateNumber
+	}
+
+	return strings.EqualFold(r.VehicleClass, v.Body)
+}
+
+func (rs *ReservationStorage) validateReservation(input Reservation, fleet vehicle.Vehicles) error {
+	if utils.IntLength(input.PersonalID) != 11 {
+		return errors.New("invalid input: personal id of the customer must be exactly 11 digits")
+	}
+
+	if input.PlateNumber == "" && input.VehicleClass == "" {
+		return errors.New("invalid input: reservation requires either a vehicle class or a plate number")
+	}
+
+	if input.PlateNumber != "" {
+		if _, ok := fleet[input.PlateNumber]; !ok {
+			return fmt.Errorf("vehicle with plateNumber %v not found", input.PlateNumber)
+		}
+	}
+
+	if input.PickupAt.IsZero() || input.ReturnAt.IsZero() {
+		return errors.New("invalid input: pickup and return time may not be empty")
+	}
+
+	if !input.ReturnAt.After(input.PickupAt) {
+		return errors.New("invalid input: return time must be after pickup time")
+	}
+
+	if input.PickupAt.Add(rs.gracePeriod).Before(time.Now()) {
+		return errors.New("invalid input: pickup time may not be in the past")
+	}
+
+	if input.PickupLocation == "" || input.ReturnLocation == "" {
+		return errors.New("invalid input: pickup and return location may not be empty")
+	}
+
+	return nil
+}
+
+func (rs *ReservationStorage) GetReservations() (Reservations, error) {
+	reservations := Reservations{}
+
+	if err := rs.storage.Load(&reservations); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+func (rs *ReservationStorage) GetReservation(id int) (Reservation, error) {
+	reservations, err := rs.GetReservations()
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	for _, reservation := range reservations {
+		if reservation.ID == id {
+			return reservation, nil
+		}
+	}
+
+	return Reservation{}, fmt.Errorf("reservation with ID %d not found", id)
+}
+
+func isAvailable(reservations Reservations, fleet vehicle.Vehicles, input Reservation) bool {
+	vehicleClass := input.VehicleClass
+	if input.PlateNumber != "" {
+		vehicleClass = fleet[input.PlateNumber].Body
+	}
+
+	available := 0
+	for _, v := range fleet {
+		if strings.EqualFold(v.Body, vehicleClass) {
+			available++
+		}
+	}
+
+	for _, reservation := range reservations {
+		if reservation.Status != StatusActive || !reservation.overlaps(input.PickupAt, input.ReturnAt) {
+			continue
+		}
+
+		if input.PlateNumber != "" && reservation.PlateNumber == input.PlateNumber {
+			return false
+		}
+
+		if strings.EqualFold(reservation.VehicleClass, vehicleClass) {
+			available--
+		}
+	}
+
+	return available > 0
+}
+
+func (rs *ReservationStorage) AddReservation(input Reservation, fleet vehicle.Vehicles) (Reservation, error) {
+	reservations := Reservations{}
+	if err := rs.storage.Load(&reservations); err != nil {
+		return Reservation{}, err
+	}
+
+	if err := rs.validateReservation(input, fleet); err != nil {
+		return Reservation{}, err
+	}
+
+	if input.PlateNumber == "" {
+		input.VehicleClass = cases.Title(language.English).String(input.VehicleClass)
+	}
+
+	if !isAvailable(reservations, fleet, input) {
+		return Reservation{}, errors.New("no vehicle available for the requested class or plate number in this period")
+	}
+
+	nextID := 1
+	for _, reservation := range reservations {
+		if reservation.ID >= nextID {
+			nextID = reservation.ID + 1
+		}
+	}
+
+	newReservation := Reservation{
+		ID:             nextID,
+		PersonalID:     input.PersonalID,
+		VehicleClass:   input.VehicleClass,
+		PlateNumber:    input.PlateNumber,
+		PickupAt:       input.PickupAt,
+		ReturnAt:       input.ReturnAt,
+		PickupLocation: input.PickupLocation,
+		ReturnLocation: input.ReturnLocation,
+		PickupBranchID: input.PickupBranchID,
+		ReturnBranchID: input.ReturnBranchID,
+		Status:         StatusActive,
+		CreatedAt:      time.Now(),
+	}
+
+	if newReservation.PlateNumber != "" {
+		newReservation.VehicleClass = fleet[newReservation.PlateNumber].Body
+	}
+
+	reservations = append(reservations, newReservation)
+
+	if err := rs.storage.Save(reservations); err != nil {
+		return Reservation{}, err
+	}
+
+	return newReservation, nil
+}
+
+func (rs *ReservationStorage) closeReservation(id int, status string, rentalID int) (Reservation, error) {
+	reservations := Reservations{}
+	if err := rs.storage.Load(&reservations); err != nil {
+		return Reservation{}, err
+	}
+
+	for idx, reservation := range reservations {
+		if reservation.ID != id {
+			continue
+		}
+
+		if reservation.Status != StatusActive {
+			return Reservation{}, fmt.Errorf("reservation with ID %d is %s", id, reservation.Status)
+		}
+
+		closedAt := time.Now()
+		reservations[idx].Status = status
+		reservations[idx].RentalID = rentalID
+		reservations[idx].ClosedAt = &closedAt
+
+		if err := rs.storage.Save(reservations); err != nil {
+			return Reservation{}, err
+		}
+
+		return reservations[idx], nil
+	}
+
+	return Reservation{}, fmt.Errorf("reservation with ID %d not found", id)
+}
+
+func (rs *ReservationStorage) CancelReservation(id int) (Reservation, error) {
+	return rs.closeReservation(id, StatusCancelled, 0)
+}
+
+func (rs *ReservationStorage) ConvertReservation(id, rentalID int) (Reservation, error) {
+	return rs.closeReservation(id, StatusConverted, rentalID)
+}
+
+// PickupDeadline is the latest time the reservation can be picked up.
+func (rs *ReservationStorage) PickupDeadline(reservation Reservation) time.Time {
+	return reservation.PickupAt.Add(rs.gracePeriod)
+}
+
+// Expired tells whether the reservation was not picked up within the grace
+// period, whether or not the expiry worker has closed it yet.
+func (rs *ReservationStorage) Expired(reservation Reservation, at time.Time) bool {
+	return at.After(rs.PickupDeadline(reservation))
+}
+
+// holds tells whether an active reservation claims a vehicle at the given time.
+func (rs *ReservationStorage) holds(reservation Reservation, at time.Time) bool {
+	if reservation.Status != StatusActive || rs.Expired(reservation, at) {
+		return false
+	}
+
+	return !at.Before(reservation.PickupAt.Add(-rs.gracePeriod)) && at.Before(reservation.ReturnAt)
+}
+
+// IsHeldForOthers tells whether handing v to the customer at the given time
+// would take a vehicle reserved by someone else: v itself, or the last free
+// vehicle of a class other customers reserved. rented lists the plate numbers
+// currently rented out, which cannot serve a class reservation.
+func (rs *ReservationStorage) IsHeldForOthers(v vehicle.Vehicle, personalID int64, at time.Time, fleet vehicle.Vehicles, rented map[string]bool) (bool, error) {
+	reservations, err := rs.GetReservations()
+	if err != nil {
+		return false, err
+	}
+
+	heldPlates := map[string]bool{}
+	heldForClass := 0
+	for _, reservation := range reservations {
+		if reservation.PersonalID == personalID || !rs.holds(reservation, at) {
+			continue
+		}
+
+		if reservation.PlateNumber != "" {
+			if reservation.PlateNumber == v.PlateNumber {
+				return true, nil
+			}
+			heldPlates[reservation.PlateNumber] = true
+			continue
+		}
+
+		if strings.EqualFold(reservation.VehicleClass, v.Body) {
+			heldForClass++
+		}
+	}
+
+	if heldForClass == 0 {
+		return false, nil
+	}
+
+	free := 0
+	for plateNumber, candidate := range fleet {
+		if plateNumber == v.PlateNumber || rented[plateNumber] || heldPlates[plateNumber] || !strings.EqualFold(candidate.Body, v.Body) {
+			continue
+		}
+		free++
+	}
+
+	return free < heldForClass, nil
+}
+
+func subject(id int) string {
+	return fmt.Sprintf("reservation/%d", id)
+}
+
+// ExpireReservations expires the active reservations not picked up within the
+// grace period, with a ReservationExpired event for each. Run it in a
+// transaction so a pickup committed meanwhile is not overwritten.
+func (rs *ReservationStorage) ExpireReservations(now time.Time) (int, error) {
+	reservations := Reservations{}
+	if err := rs.storage.Load(&reservations); err != nil {
+		return 0, err
+	}
+
+	expired := events.Events{}
+	for idx, reservation := range reservations {
+		if reservation.Status == StatusActive && rs.Expired(reservation, now) {
+			reservations[idx].Status = StatusExpired
+			reservations[idx].ClosedAt = &now
+			expired = append(expired, events.New(events.ReservationExpired, subject(reservation.ID), reservations[idx]))
+		}
+	}
+
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	if err := rs.storage.Save(reservations); err != nil {
+		return 0, err
+	}
+
+	return len(expired), rs.events.Append(expired...)
+}
+
+func (rs *ReservationStorage) expire(journal *storage.Journal, now time.Time) (int, error) {
+	tx, err := journal.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expired, err := rs.WithTx(tx).ExpireReservations(now)
+	if err != nil {
+		return 0, err
+	}
+
+	return expired, tx.Commit()
+}
+
+// StartExpiryWorker expires reservations every interval, each pass in its own
+// journaled transaction.
+func (rs *ReservationStorage) StartExpiryWorker(ctx context.Context, journal *storage.Journal, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		logger := logging.FromContext(ctx)
+		scoped := rs.WithContext(ctx)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				expired, err := scoped.expire(journal, now)
+				if err != nil {
+					logger.Error("reservation expiry failed", "error", err)
+					continue
+				}
+
+				if expired > 0 {
+					logger.Info("expired reservations", "count", expired)
+				}
+			}
+		}
+	}()
+
+	return done
+}
@@ -0,0 +1,99 @@
+package reservation
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+func TestIsHeldForOthers(t *testing.T) {
+	now := time.Now()
+	fleet := vehicle.Vehicles{
+		"111AAA": {PlateNumber: "111AAA", Body: "Sedan"},
+		"222BBB": {PlateNumber: "222BBB", Body: "Sedan"},
+		"333CCC": {PlateNumber: "333CCC", Body: "Wagon"},
+	}
+
+	active := func(r Reservation) Reservation {
+		r.Status = StatusActive
+		r.PickupAt = now.Add(-time.Minute)
+		r.ReturnAt = now.Add(24 * time.Hour)
+		return r
+	}
+
+	tests := []struct {
+		name         string
+		reservations Reservations
+		plateNumber  string
+		rented       map[string]bool
+		want         bool
+	}{
+		{
+			name:         "plate reserved by another customer",
+			reservations: Reservations{active(Reservation{ID: 1, PersonalID: 2, PlateNumber: "111AAA", VehicleClass: "Sedan"})},
+			plateNumber:  "111AAA",
+			want:         true,
+		},
+		{
+			name:         "plate reserved by the renter",
+			reservations: Reservations{active(Reservation{ID: 1, PersonalID: 1, PlateNumber: "111AAA", VehicleClass: "Sedan"})},
+			plateNumber:  "111AAA",
+		},
+		{
+			name:         "class reservation leaves another vehicle free",
+			reservations: Reservations{active(Reservation{ID: 1, PersonalID: 2, VehicleClass: "Sedan"})},
+			plateNumber:  "111AAA",
+		},
+		{
+			name:         "class reservation needs the last free vehicle",
+			reservations: Reservations{active(Reservation{ID: 1, PersonalID: 2, VehicleClass: "Sedan"})},
+			plateNumber:  "111AAA",
+			rented:       map[string]bool{"222BBB": true},
+			want:         true,
+		},
+		{
+			name: "plate reservation takes the other vehicle of the class",
+			reservations: Reservations{
+				active(Reservation{ID: 1, PersonalID: 2, VehicleClass: "Sedan"}),
+				active(Reservation{ID: 2, PersonalID: 3, PlateNumber: "222BBB", VehicleClass: "Sedan"}),
+			},
+			plateNumber: "111AAA",
+			want:        true,
+		},
+		{
+			name:         "class reservation of another class",
+			reservations: Reservations{active(Reservation{ID: 1, PersonalID: 2, VehicleClass: "Wagon"})},
+			plateNumber:  "111AAA",
+			rented:       map[string]bool{"222BBB": true},
+		},
+		{
+			name: "reservation past its pickup deadline",
+			reservations: Reservations{{
+				ID: 1, PersonalID: 2, PlateNumber: "111AAA", VehicleClass: "Sedan", Status: StatusActive,
+				PickupAt: now.Add(-2 * time.Hour), ReturnAt: now.Add(time.Hour),
+			}},
+			plateNumber: "111AAA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := NewReservationStorage(filepath.Join(t.TempDir(), "reservations.json"), time.Hour, nil)
+			if err := storage.EnsureStorageFile(rs.GetStorage(), tt.reservations); err != nil {
+				t.Fatal(err)
+			}
+
+			held, err := rs.IsHeldForOthers(fleet[tt.plateNumber], 1, now, fleet, tt.rented)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if held != tt.want {
+				t.Errorf("held = %v, want %v", held, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package shift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+	"github.com/ZulfiPy/RWAPIGo/internal/utils"
+)
+
+type Shift struct {
+	ID         int
+	PersonalID int64
+	BranchID   int
+	StartsAt   time.Time
+	EndsAt     time.Time
+	CreatedAt  time.Time
+}
+
+type Shifts []Shift
+
+type ShiftStorage struct {
+	storage *storage.Storage[Shifts]
+}
+
+func NewShiftStorage(fileName string) *ShiftStorage {
+	return &ShiftStorage{
+		storage: storage.NewStorage[Shifts](fileName),
+	}
+}
+
+func (ss *ShiftStorage) GetStorage() *storage.Storage[Shifts] {
+	return ss.storage
+}
+
+func (ss *ShiftStorage) WithContext(ctx context.Context) *ShiftStorage {
+	scoped := *ss
+	scoped.storage = ss.storage.WithContext(ctx)
+	return &scoped
+}
+
+func (ss *ShiftStorage) WithTx(tx *storage.Tx) *ShiftStorage {
+	scoped := *ss
+	scoped.storage = ss.storage.WithTx(tx)
+	return &scoped
+}
+
+func (s Shift) Hours() float64 {
+	return s.EndsAt.Sub(s.StartsAt).Hours()
+}
+
+func (ss *ShiftStorage) validateInput(input Shift) error {
+	if utils.IntLength(input.PersonalID) != 11 {
+		return errors.New("invalid input: personal id of the employee must be exactly 11 digits")
+	}
+
+	if input.BranchID == 0 {
+		return errors.New("invalid input: shift branch may not be empty")
+	}
+
+	if input.StartsAt.IsZero() || input.EndsAt.IsZero() {
+		return errors.New("invalid input: shift start and end time may not be empty")
+	}
+
+	if !input.EndsAt.After(input.StartsAt) {
+		return errors.New("invalid input: shift end time must be after its start time")
+	}
+
+	if input.Hours() > 24 {
+		return errors.New("invalid input: shift may not be longer than 24 hours")
+	}
+
+	return nil
+}
+
+func (ss *ShiftStorage) GetShifts(personalID int64, from, to time.Time) (Shifts, error) {
+	shifts := Shifts{}
+	if err := ss.storage.Load(&shifts); err != nil {
+		return nil, err
+	}
+
+	employeeShifts := Shifts{}
+	for _, shift := range shifts {
+		if shift.PersonalID != personalID {
+			continue
+		}
+
+		if !from.IsZero() && shift.EndsAt.Before(from) {
+			continue
+		}
+
+		if !to.IsZero() && shift.StartsAt.After(to) {
+			continue
+		}
+
+		employeeShifts = append(employeeShifts, shift)
+	}
+
+	return employeeShifts, nil
+}
+
+func (ss *ShiftStorage) GetShift(personalID int64, id int) (Shift, error) {
+	shifts, err := ss.GetShifts(personalID, time.Time{}, time.Time{})
+	if err != nil {
+		return Shift{}, err
+	}
+
+	for _, shift := range shifts {
+		if shift.ID == id {
+			return shift, nil
+		}
+	}
+
+	return Shift{}, fmt.Errorf("shift %d of employee with personalID %d not found", id, personalID)
+}
+
+func (ss *ShiftStorage) AddShift(input Shift) (Shift, error) {
+	shifts := Shifts{}
+	if err := ss.storage.Load(&shifts); err != nil {
+		return Shift{}, err
+	}
+
+	if err := ss.validateInput(input); err != nil {
+		return Shift{}, err
+	}
+
+	nextID := 1
+	for _, shift := range shifts {
+		if shift.ID >= nextID {
+			nextID = shift.ID + 1
+		}
+
+		if shift.PersonalID == input.PersonalID && shift.StartsAt.Before(input.EndsAt) && input.StartsAt.Before(shift.EndsAt) {
+			return Shift{}, fmt.Errorf("shift overlaps with existing shift %d of the employee", shift.ID)
+		}
+	}
+
+	newShift := Shift{
+		ID:         nextID,
+		PersonalID: input.PersonalID,
+		BranchID:   input.BranchID,
+		StartsAt:   input.StartsAt,
+		EndsAt:     input.EndsAt,
+		CreatedAt:  time.Now(),
+	}
+
+	shifts = append(shifts, newShift)
+
+	if err := ss.storage.Save(shifts); err != nil {
+		return Shift{}, err
+	}
+
+	return newShift, nil
+}
+
+func (ss *ShiftStorage) DeleteShift(personalID int64, id int) error {
+	shifts := Shifts{}
+	if err := ss.storage.Load(&shifts); err != nil {
+		return err
+	}
+
+	for idx, shift := range shifts {
+		if shift.ID == id && shift.PersonalID == personalID {
+			shifts = append(shifts[:idx], shifts[idx+1:]...)
+
+			if err := ss.storage.Save(shifts); err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("shift %d of employee with personalID %d not found", id, personalID)
+}
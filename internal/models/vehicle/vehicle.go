@@ -1,15 +1,18 @@
 package vehicle
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"slices"
+	"strings"
 	"time"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
 	"github.com/ZulfiPy/RWAPIGo/internal/storage"
 )
 
@@ -22,17 +25,51 @@ type Vehicle struct {
 	Gearbox     string
 	Color       string
 	Body        string
+	BranchID    int
 }
 
 type Vehicles map[string]Vehicle
 
+type Catalogue struct {
+	FuelTypes []string
+	Gearboxes []string
+	Colors    []string
+	Bodies    []string
+	MinYear   int
+}
+
 type VehicleStorage struct {
-	storage *storage.Storage[Vehicles]
+	storage   *storage.Storage[Vehicles]
+	catalogue Catalogue
+	events    *events.Outbox
 }
 
-func NewVehicleStorage(fileName string) *VehicleStorage {
+// titled returns the catalogue with its values in title case, the case
+// inputs are matched in.
+func (c Catalogue) titled() Catalogue {
+	caser := cases.Title(language.English)
+	title := func(values []string) []string {
+		titled := make([]string, len(values))
+		for idx, value := range values {
+			titled[idx] = caser.String(value)
+		}
+		return titled
+	}
+
+	return Catalogue{
+		FuelTypes: title(c.FuelTypes),
+		Gearboxes: title(c.Gearboxes),
+		Colors:    title(c.Colors),
+		Bodies:    title(c.Bodies),
+		MinYear:   c.MinYear,
+	}
+}
+
+func NewVehicleStorage(fileName string, catalogue Catalogue, outbox *events.Outbox) *VehicleStorage {
 	return &VehicleStorage{
-		storage: storage.NewStorage[Vehicles](fileName),
+		storage:   storage.NewStorage[Vehicles](fileName),
+		catalogue: catalogue.titled(),
+		events:    outbox,
 	}
 }
 
@@ -40,12 +77,25 @@ func (vs *VehicleStorage) GetStorage() *storage.Storage[Vehicles] {
 	return vs.storage
 }
 
-func (vs *VehicleStorage) validateVehicle(input Vehicle) error {
-	var fuelType = []string{"Petrol", "Diesel", "Hybrid", "Electric", "Lpg", "Cng"}
-	var gearbox = []string{"Automatic", "Manual"}
-	var colors = []string{"White", "Black", "Red", "Blue", "Green", "Yellow", "Gray", "Silver", "Brown"}
-	var bodies = []string{"Sedan", "Touring", "Hatchback", "Minivan", "Coupe", "Cabriolet", "Pickup", "Limousine"}
+func (vs *VehicleStorage) WithContext(ctx context.Context) *VehicleStorage {
+	scoped := *vs
+	scoped.storage = vs.storage.WithContext(ctx)
+	scoped.events = vs.events.WithContext(ctx)
+	return &scoped
+}
+
+func (vs *VehicleStorage) WithTx(tx *storage.Tx) *VehicleStorage {
+	scoped := *vs
+	scoped.storage = vs.storage.WithTx(tx)
+	scoped.events = vs.events.WithTx(tx)
+	return &scoped
+}
 
+func subject(plateNumber string) string {
+	return "vehicle/" + plateNumber
+}
+
+func (vs *VehicleStorage) validateVehicle(input Vehicle) error {
 	caser := cases.Title(language.English)
 
 	if input.PlateNumber == "" {
@@ -60,31 +110,31 @@ func (vs *VehicleStorage) validateVehicle(input Vehicle) error {
 		return errors.New("invalid input: vehicle model may not be empty")
 	}
 
-	if input.Year < 2010 || input.Year > time.Now().Year() {
-		return errors.New("invalid input: vehicle year may not be lower than 2010 or greater than the current year")
+	if input.Year < vs.catalogue.MinYear || input.Year > time.Now().Year() {
+		return fmt.Errorf("invalid input: vehicle year may not be lower than %d or greater than the current year", vs.catalogue.MinYear)
 	}
 
 	if input.FuelType == "" {
 		return errors.New("invalid input: vehicle fuel type may not be empty")
 	}
 
-	if !(slices.Contains(fuelType, caser.String(input.FuelType))) {
-		return errors.New("invalid input: vehicle fuel type may only be (Petrol / Diesel / Hybrid / Electric / LPG / CNG)")
+	if !(slices.Contains(vs.catalogue.FuelTypes, caser.String(input.FuelType))) {
+		return fmt.Errorf("invalid input: vehicle fuel type may only be (%s)", strings.Join(vs.catalogue.FuelTypes, " / "))
 	}
 
 	if input.Gearbox == "" {
 		return errors.New("invalid input: vehicle gearbox may not be empty")
 	}
 
-	if !(slices.Contains(gearbox, caser.String(input.Gearbox))) {
-		return errors.New("invalid input: vehicle gearbox may only be (Automatic or Manual)")
+	if !(slices.Contains(vs.catalogue.Gearboxes, caser.String(input.Gearbox))) {
+		return fmt.Errorf("invalid input: vehicle gearbox may only be (%s)", strings.Join(vs.catalogue.Gearboxes, " / "))
 	}
 
 	if input.Color == "" {
 		return errors.New("invalid input: vehicle color may not be empty")
 	}
 
-	if !(slices.Contains(colors, caser.String(input.Color))) {
+	if !(slices.Contains(vs.catalogue.Colors, caser.String(input.Color))) {
 		return errors.New("invalid input: wrong vehicle color")
 	}
 
@@ -92,23 +142,24 @@ func (vs *VehicleStorage) validateVehicle(input Vehicle) error {
 		return errors.New("invalid input: vehicle body may not be empty")
 	}
 
-	if !(slices.Contains(bodies, caser.String(input.Body))) {
+	if !(slices.Contains(vs.catalogue.Bodies, caser.String(input.Body))) {
 		return errors.New("invalid input: wrong vehicle body")
 	}
 
 	return nil
 }
 
-func (vs *VehicleStorage) GetVehicle(plateNumber string) error {
+func (vs *VehicleStorage) GetVehicle(plateNumber string) (Vehicle, error) {
 	vehicles := Vehicles{}
 	if err := vs.storage.Load(&vehicles); err != nil {
-		return err
+		return Vehicle{}, err
 	}
 
-	if _, ok := vehicles[plateNumber]; !ok {
-		return fmt.Errorf("vehicle with plateNumber %v not found", plateNumber)
+	vehicle, ok := vehicles[plateNumber]
+	if !ok {
+		return Vehicle{}, fmt.Errorf("vehicle with plateNumber %v not found", plateNumber)
 	}
-	return nil
+	return vehicle, nil
 }
 
 func (vs *VehicleStorage) GetVehicles() (Vehicles, error) {
@@ -122,50 +173,72 @@ func (vs *VehicleStorage) GetVehicles() (Vehicles, error) {
 }
 
 func (vs *VehicleStorage) AddVehicle(input Vehicle) (Vehicle, error) {
-	vehicles := Vehicles{}
-	vs.storage.Load(&vehicles)
-
-	if err := vs.storage.Load(&vehicles); err != nil {
+	batch, err := vs.Begin()
+	if err != nil {
 		return Vehicle{}, err
 	}
 
-	if err := vs.validateVehicle(input); err != nil {
+	vehicle, err := batch.AddVehicle(input)
+	if err != nil {
 		return Vehicle{}, err
 	}
 
-	_, ok := vehicles[input.PlateNumber]
+	return vehicle, batch.Commit()
+}
 
-	if ok {
-		return Vehicle{}, fmt.Errorf("vehiche with plate number %v is already in the storage", input.PlateNumber)
+// ImportVehicles adds the valid inputs and reports an error for every other
+// one. commit decides from the number of failed inputs whether the valid ones
+// are saved.
+func (vs *VehicleStorage) ImportVehicles(inputs []Vehicle, commit func(failed int) bool, check func(Vehicle) error) ([]error, error) {
+	vehicles := Vehicles{}
+	if err := vs.storage.Load(&vehicles); err != nil {
+		return nil, err
 	}
 
-	vehicles[input.PlateNumber] = input
+	rowErrors := make([]error, len(inputs))
+	failed := 0
+	created := events.Events{}
+	for idx, input := range inputs {
+		err := vs.validateVehicle(input)
+		if err == nil && check != nil {
+			err = check(input)
+		}
+		if _, ok := vehicles[input.PlateNumber]; err == nil && ok {
+			err = fmt.Errorf("vehicle with plate number %v is already in the storage", input.PlateNumber)
+		}
+
+		if err != nil {
+			rowErrors[idx] = err
+			failed++
+			continue
+		}
+
+		vehicles[input.PlateNumber] = input
+		created = append(created, events.New(events.VehicleCreated, subject(input.PlateNumber), input))
+	}
+
+	if !commit(failed) {
+		return rowErrors, nil
+	}
 
 	if err := vs.storage.Save(vehicles); err != nil {
-		return Vehicle{}, err
+		return rowErrors, err
 	}
 
-	return input, nil
+	return rowErrors, vs.events.Append(created...)
 }
 
 func (vs *VehicleStorage) DeleteVehicle(plateNumber string) error {
-	vehicles := Vehicles{}
-
-	if err := vs.storage.Load(&vehicles); err != nil {
+	batch, err := vs.Begin()
+	if err != nil {
 		return err
 	}
 
-	if _, ok := vehicles[plateNumber]; !ok {
-		return fmt.Errorf("vehicle with plate number %v not found in the storage", plateNumber)
-	}
-
-	delete(vehicles, plateNumber)
-
-	if err := vs.storage.Save(vehicles); err != nil {
+	if err := batch.DeleteVehicle(plateNumber); err != nil {
 		return err
 	}
 
-	return nil
+	return batch.Commit()
 }
 
 func printFields(vehicle Vehicle) error {
@@ -181,14 +254,77 @@ func printFields(vehicle Vehicle) error {
 }
 
 func (vs *VehicleStorage) EditVehicle(input Vehicle) (Vehicle, error) {
+	batch, err := vs.Begin()
+	if err != nil {
+		return input, err
+	}
+
+	vehicle, err := batch.EditVehicle(input)
+	if err != nil {
+		return vehicle, err
+	}
+
+	return vehicle, batch.Commit()
+}
+
+func (vs *VehicleStorage) SetBranch(plateNumber string, branchID int) error {
 	vehicles := Vehicles{}
 
 	if err := vs.storage.Load(&vehicles); err != nil {
-		return input, err
+		return err
+	}
+
+	vehicle, ok := vehicles[plateNumber]
+	if !ok {
+		return fmt.Errorf("vehicle with plate number %v not found in the storage", plateNumber)
+	}
+
+	vehicle.BranchID = branchID
+	vehicles[plateNumber] = vehicle
+
+	if err := vs.storage.Save(vehicles); err != nil {
+		return err
+	}
+
+	return vs.events.Append(events.New(events.VehicleUpdated, subject(plateNumber), vehicle))
+}
+
+// Batch applies several changes to one loaded copy of the vehicles file and
+// saves them with a single Commit.
+type Batch struct {
+	storage  *VehicleStorage
+	vehicles Vehicles
+	changed  bool
+	pending  events.Events
+}
+
+func (vs *VehicleStorage) Begin() (*Batch, error) {
+	vehicles := Vehicles{}
+	if err := vs.storage.Load(&vehicles); err != nil {
+		return nil, err
+	}
+
+	return &Batch{storage: vs, vehicles: vehicles}, nil
+}
+
+func (b *Batch) AddVehicle(input Vehicle) (Vehicle, error) {
+	if err := b.storage.validateVehicle(input); err != nil {
+		return Vehicle{}, err
 	}
 
-	_, ok := vehicles[input.PlateNumber]
+	if _, ok := b.vehicles[input.PlateNumber]; ok {
+		return Vehicle{}, fmt.Errorf("vehiche with plate number %v is already in the storage", input.PlateNumber)
+	}
+
+	b.vehicles[input.PlateNumber] = input
+	b.changed = true
+	b.pending = append(b.pending, events.New(events.VehicleCreated, subject(input.PlateNumber), input))
 
+	return input, nil
+}
+
+func (b *Batch) EditVehicle(input Vehicle) (Vehicle, error) {
+	current, ok := b.vehicles[input.PlateNumber]
 	if !ok {
 		return input, fmt.Errorf("vehicle with plate number %v not found in the storage", input.PlateNumber)
 	}
@@ -197,15 +333,42 @@ func (vs *VehicleStorage) EditVehicle(input Vehicle) (Vehicle, error) {
 		return input, err
 	}
 
-	if vehicles[input.PlateNumber] == input {
+	if current == input {
 		return Vehicle{}, errors.New("new data not detected")
 	}
 
-	vehicles[input.PlateNumber] = input
+	b.vehicles[input.PlateNumber] = input
+	b.changed = true
+	b.pending = append(b.pending, events.New(events.VehicleUpdated, subject(input.PlateNumber), input))
 
-	if err := vs.storage.Save(vehicles); err != nil {
-		return Vehicle{}, err
+	return input, nil
+}
+
+func (b *Batch) DeleteVehicle(plateNumber string) error {
+	deleted, ok := b.vehicles[plateNumber]
+	if !ok {
+		return fmt.Errorf("vehicle with plate number %v not found in the storage", plateNumber)
 	}
 
-	return input, nil
+	delete(b.vehicles, plateNumber)
+	b.changed = true
+	b.pending = append(b.pending, events.New(events.VehicleDeleted, subject(plateNumber), deleted))
+
+	return nil
+}
+
+// Commit saves the batch and its events in one transaction.
+func (b *Batch) Commit() error {
+	if !b.changed {
+		return nil
+	}
+
+	return b.storage.events.Within(func(tx *storage.Tx) error {
+		scoped := b.storage.WithTx(tx)
+		if err := scoped.storage.Save(b.vehicles); err != nil {
+			return err
+		}
+
+		return scoped.events.Append(b.pending...)
+	})
 }
@@ -0,0 +1,573 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+// Messages of a customer who opts out before they are sent are cancelled.
+const (
+	StatusPending   = "pending"
+	StatusSent      = "sent"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+type Attempt struct {
+	At    time.Time
+	Error string `json:",omitempty"`
+}
+
+type Message struct {
+	ID            int64
+	PersonalID    int64
+	Template      string
+	To            string
+	Subject       string
+	Text          string
+	HTML          string
+	Status        string
+	Attempts      []Attempt
+	NextAttemptAt *time.Time
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+type Messages []Message
+
+// Queue is the persistent email queue. Cursor is the last outbox event the
+// notifier has looked at.
+type Queue struct {
+	Cursor   int64
+	Messages Messages
+}
+
+type Preference struct {
+	EmailOptOut bool
+	UpdatedAt   time.Time
+}
+
+// Preferences holds the customers who opted out of email, keyed by personal
+// ID. Customers not listed receive email.
+type Preferences map[int64]Preference
+
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	PollInterval   time.Duration
+	Retention      int
+}
+
+func (p Policy) backoff(failed int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < failed && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxBackoff)
+}
+
+// Notifier turns domain events into customer emails, queues them and sends
+// them through Sender with retries.
+type Notifier struct {
+	queue       *storage.Storage[Queue]
+	preferences *storage.Storage[Preferences]
+	journal     *storage.Journal
+	outbox      *events.Outbox
+	customers   *customer.CustomerStorage
+	vehicles    *vehicle.VehicleStorage
+	rentals     *rental.RentalStorage
+	templates   Templates
+	sender      Sender
+	policy      Policy
+	wake        chan struct{}
+}
+
+func NewNotifier(queueFile, preferencesFile string, templates Templates, sender Sender, policy Policy, journal *storage.Journal, outbox *events.Outbox, customers *customer.CustomerStorage, vehicles *vehicle.VehicleStorage, rentals *rental.RentalStorage) *Notifier {
+	return &Notifier{
+		queue:       storage.NewStorage[Queue](queueFile),
+		preferences: storage.NewStorage[Preferences](preferencesFile),
+		journal:     journal,
+		outbox:      outbox,
+		customers:   customers,
+		vehicles:    vehicles,
+		rentals:     rentals,
+		templates:   templates,
+		sender:      sender,
+		policy:      policy,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+func (n *Notifier) GetStorage() *storage.Storage[Queue] {
+	return n.queue
+}
+
+func (n *Notifier) GetPreferencesStorage() *storage.Storage[Preferences] {
+	return n.preferences
+}
+
+func (n *Notifier) WithContext(ctx context.Context) *Notifier {
+	scoped := *n
+	scoped.queue = n.queue.WithContext(ctx)
+	scoped.preferences = n.preferences.WithContext(ctx)
+	return &scoped
+}
+
+func (n *Notifier) Templates() Templates {
+	return n.templates
+}
+
+func (n *Notifier) GetPreference(personalID int64) (Preference, error) {
+	preferences := Preferences{}
+	if err := n.preferences.Load(&preferences); err != nil {
+		return Preference{}, err
+	}
+
+	return preferences[personalID], nil
+}
+
+func (n *Notifier) SetPreference(personalID int64, optOut bool) (Preference, error) {
+	tx, err := n.journal.Begin()
+	if err != nil {
+		return Preference{}, err
+	}
+	defer tx.Rollback()
+
+	preferencesStorage := n.preferences.WithTx(tx)
+
+	preferences := Preferences{}
+	if err := preferencesStorage.Load(&preferences); err != nil {
+		return Preference{}, err
+	}
+
+	preference := Preference{EmailOptOut: optOut, UpdatedAt: time.Now()}
+	if optOut {
+		preferences[personalID] = preference
+	} else {
+		delete(preferences, personalID)
+	}
+
+	if err := preferencesStorage.Save(preferences); err != nil {
+		return Preference{}, err
+	}
+
+	if optOut {
+		queueStorage := n.queue.WithTx(tx)
+
+		queue := Queue{}
+		if err := queueStorage.Load(&queue); err != nil {
+			return Preference{}, err
+		}
+
+		if queue.cancel(personalID) > 0 {
+			if err := queueStorage.Save(queue); err != nil {
+				return Preference{}, err
+			}
+		}
+	}
+
+	return preference, tx.Commit()
+}
+
+// cancel drops the pending messages of a customer and returns how many.
+func (queue *Queue) cancel(personalID int64) int {
+	cancelled := 0
+	for idx := range queue.Messages {
+		msg := &queue.Messages[idx]
+		if msg.PersonalID == personalID && msg.Status == StatusPending {
+			msg.Status = StatusCancelled
+			msg.NextAttemptAt = nil
+			cancelled++
+		}
+	}
+
+	return cancelled
+}
+
+func (n *Notifier) updateQueue(fn func(queue *Queue) error) error {
+	tx, err := n.journal.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queueStorage := n.queue.WithTx(tx)
+
+	queue := Queue{}
+	if err := queueStorage.Load(&queue); err != nil {
+		return err
+	}
+
+	if err := fn(&queue); err != nil {
+		return err
+	}
+
+	if err := queueStorage.Save(queue); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (queue *Queue) nextID() int64 {
+	if len(queue.Messages) == 0 {
+		return 1
+	}
+
+	return queue.Messages[len(queue.Messages)-1].ID + 1
+}
+
+// render renders a template into a pending message for data.Customer.
+// Customers who opted out are skipped, and false is returned.
+func (n *Notifier) render(preferences Preferences, name string, data Data) (Message, bool, error) {
+	if preferences[data.Customer.PersonalID].EmailOptOut {
+		return Message{}, false, nil
+	}
+
+	rendered, err := n.templates.Render(name, data)
+	if err != nil {
+		return Message{}, false, err
+	}
+
+	now := time.Now()
+	return Message{
+		PersonalID:    data.Customer.PersonalID,
+		Template:      name,
+		To:            data.Customer.Email,
+		Subject:       rendered.Subject,
+		Text:          rendered.Text,
+		HTML:          rendered.HTML,
+		Status:        StatusPending,
+		Attempts:      []Attempt{},
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}, true, nil
+}
+
+// push adds rendered messages to the queue under the next IDs.
+func (n *Notifier) push(queue *Queue, messages ...Message) {
+	for _, msg := range messages {
+		msg.ID = queue.nextID()
+		queue.Messages = append(queue.Messages, msg)
+	}
+
+	queue.trim(n.policy.Retention)
+}
+
+// trim drops the oldest finished messages beyond retain. Pending ones are
+// always kept.
+func (queue *Queue) trim(retain int) {
+	excess := len(queue.Messages) - retain
+	if excess <= 0 {
+		return
+	}
+
+	kept := Messages{}
+	for _, msg := range queue.Messages {
+		if excess > 0 && msg.Status != StatusPending {
+			excess--
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	queue.Messages = kept
+}
+
+// Enqueue renders the named template for data.Customer and queues it, unless
+// the customer opted out.
+func (n *Notifier) Enqueue(name string, data Data) (bool, error) {
+	preferences := Preferences{}
+	if err := n.preferences.Load(&preferences); err != nil {
+		return false, err
+	}
+
+	msg, queued, err := n.render(preferences, name, data)
+	if err != nil || !queued {
+		return false, err
+	}
+
+	err = n.updateQueue(func(queue *Queue) error {
+		n.push(queue, msg)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	n.Wake()
+	return true, nil
+}
+
+func (n *Notifier) Wake() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (n *Notifier) GetMessages(status string) (Messages, error) {
+	queue := Queue{}
+	if err := n.queue.Load(&queue); err != nil {
+		return nil, err
+	}
+
+	found := Messages{}
+	for idx := len(queue.Messages) - 1; idx >= 0; idx-- {
+		if status == "" || queue.Messages[idx].Status == status {
+			found = append(found, queue.Messages[idx])
+		}
+	}
+
+	return found, nil
+}
+
+// Start runs the notifier until ctx is done.
+func (n *Notifier) Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		logger := logging.FromContext(ctx)
+
+		ticker := time.NewTicker(n.policy.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			changed := n.outbox.Changed()
+
+			if err := n.collect(logger); err != nil {
+				logger.Error("queueing emails failed", "error", err)
+			}
+
+			if err := n.sendDue(ctx, logger); err != nil {
+				logger.Error("sending emails failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			case <-n.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return done
+}
+
+// collect queues emails for the outbox events customers are notified about.
+// The emails are built from the customers, vehicles and rentals outside the
+// queue's transaction, which only saves them with the new cursor.
+func (n *Notifier) collect(logger *slog.Logger) error {
+	for {
+		preferences := Preferences{}
+		if err := n.preferences.Load(&preferences); err != nil {
+			return err
+		}
+
+		queue := Queue{}
+		if err := n.queue.Load(&queue); err != nil {
+			return err
+		}
+
+		pending, err := n.outbox.Since(queue.Cursor, 500)
+		if err != nil {
+			return err
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		messages := Messages{}
+		for _, event := range pending {
+			var name string
+			var data Data
+			var err error
+
+			switch event.Type {
+			case events.VehicleAssigned:
+				name = TemplateVehicleAssigned
+				data, err = n.assignmentData(event)
+			case events.RentalDueSoon:
+				name = TemplateRentalDueBack
+				data, err = n.rentalData(event)
+			case events.RentalOverdue:
+				name = TemplateRentalOverdue
+				data, err = n.rentalData(event)
+			default:
+				continue
+			}
+
+			if err != nil {
+				logger.Warn("skipping email for event", "event", event.ID, "error", err)
+				continue
+			}
+
+			msg, queued, err := n.render(preferences, name, data)
+			if err != nil {
+				return err
+			}
+
+			if queued {
+				messages = append(messages, msg)
+			}
+		}
+
+		err = n.updateQueue(func(current *Queue) error {
+			// Another pass got here first, the events are queued already.
+			if current.Cursor != queue.Cursor {
+				return nil
+			}
+
+			n.push(current, messages...)
+			current.Cursor = pending[len(pending)-1].ID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (n *Notifier) assignmentData(event events.Event) (Data, error) {
+	var assignment events.Assignment
+	if err := json.Unmarshal(event.Data, &assignment); err != nil {
+		return Data{}, err
+	}
+
+	customer, err := n.customers.GetCustomer(assignment.PersonalID)
+	if err != nil {
+		return Data{}, err
+	}
+
+	vehicle, err := n.vehicles.GetVehicle(assignment.PlateNumber)
+	if err != nil {
+		return Data{}, err
+	}
+
+	rentals, err := n.rentals.GetRentals()
+	if err != nil {
+		return Data{}, err
+	}
+
+	data := Data{Customer: customer, Vehicle: vehicle}
+	for idx := len(rentals) - 1; idx >= 0; idx-- {
+		if rentals[idx].PersonalID == assignment.PersonalID && rentals[idx].PlateNumber == assignment.PlateNumber {
+			data.Rental = rentals[idx]
+			break
+		}
+	}
+
+	return data, nil
+}
+
+// rentalData loads the customer and vehicle of a rental event. The rental
+// itself is taken from the event, as it was when the event happened.
+func (n *Notifier) rentalData(event events.Event) (Data, error) {
+	var rental rental.Rental
+	if err := json.Unmarshal(event.Data, &rental); err != nil {
+		return Data{}, err
+	}
+
+	customer, err := n.customers.GetCustomer(rental.PersonalID)
+	if err != nil {
+		return Data{}, err
+	}
+
+	vehicle, err := n.vehicles.GetVehicle(rental.PlateNumber)
+	if err != nil {
+		return Data{}, err
+	}
+
+	return Data{Customer: customer, Vehicle: vehicle, Rental: rental}, nil
+}
+
+func (n *Notifier) sendDue(ctx context.Context, logger *slog.Logger) error {
+	queue := Queue{}
+	if err := n.queue.Load(&queue); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, msg := range queue.Messages {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if msg.Status != StatusPending || msg.NextAttemptAt == nil || msg.NextAttemptAt.After(now) {
+			continue
+		}
+
+		// The customer may have opted out since the queue was loaded.
+		preference, err := n.GetPreference(msg.PersonalID)
+		if err != nil {
+			return err
+		}
+
+		if preference.EmailOptOut {
+			err := n.updateQueue(func(queue *Queue) error {
+				queue.cancel(msg.PersonalID)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		attempt := Attempt{At: time.Now()}
+		if err := n.sender.Send(ctx, msg); err != nil {
+			attempt.Error = err.Error()
+		}
+
+		err = n.updateQueue(func(queue *Queue) error {
+			idx := slices.IndexFunc(queue.Messages, func(queued Message) bool { return queued.ID == msg.ID })
+			if idx == -1 {
+				return fmt.Errorf("email with ID %d disappeared from the queue", msg.ID)
+			}
+
+			queued := &queue.Messages[idx]
+			queued.Attempts = append(queued.Attempts, attempt)
+
+			switch {
+			case attempt.Error == "":
+				queued.Status = StatusSent
+				queued.SentAt = &attempt.At
+				queued.NextAttemptAt = nil
+			case len(queued.Attempts) >= n.policy.MaxAttempts:
+				queued.Status = StatusFailed
+				queued.NextAttemptAt = nil
+			default:
+				next := attempt.At.Add(n.policy.backoff(len(queued.Attempts)))
+				queued.NextAttemptAt = &next
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if attempt.Error != "" {
+			logger.Warn("sending email failed", "email", msg.ID, "template", msg.Template, "error", attempt.Error)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const testPersonalID = 39001010000
+
+type recordingSender struct {
+	mu   sync.Mutex
+	sent Messages
+}
+
+func (s *recordingSender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func newTestNotifier(t *testing.T) (*Notifier, *recordingSender, *events.Outbox) {
+	t.Helper()
+
+	dir := t.TempDir()
+	journal := storage.NewJournal(filepath.Join(dir, "journal.json"))
+	outbox := events.NewOutbox(filepath.Join(dir, "events.json"), 100, journal)
+	customers := customer.NewCustomerStorage(filepath.Join(dir, "customers.json"), customer.RiskPolicy{}, outbox)
+	vehicles := vehicle.NewVehicleStorage(filepath.Join(dir, "vehicles.json"), vehicle.Catalogue{FuelTypes: []string{"Petrol"}, Gearboxes: []string{"Automatic"}, Colors: []string{"White"}, Bodies: []string{"Sedan"}, MinYear: 2010}, outbox)
+	rentals := rental.NewRentalStorage(filepath.Join(dir, "rentals.json"), rental.Pricing{}, outbox)
+
+	templates, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &recordingSender{}
+	n := NewNotifier(filepath.Join(dir, "emails.json"), filepath.Join(dir, "preferences.json"), templates, sender, Policy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		PollInterval:   time.Minute,
+		Retention:      100,
+	}, journal, outbox, customers, vehicles, rentals)
+
+	err = errors.Join(
+		storage.EnsureStorageFile(outbox.GetStorage(), events.Events{}),
+		storage.EnsureStorageFile(customers.GetStorage(), customer.Customers{{FirstName: "Mari", LastName: "Tamm", PersonalID: testPersonalID, Email: "mari@example.com"}}),
+		storage.EnsureStorageFile(vehicles.GetStorage(), vehicle.Vehicles{"111AAA": {PlateNumber: "111AAA", Make: "Toyota", Model: "Corolla"}}),
+		storage.EnsureStorageFile(rentals.GetStorage(), rental.Rentals{}),
+		storage.EnsureStorageFile(n.GetStorage(), Queue{Messages: Messages{}}),
+		storage.EnsureStorageFile(n.GetPreferencesStorage(), Preferences{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n, sender, outbox
+}
+
+func sampleData() Data {
+	data := SampleData()
+	data.Customer.PersonalID = testPersonalID
+	return data
+}
+
+func TestOptOutCancelsQueuedEmails(t *testing.T) {
+	n, sender, _ := newTestNotifier(t)
+
+	if _, err := n.Enqueue(TemplateVehicleAssigned, sampleData()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := n.SetPreference(testPersonalID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, err := n.GetMessages(StatusCancelled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled) != 1 {
+		t.Errorf("%d cancelled emails, want 1", len(cancelled))
+	}
+
+	if err := n.sendDue(context.Background(), slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d emails to a customer who opted out", len(sender.sent))
+	}
+}
+
+func TestSendDueRechecksPreference(t *testing.T) {
+	n, sender, _ := newTestNotifier(t)
+
+	if _, err := n.Enqueue(TemplateVehicleAssigned, sampleData()); err != nil {
+		t.Fatal(err)
+	}
+
+	// An opt-out saved without going through SetPreference, as a send pass
+	// that loaded the queue just before the opt-out would see it.
+	if err := n.preferences.Save(Preferences{testPersonalID: {EmailOptOut: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.sendDue(context.Background(), slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d emails to a customer who opted out", len(sender.sent))
+	}
+
+	pending, err := n.GetMessages(StatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("%d emails still pending, want them cancelled", len(pending))
+	}
+}
+
+func TestCollectQueuesEachEventOnce(t *testing.T) {
+	n, sender, outbox := newTestNotifier(t)
+
+	dueBackAt := time.Now().Add(time.Hour)
+	overdue := rental.Rental{ID: 1, PersonalID: testPersonalID, PlateNumber: "111AAA", DueBackAt: &dueBackAt}
+	err := outbox.Append(
+		events.New(events.CustomerCreated, "customer/1", nil),
+		events.New(events.RentalOverdue, "rental/1", overdue),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := n.collect(slog.Default()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queue := Queue{}
+	if err := n.queue.Load(&queue); err != nil {
+		t.Fatal(err)
+	}
+
+	if queue.Cursor != 2 {
+		t.Errorf("cursor = %d, want 2", queue.Cursor)
+	}
+	if len(queue.Messages) != 1 || queue.Messages[0].Template != TemplateRentalOverdue || queue.Messages[0].To != "mari@example.com" {
+		t.Fatalf("queue = %+v, want one overdue email", queue.Messages)
+	}
+
+	if err := n.sendDue(context.Background(), slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("sent %d emails, want 1", len(sender.sent))
+	}
+}
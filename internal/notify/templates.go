@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+)
+
+const (
+	TemplateVehicleAssigned = "VehicleAssigned"
+	TemplateRentalDueBack   = "RentalDueBack"
+	TemplateRentalOverdue   = "RentalOverdue"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Data is what every template is executed with. Fields a notification does
+// not concern are left zero.
+type Data struct {
+	Customer customer.Customer
+	Vehicle  vehicle.Vehicle
+	Rental   rental.Rental
+}
+
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// emailTemplate is one notification kind: a plain text subject and body and
+// an HTML body, loaded from <name>.subject.tmpl, <name>.txt.tmpl and
+// <name>.html.tmpl.
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+type Templates map[string]emailTemplate
+
+// LoadTemplates parses the templates in dir, or the built-in ones when dir is
+// empty. Every template must have all three parts.
+func LoadTemplates(dir string) (Templates, error) {
+	var files fs.FS
+	if dir == "" {
+		sub, err := fs.Sub(defaultTemplates, "templates")
+		if err != nil {
+			return nil, err
+		}
+		files = sub
+	} else {
+		files = os.DirFS(dir)
+	}
+
+	names, err := fs.Glob(files, "*.subject.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := Templates{}
+	for _, subjectFile := range names {
+		name := strings.TrimSuffix(subjectFile, ".subject.tmpl")
+
+		subject, err := texttemplate.ParseFS(files, subjectFile)
+		if err != nil {
+			return nil, err
+		}
+
+		text, err := texttemplate.ParseFS(files, name+".txt.tmpl")
+		if err != nil {
+			return nil, err
+		}
+
+		html, err := htmltemplate.ParseFS(files, name+".html.tmpl")
+		if err != nil {
+			return nil, err
+		}
+
+		templates[name] = emailTemplate{subject: subject.Option("missingkey=error"), text: text.Option("missingkey=error"), html: html.Option("missingkey=error")}
+	}
+
+	for _, required := range []string{TemplateVehicleAssigned, TemplateRentalDueBack, TemplateRentalOverdue} {
+		if _, ok := templates[required]; !ok {
+			return nil, fmt.Errorf("email template %s is missing", required)
+		}
+	}
+
+	return templates, nil
+}
+
+func (t Templates) Names() []string {
+	names := []string{}
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (t Templates) Render(name string, data Data) (Rendered, error) {
+	tmpl, ok := t[name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("email template %s not found", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return Rendered{}, err
+	}
+
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Rendered{}, err
+	}
+
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return Rendered{}, err
+	}
+
+	return Rendered{Subject: strings.TrimSpace(subject.String()), Text: text.String(), HTML: html.String()}, nil
+}
+
+// SampleData is used to preview templates without touching real records.
+func SampleData() Data {
+	checkoutAt := time.Date(2024, time.June, 3, 9, 30, 0, 0, time.UTC)
+	dueBackAt := checkoutAt.AddDate(0, 0, 3)
+
+	return Data{
+		Customer: customer.Customer{
+			FirstName:   "Mari",
+			LastName:    "Tamm",
+			PersonalID:  49001010001,
+			PhoneNumber: "5551234",
+			Email:       "mari.tamm@example.com",
+			CreatedAt:   checkoutAt.AddDate(0, -2, 0),
+		},
+		Vehicle: vehicle.Vehicle{
+			PlateNumber: "123ABC",
+			Make:        "Toyota",
+			Model:       "Corolla",
+			Year:        2021,
+			FuelType:    "Hybrid",
+			Gearbox:     "Automatic",
+			Color:       "White",
+			Body:        "Sedan",
+			BranchID:    1,
+		},
+		Rental: rental.Rental{
+			ID:                1,
+			PersonalID:        49001010001,
+			PlateNumber:       "123ABC",
+			CheckoutAt:        checkoutAt,
+			CheckoutOdometer:  42150,
+			CheckoutFuelLevel: 100,
+			DueBackAt:         &dueBackAt,
+			LateFee:           40,
+			PickupBranchID:    1,
+		},
+	}
+}
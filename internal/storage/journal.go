@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var fileLocks sync.Map
+
+func fileLock(fileName string) *sync.RWMutex {
+	lock, _ := fileLocks.LoadOrStore(filepath.Clean(fileName), &sync.RWMutex{})
+	return lock.(*sync.RWMutex)
+}
+
+// Journal commits transactions spanning several storage files. A commit
+// first writes every staged file into the journal, then rewrites the files
+// and finally removes the journal, so a journal found on disk always holds a
+// complete set of writes that can be replayed.
+type Journal struct {
+	FileName string
+	mu       sync.Mutex
+}
+
+type journalWrite struct {
+	FileName string
+	Data     json.RawMessage
+}
+
+type journalRecord struct {
+	CreatedAt time.Time
+	Writes    []journalWrite
+}
+
+// Tx is a unit of work over several storage files. Files are locked the
+// first time the transaction loads or saves them and stay locked until
+// Commit or Rollback; transactions of one journal run one at a time.
+type Tx struct {
+	journal  *Journal
+	locked   []string
+	staged   map[string][]byte
+	order    []string
+	onCommit []func()
+	done     bool
+}
+
+func NewJournal(fileName string) *Journal {
+	return &Journal{FileName: fileName}
+}
+
+func (j *Journal) Begin() (*Tx, error) {
+	j.mu.Lock()
+
+	if err := j.replay(); err != nil {
+		j.mu.Unlock()
+		return nil, err
+	}
+
+	return &Tx{journal: j, staged: map[string][]byte{}}, nil
+}
+
+// Recover replays a journal left behind by an interrupted commit. It must
+// run before the storage files are used.
+func (j *Journal) Recover() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.replay()
+}
+
+func (j *Journal) replay() error {
+	data, err := os.ReadFile(j.FileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var record journalRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("storage journal %s is corrupt: %w", j.FileName, err)
+	}
+
+	slog.Warn("replaying storage journal", "journal", j.FileName, "files", len(record.Writes), "created_at", record.CreatedAt)
+
+	for _, write := range record.Writes {
+		if err := replayWrite(write); err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(j.FileName)
+}
+
+func replayWrite(write journalWrite) error {
+	var fileData bytes.Buffer
+	if err := json.Indent(&fileData, write.Data, "", "    "); err != nil {
+		return err
+	}
+
+	lock := fileLock(write.FileName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	writes.RLock()
+	defer writes.RUnlock()
+
+	return writeFileAtomic(write.FileName, fileData.Bytes())
+}
+
+func (tx *Tx) lock(fileName string) {
+	for _, locked := range tx.locked {
+		if locked == fileName {
+			return
+		}
+	}
+
+	fileLock(fileName).Lock()
+	tx.locked = append(tx.locked, fileName)
+}
+
+func (tx *Tx) load(fileName string) ([]byte, error) {
+	if tx.done {
+		return nil, errors.New("transaction already finished")
+	}
+
+	tx.lock(fileName)
+
+	if data, ok := tx.staged[fileName]; ok {
+		return data, nil
+	}
+
+	return os.ReadFile(fileName)
+}
+
+func (tx *Tx) stage(fileName string, data []byte) {
+	tx.lock(fileName)
+
+	if _, ok := tx.staged[fileName]; !ok {
+		tx.order = append(tx.order, fileName)
+	}
+
+	tx.staged[fileName] = data
+}
+
+// ReadFile returns the raw contents of a storage file as the transaction
+// sees it.
+func (tx *Tx) ReadFile(fileName string) ([]byte, error) {
+	return tx.load(fileName)
+}
+
+// WriteFile stages raw contents for a storage file.
+func (tx *Tx) WriteFile(fileName string, data []byte) error {
+	if tx.done {
+		return errors.New("transaction already finished")
+	}
+
+	tx.stage(fileName, data)
+	return nil
+}
+
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return errors.New("transaction already finished")
+	}
+
+	err := tx.write()
+	tx.release()
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range tx.onCommit {
+		fn()
+	}
+
+	return nil
+}
+
+// OnCommit registers fn to run once the transaction has been committed and
+// its locks released. It is not run on Rollback.
+func (tx *Tx) OnCommit(fn func()) {
+	tx.onCommit = append(tx.onCommit, fn)
+}
+
+func (tx *Tx) write() error {
+	if len(tx.order) == 0 {
+		return nil
+	}
+
+	record := journalRecord{CreatedAt: time.Now()}
+	for _, fileName := range tx.order {
+		record.Writes = append(record.Writes, journalWrite{FileName: fileName, Data: tx.staged[fileName]})
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	writes.RLock()
+	defer writes.RUnlock()
+
+	if err := writeFileAtomic(tx.journal.FileName, data); err != nil {
+		return err
+	}
+
+	// If a write fails the journal stays on disk and the next transaction or
+	// restart replays it.
+	for _, write := range record.Writes {
+		if err := writeFileAtomic(write.FileName, write.Data); err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(tx.journal.FileName)
+}
+
+// Rollback discards everything staged in the transaction. It does nothing
+// after Commit, so it is safe to defer.
+func (tx *Tx) Rollback() {
+	if !tx.done {
+		tx.release()
+	}
+}
+
+func (tx *Tx) release() {
+	tx.done = true
+
+	for _, fileName := range tx.locked {
+		fileLock(fileName).Unlock()
+	}
+
+	tx.journal.mu.Unlock()
+}
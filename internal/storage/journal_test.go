@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type counter struct {
+	Value int
+}
+
+func newTestFiles(t *testing.T) (*Journal, *Storage[counter], *Storage[counter]) {
+	t.Helper()
+
+	dir := t.TempDir()
+	journal := NewJournal(filepath.Join(dir, "journal.json"))
+	first := NewStorage[counter](filepath.Join(dir, "first.json"))
+	second := NewStorage[counter](filepath.Join(dir, "second.json"))
+
+	for _, storage := range []*Storage[counter]{first, second} {
+		if err := storage.Save(counter{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return journal, first, second
+}
+
+func loadValue(t *testing.T, storage *Storage[counter]) int {
+	t.Helper()
+
+	var data counter
+	if err := storage.Load(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	return data.Value
+}
+
+func TestRecoverReplaysInterruptedCommit(t *testing.T) {
+	journal, first, second := newTestFiles(t)
+
+	// A crash after the journal was written but before the files were
+	// renamed leaves the journal behind with the complete set of writes.
+	record := journalRecord{CreatedAt: time.Now(), Writes: []journalWrite{
+		{FileName: first.FileName, Data: json.RawMessage(`{"Value":1}`)},
+		{FileName: second.FileName, Data: json.RawMessage(`{"Value":2}`)},
+	}}
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(journal.FileName, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := journal.Recover(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loadValue(t, first); got != 1 {
+		t.Errorf("first = %d, want 1", got)
+	}
+	if got := loadValue(t, second); got != 2 {
+		t.Errorf("second = %d, want 2", got)
+	}
+	if _, err := os.Stat(journal.FileName); !os.IsNotExist(err) {
+		t.Errorf("journal still on disk after recover: %v", err)
+	}
+}
+
+func TestRecoverRejectsCorruptJournal(t *testing.T) {
+	journal, _, _ := newTestFiles(t)
+
+	if err := os.WriteFile(journal.FileName, []byte(`{"Writes":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := journal.Recover(); err == nil {
+		t.Fatal("expected an error for a corrupt journal")
+	}
+}
+
+func TestCommitWritesEveryFile(t *testing.T) {
+	journal, first, second := newTestFiles(t)
+
+	tx, err := journal.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.WithTx(tx).Save(counter{Value: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.WithTx(tx).Save(counter{Value: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Staged writes are visible inside the transaction only.
+	if got := loadValue(t, first.WithTx(tx)); got != 1 {
+		t.Errorf("first inside tx = %d, want 1", got)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := loadValue(t, first); got != 1 {
+		t.Errorf("first = %d, want 1", got)
+	}
+	if got := loadValue(t, second); got != 2 {
+		t.Errorf("second = %d, want 2", got)
+	}
+	if _, err := os.Stat(journal.FileName); !os.IsNotExist(err) {
+		t.Errorf("journal still on disk after commit: %v", err)
+	}
+}
+
+func TestRollbackDiscardsStagedWrites(t *testing.T) {
+	journal, first, second := newTestFiles(t)
+
+	tx, err := journal.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	committed := false
+	tx.OnCommit(func() { committed = true })
+
+	if err := first.WithTx(tx).Save(counter{Value: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.WithTx(tx).Save(counter{Value: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx.Rollback()
+
+	if got := loadValue(t, first); got != 0 {
+		t.Errorf("first = %d after rollback, want 0", got)
+	}
+	if got := loadValue(t, second); got != 0 {
+		t.Errorf("second = %d after rollback, want 0", got)
+	}
+	if committed {
+		t.Error("OnCommit ran after rollback")
+	}
+	if err := tx.Commit(); err == nil {
+		t.Error("commit after rollback succeeded")
+	}
+
+	// The rollback released the journal, so the next transaction can begin.
+	next, err := journal.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.Rollback()
+}
+
+func TestOnCommitRunsAfterCommit(t *testing.T) {
+	journal, first, _ := newTestFiles(t)
+
+	tx, err := journal.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen int
+	tx.OnCommit(func() {
+		// Locks are released before the callbacks run, so the committed
+		// value can be read without the transaction.
+		seen = loadValue(t, first)
+	})
+
+	if err := first.WithTx(tx).Save(counter{Value: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if seen != 0 {
+		t.Fatal("OnCommit ran before commit")
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+
+	if seen != 3 {
+		t.Errorf("OnCommit saw %d, want 3", seen)
+	}
+}
+
+func TestConcurrentTransactionsOnOverlappingFiles(t *testing.T) {
+	journal, first, second := newTestFiles(t)
+
+	const workers = 20
+
+	// Each worker increments both files, one of them in the opposite order,
+	// so a lost update or a deadlock on the shared files shows up.
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			tx, err := journal.Begin()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx.Rollback()
+
+			files := []*Storage[counter]{first.WithTx(tx), second.WithTx(tx)}
+			if i%2 == 1 {
+				files[0], files[1] = files[1], files[0]
+			}
+
+			for _, storage := range files {
+				var data counter
+				if err := storage.Load(&data); err != nil {
+					errs <- err
+					return
+				}
+
+				data.Value++
+				if err := storage.Save(data); err != nil {
+					errs <- err
+					return
+				}
+			}
+
+			errs <- tx.Commit()
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := loadValue(t, first); got != workers {
+		t.Errorf("first = %d, want %d", got, workers)
+	}
+	if got := loadValue(t, second); got != workers {
+		t.Errorf("second = %d, want %d", got, workers)
+	}
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const lockFileName = ".rwapi.lock"
+
+var ErrLocked = errors.New("storage: data directory is in use by another process")
+
+// DirLock keeps other processes from opening the same data directory. The
+// storages only serialise writes within one process, so the server, restore
+// and local rwctl take it before touching the files.
+type DirLock struct {
+	file *os.File
+}
+
+// LockDir locks dir, returning ErrLocked while another process holds it. The
+// lock is released by Unlock or when the process exits.
+func LockDir(dir string) (*DirLock, error) {
+	file, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &DirLock{file: file}, nil
+}
+
+func (lock *DirLock) Unlock() error {
+	return lock.file.Close()
+}
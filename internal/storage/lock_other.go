@@ -0,0 +1,11 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// lockFile does not lock on platforms without flock, so running two
+// processes against one data directory is left to the operator.
+func lockFile(file *os.File) error {
+	return nil
+}
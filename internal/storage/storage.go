@@ -1,47 +1,195 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
 )
 
 type Storage[T any] struct {
 	FileName string
+	ctx      context.Context
+	tx       *Tx
 }
 
-func EnsureStorageFile[T any](storage *Storage[T], data T) error {
-	dataPath := fmt.Sprintf("./%s", storage.FileName)
+var writes sync.RWMutex
+
+var observer func(operation, fileName string, duration time.Duration)
+
+func SetObserver(fn func(operation, fileName string, duration time.Duration)) {
+	observer = fn
+}
+
+func observe(operation, fileName string, start time.Time) {
+	if observer != nil {
+		observer(operation, fileName, time.Since(start))
+	}
+}
 
-	if _, err := os.Stat(dataPath); err != nil {
+func Flush() {
+	writes.Lock()
+	defer writes.Unlock()
+}
+
+func EnsureStorageFile[T any](storage *Storage[T], data T) error {
+	if _, err := os.Stat(storage.FileName); err != nil {
 		if os.IsNotExist(err) {
-			fmt.Println("creating file", storage.FileName)
+			slog.Info("creating storage file", "file", storage.FileName)
 			return storage.Save(data)
 		}
-		fmt.Println("Error accessing file:", err)
+		slog.Error("accessing storage file failed", "file", storage.FileName, "error", err)
 		return err
 	}
 
-	fmt.Printf("file exists %s, no action needed.\n", storage.FileName)
+	slog.Debug("storage file exists", "file", storage.FileName)
 	return nil
 }
 
+// File is a storage file together with the value it is created with. Backups
+// use the value for files that did not exist yet when they were taken.
+type File struct {
+	Name  string
+	empty any
+}
+
+func NewFile[T any](storage *Storage[T], empty T) File {
+	return File{Name: storage.FileName, empty: empty}
+}
+
+// Empty returns the contents of the file as it is first created.
+func (file File) Empty() ([]byte, error) {
+	return json.MarshalIndent(file.empty, "", "    ")
+}
+
+// Ensure creates the file with its empty contents when it does not exist.
+func (file File) Ensure() error {
+	if _, err := os.Stat(file.Name); !os.IsNotExist(err) {
+		return err
+	}
+
+	data, err := file.Empty()
+	if err != nil {
+		return err
+	}
+
+	slog.Info("creating storage file", "file", file.Name)
+
+	lock := fileLock(file.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	writes.RLock()
+	defer writes.RUnlock()
+
+	return writeFileAtomic(file.Name, data)
+}
+
 func NewStorage[T any](fileName string) *Storage[T] {
 	return &Storage[T]{FileName: fileName}
 }
 
-func (storage *Storage[T]) Save(data T) error {
+func (storage *Storage[T]) WithContext(ctx context.Context) *Storage[T] {
+	scoped := *storage
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// WithTx returns a copy of the storage whose loads and saves go through tx.
+// Saves are staged until tx is committed.
+func (storage *Storage[T]) WithTx(tx *Tx) *Storage[T] {
+	scoped := *storage
+	scoped.tx = tx
+	return &scoped
+}
+
+func (storage *Storage[T]) logOperation(operation string, start time.Time, err error) {
+	logger := logging.FromContext(storage.ctx)
+	duration := time.Since(start)
+
+	if err != nil {
+		logger.Error("storage "+operation+" failed", "file", storage.FileName, "duration", duration, "error", err)
+		return
+	}
+
+	logger.Debug("storage "+operation, "file", storage.FileName, "duration", duration)
+}
+
+func (storage *Storage[T]) Save(data T) (err error) {
+	start := time.Now()
+	defer observe("save", storage.FileName, start)
+	defer func() { storage.logOperation("save", start, err) }()
+
 	fileData, err := json.MarshalIndent(data, "", "    ")
 
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(storage.FileName, fileData, 0644)
+	if storage.tx != nil {
+		storage.tx.stage(storage.FileName, fileData)
+		return nil
+	}
+
+	// The file lock is taken before the writes lock so a save waiting on a
+	// transaction never holds up Flush.
+	lock := fileLock(storage.FileName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	writes.RLock()
+	defer writes.RUnlock()
+
+	return writeFileAtomic(storage.FileName, fileData)
+}
+
+func writeFileAtomic(fileName string, fileData []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(fileData); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fileName)
 }
 
-func (storage *Storage[T]) Load(data *T) error {
-	fileData, err := os.ReadFile(storage.FileName)
+func (storage *Storage[T]) Load(data *T) (err error) {
+	start := time.Now()
+	defer observe("load", storage.FileName, start)
+	defer func() { storage.logOperation("load", start, err) }()
+
+	var fileData []byte
+	if storage.tx != nil {
+		fileData, err = storage.tx.load(storage.FileName)
+	} else {
+		lock := fileLock(storage.FileName)
+		lock.RLock()
+		fileData, err = os.ReadFile(storage.FileName)
+		lock.RUnlock()
+	}
 
 	if err != nil {
 		return err
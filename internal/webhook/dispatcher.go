@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/logging"
+)
+
+const (
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const enqueueBatchSize = 500
+
+// Sign returns the signature header value for a delivery: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+// Receivers recompute it to verify a request and reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Start runs the dispatcher until ctx is done. It turns new outbox events
+// into deliveries and sends every due delivery, retrying failures with
+// exponential backoff.
+func (m *Manager) Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		logger := logging.FromContext(ctx)
+		client := m.policy.newClient()
+
+		ticker := time.NewTicker(m.policy.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			changed := m.outbox.Changed()
+
+			if err := m.enqueue(); err != nil {
+				logger.Error("queueing webhook deliveries failed", "error", err)
+			}
+
+			if err := m.deliverDue(ctx, client, logger); err != nil {
+				logger.Error("delivering webhooks failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			case <-m.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return done
+}
+
+func (m *Manager) enqueue() error {
+	subscriptions, err := m.GetSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	return m.updateQueue(func(queue *Queue) error {
+		for {
+			pending, err := m.outbox.Since(queue.Cursor, enqueueBatchSize)
+			if err != nil {
+				return err
+			}
+
+			if len(pending) == 0 {
+				break
+			}
+
+			for _, event := range pending {
+				for _, sub := range subscriptions {
+					if !sub.Wants(event) {
+						continue
+					}
+
+					now := time.Now()
+					queue.Deliveries = append(queue.Deliveries, Delivery{
+						ID:             queue.nextID(),
+						SubscriptionID: sub.ID,
+						Event:          event,
+						Status:         StatusPending,
+						Attempts:       []Attempt{},
+						NextAttemptAt:  &now,
+						CreatedAt:      now,
+					})
+				}
+
+				queue.Cursor = event.ID
+			}
+		}
+
+		queue.trim(m.policy.DeliveryRetention)
+		return nil
+	})
+}
+
+func (m *Manager) deliverDue(ctx context.Context, client *http.Client, logger *slog.Logger) error {
+	queue := Queue{}
+	if err := m.queue.Load(&queue); err != nil {
+		return err
+	}
+
+	subscriptions, err := m.GetSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, delivery := range queue.Deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if delivery.Status != StatusPending || delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		var attempt Attempt
+		idx := findSubscription(subscriptions, delivery.SubscriptionID)
+		if idx == -1 {
+			attempt = Attempt{At: time.Now(), Error: "webhook subscription was deleted"}
+		} else {
+			attempt = send(ctx, client, subscriptions[idx], delivery)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		recorded, err := m.record(delivery.ID, attempt, idx == -1)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("webhook delivery attempted",
+			"delivery", recorded.ID,
+			"webhook", recorded.SubscriptionID,
+			"event", recorded.Event.ID,
+			"status", recorded.Status,
+			"status_code", attempt.StatusCode,
+			"error", attempt.Error,
+		)
+	}
+
+	return nil
+}
+
+func send(ctx context.Context, client *http.Client, sub Subscription, delivery Delivery) (attempt Attempt) {
+	attempt.At = time.Now()
+	defer func() {
+		attempt.DurationMs = time.Since(attempt.At).Milliseconds()
+	}()
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	timestamp := attempt.At.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RWAPIGo-Webhooks/1")
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("receiver responded with %s", resp.Status)
+	}
+
+	return attempt
+}
+
+// record stores the outcome of an attempt and schedules the next one.
+func (m *Manager) record(deliveryID int64, attempt Attempt, giveUp bool) (Delivery, error) {
+	var recorded Delivery
+	err := m.updateQueue(func(queue *Queue) error {
+		for idx := range queue.Deliveries {
+			delivery := &queue.Deliveries[idx]
+			if delivery.ID != deliveryID {
+				continue
+			}
+
+			delivery.Attempts = append(delivery.Attempts, attempt)
+
+			switch {
+			case attempt.Error == "":
+				delivery.Status = StatusDelivered
+				delivery.NextAttemptAt = nil
+			case giveUp || len(delivery.Attempts) >= m.policy.MaxAttempts:
+				delivery.Status = StatusFailed
+				delivery.NextAttemptAt = nil
+			default:
+				next := attempt.At.Add(m.policy.Backoff(len(delivery.Attempts)))
+				delivery.NextAttemptAt = &next
+			}
+
+			recorded = *delivery
+			return nil
+		}
+
+		return fmt.Errorf("delivery with ID %d disappeared from the queue", deliveryID)
+	})
+
+	return recorded, err
+}
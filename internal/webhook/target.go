@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// blockedIP reports whether ip is on the loopback, link-local or a private
+// network. Webhooks may only target such addresses when the policy allows
+// private targets, so a subscription cannot reach services behind the API.
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
+
+func errBlocked(host string) error {
+	return fmt.Errorf("invalid input: webhook host %s is a loopback, link-local or private address", host)
+}
+
+// checkHost rejects blocked IP literals and localhost without a lookup.
+func checkHost(host string) error {
+	if ip := net.ParseIP(host); ip != nil && blockedIP(ip) {
+		return errBlocked(host)
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errBlocked(host)
+	}
+
+	return nil
+}
+
+// CheckTarget resolves the host of a webhook URL and rejects it when any of
+// its addresses is blocked. It is kept apart from saving a subscription so
+// the lookup does not run inside a transaction.
+func (m *Manager) CheckTarget(ctx context.Context, rawURL string) error {
+	if m.policy.AllowPrivateTargets {
+		return nil
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Hostname() == "" {
+		return errors.New("invalid input: webhook URL must be an absolute http or https URL")
+	}
+
+	host := target.Hostname()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("invalid input: webhook host %s cannot be resolved", host)
+	}
+
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return errBlocked(host)
+		}
+	}
+
+	return nil
+}
+
+// newClient returns the client deliveries are sent with. Unless private
+// targets are allowed it refuses to connect to a blocked address, which also
+// covers hosts that resolve differently than when the subscription was saved.
+func (p Policy) newClient() *http.Client {
+	if p.AllowPrivateTargets {
+		return &http.Client{Timeout: p.Timeout}
+	}
+
+	dialer := &net.Dialer{
+		Timeout: p.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+				return fmt.Errorf("webhook target %s is not allowed", host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &http.Client{Timeout: p.Timeout, Transport: transport}
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+func newTestManager(t *testing.T, policy Policy) *Manager {
+	t.Helper()
+
+	dir := t.TempDir()
+	journal := storage.NewJournal(filepath.Join(dir, "journal.json"))
+	m := NewManager(filepath.Join(dir, "webhooks.json"), filepath.Join(dir, "deliveries.json"), policy, journal, events.NewOutbox(filepath.Join(dir, "events.json"), 100, journal))
+
+	if err := storage.EnsureStorageFile(m.GetStorage(), Subscriptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestSubscriptionRejectsPrivateTargets(t *testing.T) {
+	blocked := []string{
+		"http://127.0.0.1/hook",
+		"http://[::1]:8080/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://localhost:9000/hook",
+		"http://api.localhost/hook",
+	}
+
+	m := newTestManager(t, Policy{})
+	allowed := newTestManager(t, Policy{AllowPrivateTargets: true})
+
+	for _, target := range blocked {
+		input := Subscription{URL: target, EventTypes: []string{AllEvents}}
+
+		if _, err := m.AddSubscription(input); err == nil {
+			t.Errorf("AddSubscription(%s) succeeded, want it rejected", target)
+		}
+
+		if _, err := allowed.AddSubscription(input); err != nil {
+			t.Errorf("AddSubscription(%s) with private targets allowed: %v", target, err)
+		}
+	}
+
+	if _, err := m.AddSubscription(Subscription{URL: "https://93.184.215.14/hook", EventTypes: []string{AllEvents}}); err != nil {
+		t.Errorf("public target rejected: %v", err)
+	}
+}
+
+func TestCheckTargetResolvesHost(t *testing.T) {
+	m := newTestManager(t, Policy{})
+
+	if err := m.CheckTarget(context.Background(), "http://localhost./hook"); err == nil {
+		t.Error("CheckTarget accepted a host resolving to loopback")
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	policy := Policy{Timeout: time.Second}
+	if _, err := policy.newClient().Get(server.URL); err == nil {
+		t.Error("client connected to a loopback address")
+	}
+
+	policy.AllowPrivateTargets = true
+	response, err := policy.newClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("client with private targets allowed: %v", err)
+	}
+	response.Body.Close()
+}
@@ -0,0 +1,438 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/storage"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// AllEvents subscribes to every event type.
+const AllEvents = "*"
+
+type Subscription struct {
+	ID         int
+	URL        string
+	EventTypes []string
+	Secret     string `json:",omitempty"`
+	CreatedAt  time.Time
+}
+
+type Subscriptions []Subscription
+
+func (sub Subscription) Wants(event events.Event) bool {
+	if event.OccurredAt.Before(sub.CreatedAt) {
+		return false
+	}
+
+	return slices.Contains(sub.EventTypes, AllEvents) || slices.Contains(sub.EventTypes, event.Type)
+}
+
+type Attempt struct {
+	At         time.Time
+	StatusCode int    `json:",omitempty"`
+	Error      string `json:",omitempty"`
+	DurationMs int64
+}
+
+type Delivery struct {
+	ID             int64
+	SubscriptionID int
+	Event          events.Event
+	Status         string
+	Attempts       []Attempt
+	NextAttemptAt  *time.Time
+	CreatedAt      time.Time
+	RedeliveryOf   int64 `json:",omitempty"`
+}
+
+type Deliveries []Delivery
+
+// Queue is the persistent delivery queue. Cursor is the last outbox event
+// that has been turned into deliveries.
+type Queue struct {
+	Cursor     int64
+	Deliveries Deliveries
+}
+
+type Policy struct {
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	Timeout           time.Duration
+	PollInterval      time.Duration
+	DeliveryRetention int
+	// AllowPrivateTargets lets webhooks target loopback, link-local and
+	// private addresses, meant for local development.
+	AllowPrivateTargets bool
+}
+
+// Backoff is the delay before the attempt following the given number of
+// failed attempts, doubling from InitialBackoff up to MaxBackoff.
+func (p Policy) Backoff(failed int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < failed && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxBackoff)
+}
+
+type Manager struct {
+	subscriptions *storage.Storage[Subscriptions]
+	queue         *storage.Storage[Queue]
+	journal       *storage.Journal
+	tx            *storage.Tx
+	outbox        *events.Outbox
+	policy        Policy
+	wake          chan struct{}
+}
+
+func NewManager(subscriptionsFile, queueFile string, policy Policy, journal *storage.Journal, outbox *events.Outbox) *Manager {
+	return &Manager{
+		subscriptions: storage.NewStorage[Subscriptions](subscriptionsFile),
+		queue:         storage.NewStorage[Queue](queueFile),
+		journal:       journal,
+		outbox:        outbox,
+		policy:        policy,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+func (m *Manager) GetStorage() *storage.Storage[Subscriptions] {
+	return m.subscriptions
+}
+
+func (m *Manager) GetQueueStorage() *storage.Storage[Queue] {
+	return m.queue
+}
+
+func (m *Manager) WithContext(ctx context.Context) *Manager {
+	scoped := *m
+	scoped.subscriptions = m.subscriptions.WithContext(ctx)
+	scoped.queue = m.queue.WithContext(ctx)
+	return &scoped
+}
+
+func (m *Manager) WithTx(tx *storage.Tx) *Manager {
+	scoped := *m
+	scoped.subscriptions = m.subscriptions.WithTx(tx)
+	scoped.queue = m.queue.WithTx(tx)
+	scoped.tx = tx
+	return &scoped
+}
+
+func (m *Manager) validateSubscription(input Subscription) error {
+	target, err := url.Parse(input.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.New("invalid input: webhook URL must be an absolute http or https URL")
+	}
+
+	if !m.policy.AllowPrivateTargets {
+		if err := checkHost(target.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	if len(input.EventTypes) == 0 {
+		return fmt.Errorf("invalid input: webhook needs at least one event type, or %q for all", AllEvents)
+	}
+
+	for _, eventType := range input.EventTypes {
+		if eventType != AllEvents && !slices.Contains(events.Types, eventType) {
+			return fmt.Errorf("invalid input: unknown event type %q", eventType)
+		}
+	}
+
+	return nil
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+func findSubscription(subscriptions Subscriptions, id int) int {
+	for idx, sub := range subscriptions {
+		if sub.ID == id {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func (m *Manager) GetSubscriptions() (Subscriptions, error) {
+	subscriptions := Subscriptions{}
+	if err := m.subscriptions.Load(&subscriptions); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (m *Manager) GetSubscription(id int) (Subscription, error) {
+	subscriptions, err := m.GetSubscriptions()
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	idx := findSubscription(subscriptions, id)
+	if idx == -1 {
+		return Subscription{}, fmt.Errorf("webhook with ID %d not found", id)
+	}
+
+	return subscriptions[idx], nil
+}
+
+// AddSubscription stores a new subscription. A secret is generated when the
+// input has none; it is only ever returned here.
+func (m *Manager) AddSubscription(input Subscription) (Subscription, error) {
+	if err := m.validateSubscription(input); err != nil {
+		return Subscription{}, err
+	}
+
+	if input.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return Subscription{}, err
+		}
+		input.Secret = secret
+	}
+
+	subscriptions := Subscriptions{}
+	if err := m.subscriptions.Load(&subscriptions); err != nil {
+		return Subscription{}, err
+	}
+
+	nextID := 1
+	for _, sub := range subscriptions {
+		if sub.ID >= nextID {
+			nextID = sub.ID + 1
+		}
+	}
+
+	input.ID = nextID
+	input.CreatedAt = time.Now()
+	subscriptions = append(subscriptions, input)
+
+	if err := m.subscriptions.Save(subscriptions); err != nil {
+		return Subscription{}, err
+	}
+
+	return input, nil
+}
+
+// EditSubscription replaces the URL and event types. The secret is only
+// rotated when a new one is given.
+func (m *Manager) EditSubscription(input Subscription) (Subscription, error) {
+	if err := m.validateSubscription(input); err != nil {
+		return Subscription{}, err
+	}
+
+	subscriptions := Subscriptions{}
+	if err := m.subscriptions.Load(&subscriptions); err != nil {
+		return Subscription{}, err
+	}
+
+	idx := findSubscription(subscriptions, input.ID)
+	if idx == -1 {
+		return Subscription{}, fmt.Errorf("webhook with ID %d not found", input.ID)
+	}
+
+	sub := &subscriptions[idx]
+	sub.URL = input.URL
+	sub.EventTypes = input.EventTypes
+	if input.Secret != "" {
+		sub.Secret = input.Secret
+	}
+
+	if err := m.subscriptions.Save(subscriptions); err != nil {
+		return Subscription{}, err
+	}
+
+	return *sub, nil
+}
+
+func (m *Manager) DeleteSubscription(id int) error {
+	subscriptions := Subscriptions{}
+	if err := m.subscriptions.Load(&subscriptions); err != nil {
+		return err
+	}
+
+	idx := findSubscription(subscriptions, id)
+	if idx == -1 {
+		return fmt.Errorf("webhook with ID %d not found", id)
+	}
+
+	subscriptions = append(subscriptions[:idx], subscriptions[idx+1:]...)
+
+	return m.subscriptions.Save(subscriptions)
+}
+
+// updateQueue runs fn on the queue inside a journaled transaction, so the
+// dispatcher and the API never overwrite each other's changes. It uses the
+// manager's transaction when one is bound.
+func (m *Manager) updateQueue(fn func(queue *Queue) error) error {
+	if m.tx != nil {
+		queue := Queue{}
+		if err := m.queue.Load(&queue); err != nil {
+			return err
+		}
+
+		if err := fn(&queue); err != nil {
+			return err
+		}
+
+		return m.queue.Save(queue)
+	}
+
+	tx, err := m.journal.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queueStorage := m.queue.WithTx(tx)
+
+	queue := Queue{}
+	if err := queueStorage.Load(&queue); err != nil {
+		return err
+	}
+
+	if err := fn(&queue); err != nil {
+		return err
+	}
+
+	if err := queueStorage.Save(queue); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (queue *Queue) nextID() int64 {
+	if len(queue.Deliveries) == 0 {
+		return 1
+	}
+
+	return queue.Deliveries[len(queue.Deliveries)-1].ID + 1
+}
+
+// trim drops the oldest finished deliveries beyond retain. Pending ones are
+// always kept.
+func (queue *Queue) trim(retain int) {
+	excess := len(queue.Deliveries) - retain
+	if excess <= 0 {
+		return
+	}
+
+	kept := Deliveries{}
+	for _, delivery := range queue.Deliveries {
+		if excess > 0 && delivery.Status != StatusPending {
+			excess--
+			continue
+		}
+		kept = append(kept, delivery)
+	}
+	queue.Deliveries = kept
+}
+
+// Deliveries returns the delivery log of a subscription, newest first.
+func (m *Manager) Deliveries(subscriptionID int, status string) (Deliveries, error) {
+	if _, err := m.GetSubscription(subscriptionID); err != nil {
+		return nil, err
+	}
+
+	queue := Queue{}
+	if err := m.queue.Load(&queue); err != nil {
+		return nil, err
+	}
+
+	found := Deliveries{}
+	for idx := len(queue.Deliveries) - 1; idx >= 0; idx-- {
+		delivery := queue.Deliveries[idx]
+		if delivery.SubscriptionID != subscriptionID {
+			continue
+		}
+
+		if status != "" && delivery.Status != status {
+			continue
+		}
+
+		found = append(found, delivery)
+	}
+
+	return found, nil
+}
+
+// Redeliver queues a fresh copy of a delivery for immediate sending, keeping
+// the original in the log.
+func (m *Manager) Redeliver(subscriptionID int, deliveryID int64) (Delivery, error) {
+	if _, err := m.GetSubscription(subscriptionID); err != nil {
+		return Delivery{}, err
+	}
+
+	var queued Delivery
+	err := m.updateQueue(func(queue *Queue) error {
+		idx := slices.IndexFunc(queue.Deliveries, func(delivery Delivery) bool {
+			return delivery.ID == deliveryID && delivery.SubscriptionID == subscriptionID
+		})
+		if idx == -1 {
+			return fmt.Errorf("delivery with ID %d not found for webhook %d", deliveryID, subscriptionID)
+		}
+
+		now := time.Now()
+		queued = Delivery{
+			ID:             queue.nextID(),
+			SubscriptionID: subscriptionID,
+			Event:          queue.Deliveries[idx].Event,
+			Status:         StatusPending,
+			Attempts:       []Attempt{},
+			NextAttemptAt:  &now,
+			CreatedAt:      now,
+			RedeliveryOf:   deliveryID,
+		}
+		queue.Deliveries = append(queue.Deliveries, queued)
+		queue.trim(m.policy.DeliveryRetention)
+
+		return nil
+	})
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	if m.tx != nil {
+		m.tx.OnCommit(m.Wake)
+	} else {
+		m.Wake()
+	}
+
+	return queued, nil
+}
+
+// Wake makes the dispatcher look at the queue without waiting for its next
+// poll.
+func (m *Manager) Wake() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
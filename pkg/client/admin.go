@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type AdminService struct {
+	client *Client
+}
+
+func (s *AdminService) ListBackups(ctx context.Context) ([]Backup, error) {
+	backups := []Backup{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/admin/backups"}, &backups)
+	return backups, err
+}
+
+func (s *AdminService) CreateBackup(ctx context.Context) (Backup, error) {
+	var created Backup
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/admin/backups"}, &created)
+	return created, err
+}
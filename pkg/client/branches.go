@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type BranchesService struct {
+	client *Client
+}
+
+func (s *BranchesService) List(ctx context.Context) (Branches, error) {
+	branches := Branches{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/branches"}, &branches)
+	return branches, err
+}
+
+func (s *BranchesService) Create(ctx context.Context, input Branch) (Branch, error) {
+	var created Branch
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/branches", body: input}, &created)
+	return created, err
+}
+
+func (s *BranchesService) Update(ctx context.Context, input Branch) (Branch, error) {
+	var updated Branch
+	err := s.client.do(ctx, request{method: http.MethodPut, path: "/branches", body: input}, &updated)
+	return updated, err
+}
+
+func (s *BranchesService) Delete(ctx context.Context, branchID int) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: "/branches", body: IDRequest{ID: branchID}}, nil)
+}
@@ -0,0 +1,359 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	employeeHeader        = "X-Employee-ID"
+	managerOverrideHeader = "X-Manager-Override"
+	apiKeyHeader          = "X-API-Key"
+)
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	employeeID int64
+	userAgent  string
+
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+func WithEmployee(personalID int64) Option {
+	return func(c *Client) {
+		c.employeeID = personalID
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q must use http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		userAgent:  "rwapigolang-client",
+		maxRetries: 3,
+		minBackoff: 200 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+type employeeContextKey struct{}
+
+func ContextWithEmployee(ctx context.Context, personalID int64) context.Context {
+	return context.WithValue(ctx, employeeContextKey{}, personalID)
+}
+
+type managerOverrideContextKey struct{}
+
+// ContextWithManagerOverride approves assigning a vehicle to a customer with
+// risk flags in the name of the given manager, for requests made with ctx.
+func ContextWithManagerOverride(ctx context.Context, managerID int64) context.Context {
+	return context.WithValue(ctx, managerOverrideContextKey{}, managerID)
+}
+
+func (c *Client) Customers() *CustomersService {
+	return &CustomersService{client: c}
+}
+
+func (c *Client) Documents() *DocumentsService {
+	return &DocumentsService{client: c}
+}
+
+func (c *Client) Vehicles() *VehiclesService {
+	return &VehiclesService{client: c}
+}
+
+func (c *Client) Employees() *EmployeesService {
+	return &EmployeesService{client: c}
+}
+
+func (c *Client) Branches() *BranchesService {
+	return &BranchesService{client: c}
+}
+
+func (c *Client) Rentals() *RentalsService {
+	return &RentalsService{client: c}
+}
+
+func (c *Client) Reservations() *ReservationsService {
+	return &ReservationsService{client: c}
+}
+
+func (c *Client) Events() *EventsService {
+	return &EventsService{client: c}
+}
+
+func (c *Client) Webhooks() *WebhooksService {
+	return &WebhooksService{client: c}
+}
+
+func (c *Client) Notifications() *NotificationsService {
+	return &NotificationsService{client: c}
+}
+
+func (c *Client) Admin() *AdminService {
+	return &AdminService{client: c}
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        any
+	rawBody     []byte
+	contentType string
+	employee    bool
+	// stream marks a response read for as long as the server keeps it open,
+	// so the client timeout does not apply.
+	stream bool
+}
+
+func (c *Client) url(path string, query url.Values) string {
+	target := *c.baseURL
+	target.Path = c.baseURL.Path + path
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
+
+func (c *Client) newRequest(ctx context.Context, req request, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.url(req.path, req.query), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	if req.stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+	httpReq.Header.Set("User-Agent", c.userAgent)
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+
+	if c.apiKey != "" {
+		httpReq.Header.Set(apiKeyHeader, c.apiKey)
+	}
+
+	employeeID := c.employeeID
+	if value, ok := ctx.Value(employeeContextKey{}).(int64); ok {
+		employeeID = value
+	}
+
+	if employeeID != 0 {
+		httpReq.Header.Set(employeeHeader, strconv.FormatInt(employeeID, 10))
+	} else if req.employee {
+		return nil, errors.New("client: this operation requires an employee, use WithEmployee or ContextWithEmployee")
+	}
+
+	if managerID, ok := ctx.Value(managerOverrideContextKey{}).(int64); ok {
+		httpReq.Header.Set(managerOverrideHeader, strconv.FormatInt(managerID, 10))
+	}
+
+	return httpReq, nil
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete || method == http.MethodHead
+}
+
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	delay := c.minBackoff << attempt
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// send performs the request, retrying rate limited requests and, for
+// idempotent methods, server errors and transport failures.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	body := req.rawBody
+	if req.body != nil {
+		encoded, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("client: encoding request body: %w", err)
+		}
+		body = encoded
+		req.contentType = "application/json"
+	}
+
+	httpClient := c.httpClient
+	if req.stream {
+		streaming := *c.httpClient
+		streaming.Timeout = 0
+		httpClient = &streaming
+	}
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := c.newRequest(ctx, req, body)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := httpClient.Do(httpReq)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.maxRetries || !idempotent(req.method) {
+				return nil, err
+			}
+
+			if err := sleep(ctx, c.backoff(attempt, 0)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		apiErr := newAPIError(resp)
+		resp.Body.Close()
+
+		retryable := resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode >= 500 && idempotent(req.method))
+		if !retryable || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		if err := sleep(ctx, c.backoff(attempt, apiErr.RetryAfter)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decoding %s %s response: %w", req.method, req.path, err)
+	}
+
+	return nil
+}
+
+func (c *Client) Health(ctx context.Context) (map[string]string, error) {
+	status := map[string]string{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/healthz"}, &status)
+	return status, err
+}
+
+func (c *Client) Ready(ctx context.Context) (ReadinessResponse, error) {
+	var readiness ReadinessResponse
+	err := c.do(ctx, request{method: http.MethodGet, path: "/readyz"}, &readiness)
+	return readiness, err
+}
+
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/metrics"})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+func (c *Client) OpenAPI(ctx context.Context) (map[string]any, error) {
+	document := map[string]any{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/openapi.json"}, &document)
+	return document, err
+}
+
+func pathID[T int | int64](id T) string {
+	return strconv.FormatInt(int64(id), 10)
+}
+
+func (c *Client) Batch(ctx context.Context, input BatchRequest) (BatchResponse, error) {
+	var response BatchResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/batch", body: input}, &response)
+	return response, err
+}
@@ -0,0 +1,634 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/app"
+	"github.com/ZulfiPy/RWAPIGo/internal/config"
+	"github.com/ZulfiPy/RWAPIGo/pkg/client"
+)
+
+const (
+	testCustomerID = 49001010000
+	testEmployeeID = 38001010000
+)
+
+func testConfig(t *testing.T) config.Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DataDir = dir
+	cfg.DamagePhotoDir = filepath.Join(dir, "damage_photos")
+	cfg.Documents.Dir = filepath.Join(dir, "documents")
+	cfg.Backup.Dir = filepath.Join(dir, "backups")
+	cfg.Server.RateLimits = map[string]config.RateLimit{}
+
+	return cfg
+}
+
+// newServer serves the API from fresh storage files, with wrap applied to
+// its handler when given.
+func newServer(t *testing.T, cfg config.Config, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+
+	storages, err := app.OpenStorages(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := storages.NewAPIServer(cfg).Handler()
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newClient(t *testing.T, server *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	opts = append([]client.Option{client.WithRetries(3, time.Millisecond, 5*time.Millisecond)}, opts...)
+	c, err := client.New(server.URL, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func testEmployee(personalID int64) client.Employee {
+	return client.Employee{
+		FirstName:   "Jaan",
+		LastName:    "Kask",
+		PersonalID:  personalID,
+		DateOfBirth: "01.01.1980",
+		Email:       "jaan.kask@example.com",
+		PhoneNumber: "5551234",
+		Address:     "Tartu mnt 1, Tallinn",
+	}
+}
+
+func testVehicle(plateNumber string) client.Vehicle {
+	return client.Vehicle{
+		PlateNumber: plateNumber,
+		Make:        "Toyota",
+		Model:       "Corolla",
+		Year:        2020,
+		FuelType:    "Petrol",
+		Gearbox:     "Automatic",
+		Color:       "White",
+		Body:        "Sedan",
+	}
+}
+
+func TestCustomersCRUD(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil))
+	ctx := context.Background()
+
+	created, err := c.Customers().Create(ctx, client.Customer{FirstName: "Mari", LastName: "Tamm", PersonalID: testCustomerID, PhoneNumber: "5559876", Email: "mari.tamm@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.PersonalID != testCustomerID {
+		t.Fatalf("created = %+v", created)
+	}
+
+	if err := c.Customers().Update(ctx, client.EditCustomerRequest{PersonalID: testCustomerID, FirstName: "Maria", LastName: "Kuusk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	customers, err := c.Customers().List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 1 || customers[0].FirstName != "Maria" || customers[0].LastName != "Kuusk" {
+		t.Fatalf("customers after update = %+v", customers)
+	}
+
+	if err := c.Customers().Delete(ctx, testCustomerID); err != nil {
+		t.Fatal(err)
+	}
+
+	customers, err = c.Customers().List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 0 {
+		t.Fatalf("customers after delete = %+v", customers)
+	}
+}
+
+func TestVehiclesCRUD(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil))
+	ctx := context.Background()
+
+	if _, err := c.Vehicles().Create(ctx, testVehicle("123ABC")); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := testVehicle("123ABC")
+	edited.Color = "Black"
+	if _, err := c.Vehicles().Update(ctx, edited); err != nil {
+		t.Fatal(err)
+	}
+
+	vehicles, err := c.Vehicles().List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vehicles["123ABC"].Color != "Black" {
+		t.Fatalf("vehicles after update = %+v", vehicles)
+	}
+
+	if err := c.Vehicles().Delete(ctx, "123ABC"); err != nil {
+		t.Fatal(err)
+	}
+
+	vehicles, err = c.Vehicles().List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vehicles) != 0 {
+		t.Fatalf("vehicles after delete = %+v", vehicles)
+	}
+}
+
+func TestEmployeesCRUD(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil))
+	ctx := context.Background()
+
+	if _, err := c.Employees().Create(ctx, testEmployee(testEmployeeID)); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := c.Employees().Update(ctx, client.EditEmployeeRequest{PersonalID: testEmployeeID, Email: "jaan@example.com", PhoneNumber: "5554321", Address: "Narva mnt 2, Tallinn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Email != "jaan@example.com" {
+		t.Fatalf("updated = %+v", updated)
+	}
+
+	employees, err := c.Employees().List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(employees) != 1 || employees[0].Address != "Narva mnt 2, Tallinn" {
+		t.Fatalf("employees after update = %+v", employees)
+	}
+
+	if err := c.Employees().Delete(ctx, testEmployeeID); err != nil {
+		t.Fatal(err)
+	}
+
+	employees, err = c.Employees().List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(employees) != 0 {
+		t.Fatalf("employees after delete = %+v", employees)
+	}
+}
+
+func TestBranchesCRUD(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil))
+	ctx := context.Background()
+
+	created, err := c.Branches().Create(ctx, client.Branch{Name: "Tallinn", Address: "Tartu mnt 1, Tallinn", OpeningHours: "08-20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created.OpeningHours = "09-18"
+	if _, err := c.Branches().Update(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+
+	branches, err := c.Branches().List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 1 || branches[0].OpeningHours != "09-18" {
+		t.Fatalf("branches after update = %+v", branches)
+	}
+
+	if err := c.Branches().Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	branches, err = c.Branches().List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 0 {
+		t.Fatalf("branches after delete = %+v", branches)
+	}
+}
+
+func TestWebhooksCRUD(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil))
+	ctx := context.Background()
+
+	created, err := c.Webhooks().Create(ctx, client.Webhook{URL: "https://93.184.215.14/hooks", EventTypes: []string{"*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Secret == "" {
+		t.Fatal("created webhook has no secret")
+	}
+
+	created.EventTypes = []string{"CustomerCreated"}
+	created.Secret = ""
+	if _, err := c.Webhooks().Update(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+
+	webhooks, err := c.Webhooks().List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 1 || webhooks[0].EventTypes[0] != "CustomerCreated" || webhooks[0].Secret != "" {
+		t.Fatalf("webhooks after update = %+v", webhooks)
+	}
+
+	if err := c.Webhooks().Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	webhooks, err = c.Webhooks().List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 0 {
+		t.Fatalf("webhooks after delete = %+v", webhooks)
+	}
+}
+
+func TestDocumentsCRUD(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil), client.WithEmployee(testEmployeeID))
+	ctx := context.Background()
+
+	if _, err := c.Employees().Create(ctx, testEmployee(testEmployeeID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Customers().Create(ctx, client.Customer{FirstName: "Mari", LastName: "Tamm", PersonalID: testCustomerID, PhoneNumber: "5559876", Email: "mari.tamm@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "%PDF-1.4\nsigned contract"
+	uploaded, err := c.Documents().Upload(ctx, testCustomerID, client.DocumentUpload{Type: "contract", FileName: "contract.pdf", Content: strings.NewReader(content)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.ContentType != "application/pdf" {
+		t.Fatalf("uploaded = %+v", uploaded)
+	}
+
+	body, contentType, err := c.Documents().Download(ctx, testCustomerID, uploaded.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(downloaded) != content || contentType != "application/pdf" {
+		t.Fatalf("downloaded %q as %s", downloaded, contentType)
+	}
+
+	if err := c.Documents().Delete(ctx, testCustomerID, uploaded.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	documents, err := c.Documents().List(ctx, testCustomerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 0 {
+		t.Fatalf("documents after delete = %+v", documents)
+	}
+}
+
+func TestReservationsCreateAndCancel(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil))
+	ctx := context.Background()
+
+	if _, err := c.Customers().Create(ctx, client.Customer{FirstName: "Mari", LastName: "Tamm", PersonalID: testCustomerID, PhoneNumber: "5559876", Email: "mari.tamm@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Vehicles().Create(ctx, testVehicle("123ABC")); err != nil {
+		t.Fatal(err)
+	}
+
+	pickupAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	created, err := c.Reservations().Create(ctx, client.Reservation{PersonalID: testCustomerID, PlateNumber: "123ABC", PickupAt: pickupAt, ReturnAt: pickupAt.Add(48 * time.Hour), PickupLocation: "Tallinn", ReturnLocation: "Tallinn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reservations, err := c.Reservations().List(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 1 || reservations[0].ID != created.ID {
+		t.Fatalf("reservations = %+v", reservations)
+	}
+
+	cancelled, err := c.Reservations().Cancel(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status == created.Status {
+		t.Fatalf("status after cancel = %s, want it changed from %s", cancelled.Status, created.Status)
+	}
+}
+
+// failing answers the first failures requests with status and passes the
+// rest on to next.
+type failing struct {
+	next     http.Handler
+	failures int32
+	status   int
+	calls    atomic.Int32
+}
+
+func (f *failing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.calls.Add(1) <= f.failures {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(f.status)
+		io.WriteString(w, `{"error":"try again"}`)
+		return
+	}
+
+	f.next.ServeHTTP(w, r)
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		failures  int32
+		post      bool
+		wantCalls int32
+		wantErr   int
+	}{
+		{"server error on GET", http.StatusServiceUnavailable, 2, false, 3, 0},
+		{"rate limited GET", http.StatusTooManyRequests, 2, false, 3, 0},
+		{"rate limited POST", http.StatusTooManyRequests, 1, true, 2, 0},
+		{"server error on POST is not retried", http.StatusInternalServerError, 1, true, 1, http.StatusInternalServerError},
+		{"retries run out", http.StatusBadGateway, 10, false, 4, http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flaky := &failing{failures: test.failures, status: test.status}
+			server := newServer(t, testConfig(t), func(next http.Handler) http.Handler {
+				flaky.next = next
+				return flaky
+			})
+			c := newClient(t, server)
+
+			var err error
+			if test.post {
+				_, err = c.Branches().Create(context.Background(), client.Branch{Name: "Tallinn", Address: "Tartu mnt 1, Tallinn", OpeningHours: "08-20"})
+			} else {
+				_, err = c.Branches().List(context.Background())
+			}
+
+			if test.wantErr == 0 && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var apiErr *client.APIError
+			if test.wantErr != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != test.wantErr) {
+				t.Fatalf("error = %v, want status %d", err, test.wantErr)
+			}
+
+			if calls := flaky.calls.Load(); calls != test.wantCalls {
+				t.Fatalf("server was called %d times, want %d", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestBackoffStopsWhenContextIsCancelled(t *testing.T) {
+	flaky := &failing{failures: 100, status: http.StatusServiceUnavailable}
+	server := newServer(t, testConfig(t), func(next http.Handler) http.Handler {
+		flaky.next = next
+		return flaky
+	})
+	c := newClient(t, server, client.WithRetries(5, time.Minute, time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.Branches().List(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cancelled request took %s", elapsed)
+	}
+	if calls := flaky.calls.Load(); calls != 1 {
+		t.Fatalf("server was called %d times, want 1", calls)
+	}
+}
+
+func TestRequestStopsAtDeadline(t *testing.T) {
+	server := newServer(t, testConfig(t), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		})
+	})
+	c := newClient(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Branches().List(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newServer(t, testConfig(t), nil), client.WithEmployee(testEmployeeID))
+
+	_, err := c.Customers().Create(ctx, client.Customer{FirstName: "Mari"})
+	if !client.IsBadRequest(err) {
+		t.Errorf("invalid customer: error = %v, want a bad request", err)
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(apiErr.Message, "last name") || apiErr.RequestID == "" {
+		t.Errorf("invalid customer: error = %#v, want the server's message and request ID", apiErr)
+	}
+
+	if _, err := c.Documents().Upload(ctx, testCustomerID, client.DocumentUpload{Type: "contract", FileName: "contract.pdf", Content: strings.NewReader("%PDF-1.4")}); !client.IsNotFound(err) {
+		t.Errorf("upload for unknown customer: error = %v, want not found", err)
+	}
+
+	if _, err := c.Employees().Create(ctx, testEmployee(testEmployeeID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Customers().Create(ctx, client.Customer{FirstName: "Mari", LastName: "Tamm", PersonalID: testCustomerID, PhoneNumber: "5559876", Email: "mari.tamm@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Vehicles().Create(ctx, testVehicle("123ABC")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Vehicles().ReportDamage(ctx, "123ABC", client.DamageReport{Location: "door", Severity: "minor", Description: "dent"}); err != nil {
+		t.Fatal(err)
+	}
+
+	odometer, fuel := 1000, 80
+	checkout := client.CheckoutRequest{Vehicle: testVehicle("123ABC"), VehicleReading: client.VehicleReading{Odometer: &odometer, FuelLevel: &fuel}}
+	if _, err := c.Customers().Checkout(ctx, testCustomerID, checkout); !client.IsConflict(err) {
+		t.Errorf("checkout of a damaged vehicle: error = %v, want a conflict", err)
+	}
+}
+
+func TestRateLimitedError(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Server.RateLimits = map[string]config.RateLimit{config.DefaultRateLimitGroup: {RequestsPerSecond: 0.01, Burst: 1, Key: config.RateLimitByIP}}
+	c := newClient(t, newServer(t, cfg, nil), client.WithRetries(0, time.Millisecond, time.Millisecond))
+
+	if _, err := c.Branches().List(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := c.Branches().List(context.Background())
+	if !client.IsRateLimited(err) {
+		t.Fatalf("error = %v, want rate limited", err)
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		t.Fatalf("error = %#v, want a Retry-After", err)
+	}
+}
+
+func TestOperationRequiresEmployee(t *testing.T) {
+	server := newServer(t, testConfig(t), nil)
+	c := newClient(t, server)
+
+	var apiErr *client.APIError
+	if _, err := c.Documents().Upload(context.Background(), testCustomerID, client.DocumentUpload{Type: "contract", FileName: "contract.pdf", Content: strings.NewReader("%PDF-1.4")}); err == nil || errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want the client to refuse before sending", err)
+	}
+
+	ctx := client.ContextWithEmployee(context.Background(), testEmployeeID)
+	if _, err := c.Documents().Upload(ctx, testCustomerID, client.DocumentUpload{Type: "contract", FileName: "contract.pdf", Content: strings.NewReader("%PDF-1.4")}); !client.IsNotFound(err) {
+		t.Fatalf("error = %v, want the request sent and the customer not found", err)
+	}
+}
+
+func TestImportAndExport(t *testing.T) {
+	c := newClient(t, newServer(t, testConfig(t), nil))
+	ctx := context.Background()
+
+	content := "ID,FirstName,LastName,PhoneNumber,Email\n49001010000,Mari,Tamm,5559876,mari.tamm@example.com\n"
+	report, err := c.Customers().Import(ctx, strings.NewReader(content), &client.ImportOptions{DryRun: true, Columns: map[string]string{"ID": "PersonalID"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Committed || report.Valid != 1 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+
+	report, err = c.Customers().Import(ctx, strings.NewReader(content), &client.ImportOptions{Columns: map[string]string{"ID": "PersonalID"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Committed {
+		t.Fatalf("report = %+v", report)
+	}
+
+	body, err := c.Customers().Export(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(exported), "PersonalID,") || !strings.Contains(string(exported), "49001010000,Mari,Tamm") {
+		t.Fatalf("export = %q", exported)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.EventRetention = 2
+	c := newClient(t, newServer(t, cfg, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := range 4 {
+		customer := client.Customer{FirstName: "Mari", LastName: "Tamm", PersonalID: testCustomerID + int64(i), PhoneNumber: "5559876", Email: "mari.tamm@example.com"}
+		if _, err := c.Customers().Create(ctx, customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	polled, err := c.Events().Poll(ctx, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polled.Reset == nil || polled.Reset.Oldest != 3 {
+		t.Fatalf("poll = %+v, want a reset to event 3", polled)
+	}
+
+	stream, err := c.Events().Stream(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	message, err := stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Reset == nil || message.Reset.Cursor != 1 || message.Reset.Oldest != 3 {
+		t.Fatalf("first message = %+v, want a reset from 1 to 3", message)
+	}
+
+	for _, want := range []int64{3, 4} {
+		message, err := stream.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if message.Event.ID != want || message.Event.Type != "CustomerCreated" {
+			t.Fatalf("message = %+v, want event %d", message, want)
+		}
+	}
+
+	// Events created while the stream is open arrive on it too.
+	if _, err := c.Customers().Create(ctx, client.Customer{FirstName: "Liis", LastName: "Saar", PersonalID: testCustomerID + 9, PhoneNumber: "5551003", Email: "liis.saar@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err = stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Event.ID != 5 || stream.Cursor != 5 {
+		t.Fatalf("message = %+v with cursor %d, want event 5", message, stream.Cursor)
+	}
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) importCSV(ctx context.Context, path string, content io.Reader, opts *ImportOptions) (ImportReport, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var report ImportReport
+	err = c.do(ctx, request{method: http.MethodPost, path: path, query: opts.values(), rawBody: data, contentType: "text/csv"}, &report)
+	return report, err
+}
+
+// exportCSV returns the CSV export at path; the caller closes the body.
+func (c *Client) exportCSV(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: path, query: url.Values{"format": {"csv"}}})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type CustomersService struct {
+	client *Client
+}
+
+func customerPath(personalID int64, suffix string) string {
+	return "/customers/" + pathID(personalID) + suffix
+}
+
+func (s *CustomersService) List(ctx context.Context, opts *ListOptions) (Customers, error) {
+	customers := Customers{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/customers", query: opts.values()}, &customers)
+	return customers, err
+}
+
+func (s *CustomersService) Create(ctx context.Context, input Customer) (Customer, error) {
+	var created Customer
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/customers", body: input}, &created)
+	return created, err
+}
+
+func (s *CustomersService) Update(ctx context.Context, input EditCustomerRequest) error {
+	return s.client.do(ctx, request{method: http.MethodPut, path: "/customers", body: input}, nil)
+}
+
+func (s *CustomersService) Delete(ctx context.Context, personalID int64) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: "/customers", body: PersonalIDRequest{PersonalID: personalID}}, nil)
+}
+
+// Import creates customers from CSV content with a header row.
+func (s *CustomersService) Import(ctx context.Context, content io.Reader, opts *ImportOptions) (ImportReport, error) {
+	return s.client.importCSV(ctx, "/customers/import", content, opts)
+}
+
+// Export returns all customers as CSV; the caller closes the body.
+func (s *CustomersService) Export(ctx context.Context) (io.ReadCloser, error) {
+	return s.client.exportCSV(ctx, "/customers/export")
+}
+
+func (s *CustomersService) Checkout(ctx context.Context, personalID int64, input CheckoutRequest) (Customer, error) {
+	var updated Customer
+	err := s.client.do(ctx, request{method: http.MethodPost, path: customerPath(personalID, "/vehicles"), body: input, employee: true}, &updated)
+	return updated, err
+}
+
+func (s *CustomersService) CheckIn(ctx context.Context, personalID int64, plateNumber string, input CheckInRequest) (Rental, error) {
+	var returned Rental
+	path := customerPath(personalID, "/"+url.PathEscape(plateNumber)+"/delete-vehicle")
+	err := s.client.do(ctx, request{method: http.MethodDelete, path: path, body: input, employee: true}, &returned)
+	return returned, err
+}
+
+func (s *CustomersService) RiskFlags(ctx context.Context, personalID int64) ([]RiskFlag, error) {
+	flags := []RiskFlag{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: customerPath(personalID, "/risk-flags")}, &flags)
+	return flags, err
+}
+
+func (s *CustomersService) AddRiskFlag(ctx context.Context, personalID int64, input RiskFlagRequest) (RiskFlag, error) {
+	var flag RiskFlag
+	err := s.client.do(ctx, request{method: http.MethodPost, path: customerPath(personalID, "/risk-flags"), body: input, employee: true}, &flag)
+	return flag, err
+}
+
+func (s *CustomersService) RemoveRiskFlag(ctx context.Context, personalID int64, flagID int) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: customerPath(personalID, "/risk-flags"), body: IDRequest{ID: flagID}, employee: true}, nil)
+}
+
+func (s *CustomersService) Payments(ctx context.Context, personalID int64) (Entries, error) {
+	entries := Entries{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: customerPath(personalID, "/payments")}, &entries)
+	return entries, err
+}
+
+func (s *CustomersService) PostPayment(ctx context.Context, personalID int64, input PaymentRequest) (Entry, error) {
+	var entry Entry
+	err := s.client.do(ctx, request{method: http.MethodPost, path: customerPath(personalID, "/payments"), body: input}, &entry)
+	return entry, err
+}
+
+func (s *CustomersService) Balance(ctx context.Context, personalID int64) (Balance, error) {
+	var balance Balance
+	err := s.client.do(ctx, request{method: http.MethodGet, path: customerPath(personalID, "/balance")}, &balance)
+	return balance, err
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+type DocumentsService struct {
+	client *Client
+}
+
+// DocumentUpload is a scan to upload. Type is id_card, licence or contract;
+// ExpiresAt is optional and only its date is sent.
+type DocumentUpload struct {
+	Type      string
+	ExpiresAt time.Time
+	FileName  string
+	Content   io.Reader
+}
+
+func (s *DocumentsService) List(ctx context.Context, personalID int64) (Documents, error) {
+	documents := Documents{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: customerPath(personalID, "/documents")}, &documents)
+	return documents, err
+}
+
+func (s *DocumentsService) Upload(ctx context.Context, personalID int64, upload DocumentUpload) (Document, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("Type", upload.Type); err != nil {
+		return Document{}, err
+	}
+
+	if !upload.ExpiresAt.IsZero() {
+		if err := writer.WriteField("ExpiresAt", upload.ExpiresAt.Format(time.DateOnly)); err != nil {
+			return Document{}, err
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", upload.FileName)
+	if err != nil {
+		return Document{}, err
+	}
+
+	if _, err := io.Copy(part, upload.Content); err != nil {
+		return Document{}, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return Document{}, err
+	}
+
+	var document Document
+	err = s.client.do(ctx, request{method: http.MethodPost, path: customerPath(personalID, "/documents"), rawBody: body.Bytes(), contentType: writer.FormDataContentType(), employee: true}, &document)
+	return document, err
+}
+
+// Download returns the document body and its content type; the caller closes the body.
+func (s *DocumentsService) Download(ctx context.Context, personalID int64, documentID int) (io.ReadCloser, string, error) {
+	resp, err := s.client.send(ctx, request{method: http.MethodGet, path: customerPath(personalID, "/documents/"+pathID(documentID))})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+func (s *DocumentsService) Delete(ctx context.Context, personalID int64, documentID int) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: customerPath(personalID, "/documents"), body: IDRequest{ID: documentID}}, nil)
+}
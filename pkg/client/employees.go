@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+type EmployeesService struct {
+	client *Client
+}
+
+func employeePath(personalID int64, suffix string) string {
+	return "/employees/" + pathID(personalID) + suffix
+}
+
+func (s *EmployeesService) List(ctx context.Context, opts *ListOptions) (Employees, error) {
+	employees := Employees{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/employees", query: opts.values()}, &employees)
+	return employees, err
+}
+
+func (s *EmployeesService) Create(ctx context.Context, input Employee) (Employee, error) {
+	var created Employee
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/employees", body: input}, &created)
+	return created, err
+}
+
+func (s *EmployeesService) Update(ctx context.Context, input EditEmployeeRequest) (Employee, error) {
+	var updated Employee
+	err := s.client.do(ctx, request{method: http.MethodPut, path: "/employees", body: input}, &updated)
+	return updated, err
+}
+
+func (s *EmployeesService) Delete(ctx context.Context, personalID int64) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: "/employees", body: PersonalIDRequest{PersonalID: personalID}}, nil)
+}
+
+// Import creates employees from CSV content with a header row.
+func (s *EmployeesService) Import(ctx context.Context, content io.Reader, opts *ImportOptions) (ImportReport, error) {
+	return s.client.importCSV(ctx, "/employees/import", content, opts)
+}
+
+// Export returns all employees as CSV; the caller closes the body.
+func (s *EmployeesService) Export(ctx context.Context) (io.ReadCloser, error) {
+	return s.client.exportCSV(ctx, "/employees/export")
+}
+
+func (s *EmployeesService) AssignBranch(ctx context.Context, personalID int64, branchID int) (Employee, error) {
+	var updated Employee
+	err := s.client.do(ctx, request{method: http.MethodPost, path: employeePath(personalID, "/branch"), body: AssignBranchRequest{BranchID: branchID}}, &updated)
+	return updated, err
+}
+
+func (s *EmployeesService) Shifts(ctx context.Context, personalID int64, dates *DateRange) (Shifts, error) {
+	shifts := Shifts{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: employeePath(personalID, "/shifts"), query: dates.values()}, &shifts)
+	return shifts, err
+}
+
+func (s *EmployeesService) AddShift(ctx context.Context, personalID int64, input Shift) (Shift, error) {
+	var created Shift
+	err := s.client.do(ctx, request{method: http.MethodPost, path: employeePath(personalID, "/shifts"), body: input}, &created)
+	return created, err
+}
+
+func (s *EmployeesService) DeleteShift(ctx context.Context, personalID int64, shiftID int) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: employeePath(personalID, "/shifts"), body: IDRequest{ID: shiftID}}, nil)
+}
+
+func (s *EmployeesService) Activity(ctx context.Context, personalID int64, dates *DateRange) (EmployeeActivity, error) {
+	var activity EmployeeActivity
+	err := s.client.do(ctx, request{method: http.MethodGet, path: employeePath(personalID, "/activity"), query: dates.values()}, &activity)
+	return activity, err
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("rwapi: %d %s: %s (request %s)", e.StatusCode, http.StatusText(e.StatusCode), e.Message, e.RequestID)
+	}
+
+	return fmt.Sprintf("rwapi: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Error != "" {
+		apiErr.Message = body.Error
+	} else {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
+
+func statusOf(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+
+	return 0
+}
+
+func IsBadRequest(err error) bool {
+	return statusOf(err) == http.StatusBadRequest
+}
+
+func IsNotFound(err error) bool {
+	return statusOf(err) == http.StatusNotFound
+}
+
+func IsConflict(err error) bool {
+	return statusOf(err) == http.StatusConflict
+}
+
+func IsRateLimited(err error) bool {
+	return statusOf(err) == http.StatusTooManyRequests
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EventsService struct {
+	client *Client
+}
+
+// Poll returns the events after cursor, waiting up to wait for new ones when
+// there are none yet. Pass the returned Cursor to the next call.
+func (s *EventsService) Poll(ctx context.Context, cursor int64, wait time.Duration) (EventsResponse, error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(cursor, 10))
+	query.Set("wait", wait.String())
+
+	var response EventsResponse
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/events", query: query}, &response)
+	return response, err
+}
+
+// StreamMessage is one message of an event stream: an event, or a Reset when
+// the events after the cursor were dropped by the retention limit.
+type StreamMessage struct {
+	Event Event
+	Reset *StreamReset
+}
+
+// EventStream reads Server-Sent Events from /events/stream. Cursor is the ID
+// of the last event read, to resume from after reconnecting.
+type EventStream struct {
+	Cursor int64
+	body   io.ReadCloser
+	reader *bufio.Reader
+}
+
+// Stream opens an event stream after cursor. The stream ends when ctx is
+// done or Close is called.
+func (s *EventsService) Stream(ctx context.Context, cursor int64) (*EventStream, error) {
+	query := url.Values{}
+	query.Set("since", strconv.FormatInt(cursor, 10))
+
+	resp, err := s.client.send(ctx, request{method: http.MethodGet, path: "/events/stream", query: query, stream: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventStream{Cursor: cursor, body: resp.Body, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+// Next blocks until the next message arrives. It returns io.EOF once the
+// server has closed the stream.
+func (es *EventStream) Next() (StreamMessage, error) {
+	var eventType string
+	var data strings.Builder
+
+	for {
+		line, err := es.reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = io.EOF
+			}
+			return StreamMessage{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+
+			switch field {
+			case "event":
+				eventType = value
+			case "data":
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(value)
+			}
+			continue
+		}
+
+		// A blank line ends a message; comments and keep-alives carry no
+		// data.
+		if data.Len() == 0 {
+			eventType = ""
+			continue
+		}
+
+		if eventType == "reset" {
+			var reset StreamReset
+			if err := json.Unmarshal([]byte(data.String()), &reset); err != nil {
+				return StreamMessage{}, fmt.Errorf("client: decoding stream reset: %w", err)
+			}
+			return StreamMessage{Reset: &reset}, nil
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+			return StreamMessage{}, fmt.Errorf("client: decoding %s event: %w", eventType, err)
+		}
+		es.Cursor = event.ID
+
+		return StreamMessage{Event: event}, nil
+	}
+}
+
+func (es *EventStream) Close() error {
+	return es.body.Close()
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type NotificationsService struct {
+	client *Client
+}
+
+// Emails lists queued and sent emails, newest first, optionally only those
+// with the given status.
+func (s *NotificationsService) Emails(ctx context.Context, status string) (Emails, error) {
+	var query url.Values
+	if status != "" {
+		query = url.Values{"status": {status}}
+	}
+
+	emails := Emails{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/notifications/emails", query: query}, &emails)
+	return emails, err
+}
+
+func (s *NotificationsService) Preference(ctx context.Context, personalID int64) (Preference, error) {
+	var preference Preference
+	err := s.client.do(ctx, request{method: http.MethodGet, path: customerPath(personalID, "/notifications")}, &preference)
+	return preference, err
+}
+
+func (s *NotificationsService) SetEmailOptOut(ctx context.Context, personalID int64, optOut bool) (Preference, error) {
+	var preference Preference
+	err := s.client.do(ctx, request{method: http.MethodPut, path: customerPath(personalID, "/notifications"), body: NotificationPreferenceRequest{EmailOptOut: optOut}}, &preference)
+	return preference, err
+}
+
+func (s *NotificationsService) Templates(ctx context.Context) ([]string, error) {
+	names := []string{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/notifications/templates"}, &names)
+	return names, err
+}
+
+// Preview renders a template with the server's sample data.
+func (s *NotificationsService) Preview(ctx context.Context, name string) (EmailPreview, error) {
+	var preview EmailPreview
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/notifications/templates/" + url.PathEscape(name) + "/preview"}, &preview)
+	return preview, err
+}
@@ -0,0 +1,17 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type RentalsService struct {
+	client *Client
+}
+
+// Overdue lists the active rentals past their due-back time, latest first.
+func (s *RentalsService) Overdue(ctx context.Context) ([]Overdue, error) {
+	overdue := []Overdue{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/rentals/overdue"}, &overdue)
+	return overdue, err
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type ReservationsService struct {
+	client *Client
+}
+
+func reservationPath(reservationID int, suffix string) string {
+	return "/reservations/" + pathID(reservationID) + suffix
+}
+
+func (s *ReservationsService) List(ctx context.Context, opts *ListOptions) (Reservations, error) {
+	reservations := Reservations{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/reservations", query: opts.values()}, &reservations)
+	return reservations, err
+}
+
+func (s *ReservationsService) Create(ctx context.Context, input Reservation) (Reservation, error) {
+	var created Reservation
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/reservations", body: input}, &created)
+	return created, err
+}
+
+func (s *ReservationsService) Pickup(ctx context.Context, reservationID int, input PickupRequest) (Reservation, error) {
+	var converted Reservation
+	err := s.client.do(ctx, request{method: http.MethodPost, path: reservationPath(reservationID, "/pickup"), body: input, employee: true}, &converted)
+	return converted, err
+}
+
+func (s *ReservationsService) Cancel(ctx context.Context, reservationID int) (Reservation, error) {
+	var cancelled Reservation
+	err := s.client.do(ctx, request{method: http.MethodPost, path: reservationPath(reservationID, "/cancel")}, &cancelled)
+	return cancelled, err
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ZulfiPy/RWAPIGo/internal/api"
+	"github.com/ZulfiPy/RWAPIGo/internal/backup"
+	"github.com/ZulfiPy/RWAPIGo/internal/events"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/branch"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/customer"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/damage"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/document"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/employee"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/payment"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/rental"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/reservation"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/shift"
+	"github.com/ZulfiPy/RWAPIGo/internal/models/vehicle"
+	"github.com/ZulfiPy/RWAPIGo/internal/notify"
+	"github.com/ZulfiPy/RWAPIGo/internal/webhook"
+)
+
+type (
+	Customer     = customer.Customer
+	Customers    = customer.Customers
+	Vehicle      = vehicle.Vehicle
+	Vehicles     = vehicle.Vehicles
+	Employee     = employee.Employee
+	Employees    = employee.Employees
+	RiskFlag     = customer.RiskFlag
+	Rental       = rental.Rental
+	Overdue      = rental.OverdueRental
+	Damage       = damage.Damage
+	Damages      = damage.Damages
+	Document     = document.Document
+	Documents    = document.Documents
+	Reservation  = reservation.Reservation
+	Reservations = reservation.Reservations
+	Branch       = branch.Branch
+	Branches     = branch.Branches
+	Shift        = shift.Shift
+	Shifts       = shift.Shifts
+	Entry        = payment.Entry
+	Entries      = payment.Entries
+	Balance      = payment.Balance
+	Backup       = backup.Snapshot
+	Event        = events.Event
+	Webhook      = webhook.Subscription
+	Webhooks     = webhook.Subscriptions
+	Delivery     = webhook.Delivery
+	Deliveries   = webhook.Deliveries
+	Email        = notify.Message
+	Emails       = notify.Messages
+	EmailPreview = notify.Rendered
+	Preference   = notify.Preference
+
+	VehicleReading      = api.VehicleReading
+	CheckoutRequest     = api.CheckoutRequest
+	CheckInRequest      = api.CheckInRequest
+	EditCustomerRequest = api.EditCustomerRequest
+	EditEmployeeRequest = api.EditEmployeeRequest
+	PaymentRequest      = api.PaymentRequest
+	PickupRequest       = api.PickupRequest
+	PersonalIDRequest   = api.PersonalIDRequest
+	PlateNumberRequest  = api.PlateNumberRequest
+	IDRequest           = api.IDRequest
+	AssignBranchRequest = api.AssignBranchRequest
+	EmployeeActivity    = api.EmployeeActivity
+	ReadinessResponse   = api.ReadinessResponse
+	CustomResponse      = api.CustomResponse
+	BatchOperation      = api.BatchOperation
+	BatchRequest        = api.BatchRequest
+	BatchResponse       = api.BatchResponse
+	EventsResponse      = api.EventsResponse
+	StreamReset         = api.StreamReset
+	ImportReport        = api.ImportReport
+	ImportRow           = api.ImportRow
+
+	NotificationPreferenceRequest = api.NotificationPreferenceRequest
+	RiskFlagRequest               = api.RiskFlagRequest
+)
+
+type ListOptions struct {
+	BranchID int
+}
+
+func (opts *ListOptions) values() url.Values {
+	values := url.Values{}
+	if opts != nil && opts.BranchID != 0 {
+		values.Set("branch", strconv.Itoa(opts.BranchID))
+	}
+
+	return values
+}
+
+// ImportOptions control a CSV import. Rows are imported all or nothing unless
+// BestEffort is set. Columns maps CSV headers to field names, "-" ignores a
+// column.
+type ImportOptions struct {
+	DryRun     bool
+	BestEffort bool
+	Columns    map[string]string
+}
+
+func (opts *ImportOptions) values() url.Values {
+	values := url.Values{}
+	if opts == nil {
+		return values
+	}
+
+	if opts.DryRun {
+		values.Set("dry_run", "true")
+	}
+
+	if opts.BestEffort {
+		values.Set("mode", "best-effort")
+	}
+
+	for _, header := range slices.Sorted(maps.Keys(opts.Columns)) {
+		values.Add("map", header+":"+opts.Columns[header])
+	}
+
+	return values
+}
+
+type DateRange struct {
+	From     time.Time
+	To       time.Time
+	BranchID int
+}
+
+func (dr *DateRange) values() url.Values {
+	values := url.Values{}
+	if dr == nil {
+		return values
+	}
+
+	if !dr.From.IsZero() {
+		values.Set("from", dr.From.Format(time.DateOnly))
+	}
+
+	if !dr.To.IsZero() {
+		values.Set("to", dr.To.Format(time.DateOnly))
+	}
+
+	if dr.BranchID != 0 {
+		values.Set("branch", strconv.Itoa(dr.BranchID))
+	}
+
+	return values
+}
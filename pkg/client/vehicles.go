@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type VehiclesService struct {
+	client *Client
+}
+
+type DamagePhoto struct {
+	FileName string
+	Content  io.Reader
+}
+
+type DamageReport struct {
+	Location           string
+	Severity           string
+	Description        string
+	RepairCostEstimate float64
+	RentalID           int
+	Photos             []DamagePhoto
+}
+
+func damagesPath(plateNumber string) string {
+	return "/vehicles/" + url.PathEscape(plateNumber) + "/damages"
+}
+
+func (s *VehiclesService) List(ctx context.Context, opts *ListOptions) (Vehicles, error) {
+	vehicles := Vehicles{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/vehicles", query: opts.values()}, &vehicles)
+	return vehicles, err
+}
+
+func (s *VehiclesService) Create(ctx context.Context, input Vehicle) (Vehicle, error) {
+	var created Vehicle
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/vehicles", body: input}, &created)
+	return created, err
+}
+
+func (s *VehiclesService) Update(ctx context.Context, input Vehicle) (Vehicle, error) {
+	var updated Vehicle
+	err := s.client.do(ctx, request{method: http.MethodPut, path: "/vehicles", body: input}, &updated)
+	return updated, err
+}
+
+func (s *VehiclesService) Delete(ctx context.Context, plateNumber string) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: "/vehicles", body: PlateNumberRequest{PlateNumber: plateNumber}}, nil)
+}
+
+// Import creates vehicles from CSV content with a header row.
+func (s *VehiclesService) Import(ctx context.Context, content io.Reader, opts *ImportOptions) (ImportReport, error) {
+	return s.client.importCSV(ctx, "/vehicles/import", content, opts)
+}
+
+// Export returns all vehicles as CSV; the caller closes the body.
+func (s *VehiclesService) Export(ctx context.Context) (io.ReadCloser, error) {
+	return s.client.exportCSV(ctx, "/vehicles/export")
+}
+
+func (s *VehiclesService) Damages(ctx context.Context, plateNumber string) (Damages, error) {
+	damages := Damages{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: damagesPath(plateNumber)}, &damages)
+	return damages, err
+}
+
+func (s *VehiclesService) ReportDamage(ctx context.Context, plateNumber string, report DamageReport) (Damage, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	fields := map[string]string{
+		"Location":    report.Location,
+		"Severity":    report.Severity,
+		"Description": report.Description,
+	}
+	if report.RepairCostEstimate != 0 {
+		fields["RepairCostEstimate"] = strconv.FormatFloat(report.RepairCostEstimate, 'f', -1, 64)
+	}
+	if report.RentalID != 0 {
+		fields["RentalID"] = strconv.Itoa(report.RentalID)
+	}
+
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return Damage{}, err
+		}
+	}
+
+	for _, photo := range report.Photos {
+		part, err := writer.CreateFormFile("photos", photo.FileName)
+		if err != nil {
+			return Damage{}, err
+		}
+
+		if _, err := io.Copy(part, photo.Content); err != nil {
+			return Damage{}, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return Damage{}, err
+	}
+
+	var damage Damage
+	err := s.client.do(ctx, request{method: http.MethodPost, path: damagesPath(plateNumber), rawBody: body.Bytes(), contentType: writer.FormDataContentType()}, &damage)
+	return damage, err
+}
+
+func (s *VehiclesService) RepairDamage(ctx context.Context, plateNumber string, damageID int) (Damage, error) {
+	var damage Damage
+	err := s.client.do(ctx, request{method: http.MethodPost, path: damagesPath(plateNumber) + "/" + pathID(damageID) + "/repair"}, &damage)
+	return damage, err
+}
+
+// DamagePhoto returns the photo body and its content type; the caller closes the body.
+func (s *VehiclesService) DamagePhoto(ctx context.Context, plateNumber, photo string) (io.ReadCloser, string, error) {
+	resp, err := s.client.send(ctx, request{method: http.MethodGet, path: damagesPath(plateNumber) + "/photos/" + url.PathEscape(photo)})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type WebhooksService struct {
+	client *Client
+}
+
+func (s *WebhooksService) List(ctx context.Context) (Webhooks, error) {
+	webhooks := Webhooks{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/webhooks"}, &webhooks)
+	return webhooks, err
+}
+
+func (s *WebhooksService) Create(ctx context.Context, input Webhook) (Webhook, error) {
+	var created Webhook
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/webhooks", body: input}, &created)
+	return created, err
+}
+
+func (s *WebhooksService) Update(ctx context.Context, input Webhook) (Webhook, error) {
+	var updated Webhook
+	err := s.client.do(ctx, request{method: http.MethodPut, path: "/webhooks", body: input}, &updated)
+	return updated, err
+}
+
+func (s *WebhooksService) Delete(ctx context.Context, webhookID int) error {
+	return s.client.do(ctx, request{method: http.MethodDelete, path: "/webhooks", body: IDRequest{ID: webhookID}}, nil)
+}
+
+// Deliveries lists a webhook's deliveries, optionally only those with the
+// given status.
+func (s *WebhooksService) Deliveries(ctx context.Context, webhookID int, status string) (Deliveries, error) {
+	var query url.Values
+	if status != "" {
+		query = url.Values{"status": {status}}
+	}
+
+	deliveries := Deliveries{}
+	err := s.client.do(ctx, request{method: http.MethodGet, path: "/webhooks/" + pathID(webhookID) + "/deliveries", query: query}, &deliveries)
+	return deliveries, err
+}
+
+func (s *WebhooksService) Redeliver(ctx context.Context, webhookID int, deliveryID int64) (Delivery, error) {
+	var queued Delivery
+	err := s.client.do(ctx, request{method: http.MethodPost, path: "/webhooks/" + pathID(webhookID) + "/deliveries/" + pathID(deliveryID) + "/redeliver"}, &queued)
+	return queued, err
+}